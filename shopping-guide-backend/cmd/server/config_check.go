@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"shopping-guide-backend/internal/config"
+)
+
+// runConfigCheck 执行 config check 子命令：逐个环境加载 configs/config.*.yaml 并校验
+// 用法：server config check [-dir configs] [-env dev,prod] [-skip-secrets]
+func runConfigCheck(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	fs.SetOutput(out)
+	dir := fs.String("dir", "configs", "配置目录")
+	envs := fs.String("env", "", "要检查的环境，逗号分隔；为空时检查目录下全部 config.*.yaml")
+	skipSecrets := fs.Bool("skip-secrets", false, "不检查 api_key、app_id、jwt_secret 等密钥（CI 中未注入密钥时使用）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var names []string
+	if *envs != "" {
+		names = strings.Split(*envs, ",")
+	} else {
+		var err error
+		if names, err = discoverEnvs(*dir); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, env := range names {
+		env = strings.TrimSpace(env)
+		cfg, err := config.Load(*dir, env)
+		if err == nil {
+			err = cfg.ValidateWith(config.ValidateOptions{SkipSecrets: *skipSecrets})
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL %s: %v\n", env, err)
+			continue
+		}
+		fmt.Fprintf(out, "ok   %s\n", env)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d environments have invalid config", failed, len(names))
+	}
+	return nil
+}
+
+// discoverEnvs 列出目录下 config.<env>.yaml 对应的环境
+func discoverEnvs(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "config.*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	envs := make([]string, 0, len(files))
+	for _, f := range files {
+		env := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), "config."), ".yaml")
+		// config.local.yaml 是本地覆盖文件，不纳入检查
+		if env == "local" {
+			continue
+		}
+		envs = append(envs, env)
+	}
+	if len(envs) == 0 {
+		return nil, errors.New("no config.<env>.yaml found in " + dir)
+	}
+	sort.Strings(envs)
+	return envs, nil
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/client"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/database"
+	"shopping-guide-backend/internal/handler"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/ratelimit"
+	"shopping-guide-backend/internal/repository"
+	"shopping-guide-backend/internal/router"
+	"shopping-guide-backend/internal/search"
+	"shopping-guide-backend/internal/service"
+	"shopping-guide-backend/internal/style"
+	"shopping-guide-backend/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// 构建信息，由 Makefile 通过 -ldflags "-X main.Version=... -X main.BuildTime=..." 注入
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+)
+
+// defaultShutdownTimeout 未配置 server.shutdown_timeout 时的退出等待时间
+const defaultShutdownTimeout = 30 * time.Second
+
+func main() {
+	// 子命令：server config check，供 CI 校验配置文件
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "check" {
+		if err := runConfigCheck(os.Args[3:], os.Stdout); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// app 进程内需要在退出时按顺序关闭的组件
+type app struct {
+	server *http.Server
+
+	enrichmentService service.ProfileEnrichmentService
+	quotaService      service.QuotaService
+	logService        service.LogService
+
+	shutdownTracing func(context.Context) error
+	rdb             *redis.Client
+	db              *gorm.DB
+}
+
+func run() (err error) {
+	// 日志文件最后关闭，保证退出原因能写入日志
+	var logCloser io.Closer
+	defer func() {
+		if err != nil {
+			// 配置问题逐条输出，便于定位
+			var invalid *config.ValidationError
+			if errors.As(err, &invalid) {
+				for _, issue := range invalid.Issues {
+					slog.Error("invalid config", "path", issue.Path, "problem", issue.Message)
+				}
+			}
+			slog.Error("server exited with error", "error", err)
+		}
+		if logCloser != nil {
+			logCloser.Close()
+		}
+	}()
+
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "dev"
+	}
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "configs"
+	}
+
+	cfg, err := config.Load(configPath, env)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	log, closer, err := logger.New(&cfg.Log)
+	if err != nil {
+		return fmt.Errorf("failed to init logger: %w", err)
+	}
+	logCloser = closer
+	slog.SetDefault(log)
+	slog.Info("starting server", "version", Version, "build_time", BuildTime, "env", env)
+	for _, src := range cfg.SecretSources() {
+		slog.Info("secret loaded", "key", src.Path, "source", src.Source)
+	}
+	if cfg.Middleware.Auth.TrustUserHeader {
+		slog.Warn("trusting X-User-ID header without token, for local debugging only")
+	}
+
+	a := &app{}
+	defer a.closeResources()
+
+	if a.shutdownTracing, err = tracing.Init(&cfg.Tracing, Version); err != nil {
+		return fmt.Errorf("failed to init tracing: %w", err)
+	}
+
+	store := config.NewStore(cfg)
+	if err := a.build(store); err != nil {
+		return err
+	}
+	// 热更新 business、middleware.rate_limit 与工作流超时，其余配置修改后需重启
+	if err := config.Watch(store, configPath, env); err != nil {
+		return fmt.Errorf("failed to watch config: %w", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("http server listening", "addr", a.server.Addr)
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-quit:
+		slog.Info("received signal, shutting down", "signal", sig.String())
+	case err := <-errCh:
+		return fmt.Errorf("http server failed: %w", err)
+	}
+
+	a.shutdown(cfg.Server.ShutdownTimeout)
+	return nil
+}
+
+// build 按 docs/architecture.md 的依赖注入顺序组装各层
+func (a *app) build(store *config.Store) error {
+	cfg := store.Get()
+
+	// 1. 数据库连接
+	db, err := database.InitMySQL(&cfg.MySQL)
+	if err != nil {
+		return fmt.Errorf("failed to init MySQL: %w", err)
+	}
+	a.db = db
+
+	rdb, err := database.InitRedis(&cfg.Redis)
+	if err != nil {
+		return fmt.Errorf("failed to init Redis: %w", err)
+	}
+	a.rdb = rdb
+
+	// 2. Repository层
+	productRepo := repository.NewProductRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	profileRepo := repository.NewProfileRepository(db, &cfg.MySQL)
+	recommendationRepo := repository.NewRecommendationRepository(db)
+	tokenUsageRepo := repository.NewTokenUsageRepository(db)
+	logRepo := repository.NewLogRepository(db)
+	sessionRepo := repository.NewSessionRepository(rdb, &cfg.Redis)
+	quotaRepo := repository.NewQuotaRepository(rdb)
+
+	// 3. Client层，Dify 调用日志经 LogService 异步落库
+	a.logService = service.NewLogService(logRepo, &cfg.Log.Persist)
+	difyClient := client.NewRecordingDifyClient(
+		client.NewDifyClient(store),
+		a.logService,
+	)
+
+	embedder, err := search.NewEmbedder(&cfg.Business.Product.Search.Embedding)
+	if err != nil {
+		return fmt.Errorf("failed to init embedder: %w", err)
+	}
+	styles, err := style.Load(cfg.Business.Style.Path, cfg.Business.Session.DefaultStyle)
+	if err != nil {
+		return fmt.Errorf("failed to load styles: %w", err)
+	}
+	store.Subscribe(func(cfg *config.Config) {
+		if err := styles.Reload(cfg.Business.Style.Path, cfg.Business.Session.DefaultStyle); err != nil {
+			slog.Error("failed to reload styles, keeping current styles", "error", err)
+		}
+	})
+	tokens, err := auth.NewTokenManager(&cfg.Middleware.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to init token manager: %w", err)
+	}
+
+	// 4. 基础 Service
+	productService := service.NewProductService(productRepo, embedder, store)
+	sessionService := service.NewSessionServiceImpl(sessionRepo, productRepo, styles, store)
+	profileService := service.NewProfileService(profileRepo, styles)
+	recommendationService := service.NewRecommendationService(recommendationRepo)
+	rankingService := service.NewRankingService(productRepo, recommendationRepo, productService, profileService, store)
+	comparisonService := service.NewComparisonService(productService)
+	groundingService := service.NewGroundingService(productService, store)
+	userService := service.NewUserService(userRepo, tokens)
+	a.quotaService = service.NewQuotaService(quotaRepo, tokenUsageRepo, store)
+	a.enrichmentService = service.NewProfileEnrichmentService(
+		profileRepo,
+		service.NewProfileExtractor(difyClient, store),
+		store,
+	)
+
+	// 5. Planner / Executor
+	plannerService := service.NewPlannerService(difyClient, store)
+	executorService := service.NewExecutorService(difyClient, productService, rankingService, store)
+
+	// 6. 编排器
+	orchestratorService := service.NewOrchestratorService(
+		plannerService,
+		executorService,
+		sessionService,
+		productService,
+		profileService,
+		recommendationService,
+		comparisonService,
+		groundingService,
+		a.enrichmentService,
+		a.quotaService,
+		a.logService,
+		styles,
+		store,
+	)
+
+	// 7. 对话服务
+	chatService := service.NewChatService(orchestratorService)
+
+	healthChecks := []service.HealthCheck{
+		{Name: "redis", Critical: true, Check: database.PingRedis(rdb)},
+		{Name: "mysql", Critical: true, Check: database.PingMySQL(db)},
+	}
+	if cfg.Server.Health.ProbeDify {
+		healthChecks = append(healthChecks, service.HealthCheck{Name: "dify", Check: difyClient.Ping})
+	}
+	healthService := service.NewHealthService(healthChecks, cfg.Server.Health.Timeout)
+
+	// 8. Handler层
+	handlers := &router.Handlers{
+		Chat:           handler.NewChatHandler(chatService),
+		Product:        handler.NewProductHandler(productService),
+		Recommendation: handler.NewRecommendationHandler(recommendationService),
+		Ranking:        handler.NewRankingHandler(rankingService),
+		Profile:        handler.NewProfileHandler(profileService),
+		Session:        handler.NewSessionHandler(sessionService),
+		Style:          handler.NewStyleHandler(styles),
+		User:           handler.NewUserHandler(userService),
+		Usage:          handler.NewUsageHandler(a.quotaService),
+		Admin:          handler.NewAdminHandler(healthService, store),
+	}
+
+	// 9. Router
+	if cfg.Server.Mode != "" {
+		gin.SetMode(cfg.Server.Mode)
+	}
+	// 限流开关可热更新，limiter 始终创建
+	engine := router.SetupRouter(store, tokens, ratelimit.NewRedisLimiter(rdb), handlers)
+
+	// WriteTimeout 不作用于 SSE 流：流式接口清除本请求的写超时，避免长回复被截断，流的时长由 Executor 工作流的 timeout 限制
+	a.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      engine,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+	return nil
+}
+
+// shutdown 停止接收新连接并等待进行中请求（含 SSE 流）结束，超时后强制关闭连接
+func (a *app) shutdown(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := a.server.Shutdown(ctx); err != nil {
+		slog.Warn("graceful shutdown timed out, closing remaining connections", "timeout", timeout, "error", err)
+		if err := a.server.Close(); err != nil {
+			slog.Error("failed to close http server", "error", err)
+		}
+	}
+	slog.Info("http server stopped")
+}
+
+// closeResources 请求处理结束后关闭后台任务与连接池
+// 先停画像补全与用量汇总（它们会写日志和数据库），再刷新日志队列，最后关闭 tracing 与连接池
+func (a *app) closeResources() {
+	if a.enrichmentService != nil {
+		a.enrichmentService.Close()
+	}
+	if a.quotaService != nil {
+		a.quotaService.Close()
+	}
+	if a.logService != nil {
+		a.logService.Close()
+	}
+
+	if a.shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := a.shutdownTracing(ctx); err != nil {
+			slog.Error("failed to shut down tracing", "error", err)
+		}
+		cancel()
+	}
+
+	if a.rdb != nil {
+		if err := a.rdb.Close(); err != nil {
+			slog.Error("failed to close redis", "error", err)
+		}
+	}
+	if a.db != nil {
+		if sqlDB, err := a.db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				slog.Error("failed to close mysql", "error", err)
+			}
+		}
+	}
+
+	slog.Info("server exited")
+}
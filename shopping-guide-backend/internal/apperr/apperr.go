@@ -0,0 +1,118 @@
+// Package apperr 带类别的业务错误
+// 类别决定 HTTP 状态码与响应码，Message 是可以返回给调用方的说明；原因错误只进日志，不返回给调用方，
+// 避免泄露 Dify 响应体、内部地址等信息
+package apperr
+
+import (
+	"context"
+	"errors"
+)
+
+// Kind 错误类别
+type Kind int
+
+const (
+	KindInternal            Kind = iota // 内部错误，未分类的错误均按此处理
+	KindNotFound                        // 资源不存在
+	KindInvalidInput                    // 请求参数不合法
+	KindUnauthorized                    // 未登录或凭证无效
+	KindForbidden                       // 无权访问
+	KindConflict                        // 与已有数据冲突，如重复注册
+	KindRateLimited                     // 超出限流或配额
+	KindUpstreamUnavailable             // 上游服务（Dify 等）不可用或返回错误
+	KindUpstreamTimeout                 // 上游服务超时
+)
+
+// defaultMessages 未指定 Message 时返回给调用方的说明
+var defaultMessages = map[Kind]string{
+	KindInternal:            "internal server error",
+	KindNotFound:            "not found",
+	KindInvalidInput:        "invalid request",
+	KindUnauthorized:        "unauthenticated",
+	KindForbidden:           "forbidden",
+	KindConflict:            "conflict",
+	KindRateLimited:         "too many requests",
+	KindUpstreamUnavailable: "upstream service unavailable",
+	KindUpstreamTimeout:     "upstream service timed out",
+}
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not_found"
+	case KindInvalidInput:
+		return "invalid_input"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindConflict:
+		return "conflict"
+	case KindRateLimited:
+		return "rate_limited"
+	case KindUpstreamUnavailable:
+		return "upstream_unavailable"
+	case KindUpstreamTimeout:
+		return "upstream_timeout"
+	default:
+		return "internal"
+	}
+}
+
+// Error 带类别的错误
+type Error struct {
+	Kind    Kind
+	Message string // 返回给调用方的说明，为空时使用类别默认说明
+	Err     error  // 原因，只进日志
+}
+
+// New 创建错误，message 会返回给调用方
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap 以类别包装原因错误，message 会返回给调用方，err 不会
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = defaultMessages[e.Kind]
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf 错误链中最外层 *Error 的类别；链中没有 *Error 时，超时按上游超时处理，其余按内部错误处理
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindUpstreamTimeout
+	}
+	return KindInternal
+}
+
+// Is 错误链中是否有指定类别的错误
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// Message 可以返回给调用方的说明：错误链中最外层 *Error 的 Message，内部错误一律返回默认说明
+func Message(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Kind != KindInternal && e.Message != "" {
+		return e.Message
+	}
+	return defaultMessages[KindOf(err)]
+}
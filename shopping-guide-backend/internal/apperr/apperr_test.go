@@ -0,0 +1,72 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.3:80: connection refused")
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{name: "nil", err: nil, want: KindInternal},
+		{name: "plain error", err: cause, want: KindInternal},
+		{name: "typed", err: New(KindNotFound, "product not found"), want: KindNotFound},
+		{name: "wrapped by fmt", err: fmt.Errorf("failed to get session: %w", New(KindForbidden, "")), want: KindForbidden},
+		{name: "outermost kind wins", err: Wrap(KindUpstreamUnavailable, "", New(KindInvalidInput, "")), want: KindUpstreamUnavailable},
+		{name: "deadline exceeded", err: fmt.Errorf("call: %w", context.DeadlineExceeded), want: KindUpstreamTimeout},
+		{name: "canceled", err: context.Canceled, want: KindInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if Is(nil, KindInternal) {
+		t.Error("Is(nil, KindInternal) = true")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	cause := errors.New("dify api error: status=502, body=<html>")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "custom message", err: New(KindInvalidInput, "query is required"), want: "query is required"},
+		{name: "default message", err: Wrap(KindUpstreamUnavailable, "", cause), want: "upstream service unavailable"},
+		{name: "cause is hidden", err: fmt.Errorf("executor: %w", Wrap(KindUpstreamTimeout, "assistant service timed out", cause)), want: "assistant service timed out"},
+		{name: "internal message is hidden", err: New(KindInternal, "sql: no rows in users"), want: "internal server error"},
+		{name: "untyped error", err: cause, want: "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Message(tt.err); got != tt.want {
+				t.Errorf("Message() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	sentinel := New(KindConflict, "user already exists")
+	err := Wrap(KindConflict, "email already registered", sentinel)
+	if !errors.Is(err, sentinel) {
+		t.Error("errors.Is() through Wrap = false")
+	}
+	if got := err.Error(); got != "email already registered: user already exists" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := Wrap(KindRateLimited, "", nil).Error(); got != "too many requests" {
+		t.Errorf("Error() without message = %q", got)
+	}
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound 密钥集合中没有对应 kid 的密钥
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// minKeyReloadInterval 未知 kid 触发重新加载的最小间隔，避免伪造 kid 的请求频繁读盘
+const minKeyReloadInterval = time.Second
+
+// jwk JSON Web Key，支持 RSA 公钥（kty=RSA）与对称密钥（kty=oct）
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// KeySet 从 JWKS 文件加载的校验密钥，按 kid 查找
+// 文件修改后在下一次检查时重新加载：定期检查，或遇到未知 kid 时立即检查，以支持密钥轮换
+type KeySet struct {
+	path     string
+	interval time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]interface{} // kid -> *rsa.PublicKey / []byte
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// LoadKeySet 加载 JWKS 文件
+func LoadKeySet(path string, interval time.Duration) (*KeySet, error) {
+	ks := &KeySet{
+		path:     path,
+		interval: interval,
+	}
+	if err := ks.reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Key 按 kid 查找密钥；kid 为空且集合中只有一个密钥时返回该密钥
+func (ks *KeySet) Key(kid string) (interface{}, error) {
+	ks.mu.RLock()
+	key, ok := ks.lookup(kid)
+	checkedAt := ks.checkedAt
+	ks.mu.RUnlock()
+
+	since := time.Since(checkedAt)
+	if (ok && since < ks.interval) || (!ok && since < minKeyReloadInterval) {
+		if !ok {
+			return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+		}
+		return key, nil
+	}
+
+	if err := ks.reloadIfModified(); err != nil {
+		// 重新加载失败时沿用已加载的密钥
+		slog.Warn("failed to reload jwks file, keeping loaded keys", "path", ks.path, "error", err)
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+}
+
+func (ks *KeySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+func (ks *KeySet) reloadIfModified() error {
+	info, err := os.Stat(ks.path)
+
+	ks.mu.Lock()
+	ks.checkedAt = time.Now()
+	unchanged := err == nil && info.ModTime().Equal(ks.modTime)
+	ks.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if unchanged {
+		return nil
+	}
+	return ks.reload()
+}
+
+func (ks *KeySet) reload() error {
+	info, err := os.Stat(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat jwks file: %w", err)
+	}
+	data, err := os.ReadFile(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed to read jwks file: %w", err)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse jwks file: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.key()
+		if err != nil {
+			return fmt.Errorf("invalid jwk %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("jwks file %s contains no signing keys", ks.path)
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.modTime = info.ModTime()
+	ks.checkedAt = time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+func (k *jwk) key() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("invalid symmetric key: %w", err)
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSymmetricKey = "jwks-symmetric-key-0123456789abcdef"
+
+// testKeys 测试用的密钥与 JWKS 文件：rsa-1 为 RSA 公钥，hs-1 为对称密钥
+type testKeys struct {
+	rsaKey      *rsa.PrivateKey
+	jwksFile    string
+	privateFile string
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	dir := t.TempDir()
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	doc := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "oct", "kid": "hs-1", "use": "sig", "k": b64([]byte(testSymmetricKey))},
+			{"kty": "oct", "kid": "enc-1", "use": "enc", "k": b64([]byte("encryption-only"))},
+		},
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	jwksFile := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(jwksFile, data, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+
+	privateFile := filepath.Join(dir, "private.pem")
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+	if err := os.WriteFile(privateFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("write private key: %v", err)
+	}
+	return &testKeys{rsaKey: rsaKey, jwksFile: jwksFile, privateFile: privateFile}
+}
+
+// signToken 按指定算法与 kid 签发 access token
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	now := time.Now()
+	token := jwt.NewWithClaims(method, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user_1",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		TokenType: TokenTypeAccess,
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestTokenManagerKeySelection(t *testing.T) {
+	keys := newTestKeys(t)
+	rsaPublicDER := x509.MarshalPKCS1PublicKey(&keys.rsaKey.PublicKey)
+
+	hs256 := &config.AuthConfig{Algorithm: AlgorithmHS256, JWTSecret: "config-secret", JWKSFile: keys.jwksFile}
+	rs256 := &config.AuthConfig{Algorithm: AlgorithmRS256, JWKSFile: keys.jwksFile}
+
+	tests := []struct {
+		name   string
+		cfg    *config.AuthConfig
+		token  func(t *testing.T) string
+		wantOK bool
+	}{
+		{
+			name: "HS256 kid selects symmetric key from jwks",
+			cfg:  hs256,
+			token: func(t *testing.T) string {
+				return signToken(t, jwt.SigningMethodHS256, "hs-1", []byte(testSymmetricKey))
+			},
+			wantOK: true,
+		},
+		{
+			name:   "HS256 without kid uses jwt_secret",
+			cfg:    hs256,
+			token:  func(t *testing.T) string { return signToken(t, jwt.SigningMethodHS256, "", []byte("config-secret")) },
+			wantOK: true,
+		},
+		{
+			name: "HS256 kid does not fall back to jwt_secret",
+			cfg:  hs256,
+			token: func(t *testing.T) string {
+				return signToken(t, jwt.SigningMethodHS256, "hs-1", []byte("config-secret"))
+			},
+		},
+		{
+			name: "HS256 unknown kid",
+			cfg:  hs256,
+			token: func(t *testing.T) string {
+				return signToken(t, jwt.SigningMethodHS256, "missing", []byte(testSymmetricKey))
+			},
+		},
+		{
+			name: "HS256 kid of encryption key",
+			cfg:  hs256,
+			token: func(t *testing.T) string {
+				return signToken(t, jwt.SigningMethodHS256, "enc-1", []byte("encryption-only"))
+			},
+		},
+		{
+			// 算法混淆：以 RSA 公钥作为 HMAC 密钥伪造令牌
+			name:  "HS256 kid of RSA public key",
+			cfg:   hs256,
+			token: func(t *testing.T) string { return signToken(t, jwt.SigningMethodHS256, "rsa-1", rsaPublicDER) },
+		},
+		{
+			name:  "RS256 token rejected by HS256 manager",
+			cfg:   hs256,
+			token: func(t *testing.T) string { return signToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsaKey) },
+		},
+		{
+			name:   "RS256 kid selects RSA public key",
+			cfg:    rs256,
+			token:  func(t *testing.T) string { return signToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsaKey) },
+			wantOK: true,
+		},
+		{
+			name:  "RS256 kid of symmetric key",
+			cfg:   rs256,
+			token: func(t *testing.T) string { return signToken(t, jwt.SigningMethodRS256, "hs-1", keys.rsaKey) },
+		},
+		{
+			name:  "RS256 without kid and multiple keys",
+			cfg:   rs256,
+			token: func(t *testing.T) string { return signToken(t, jwt.SigningMethodRS256, "", keys.rsaKey) },
+		},
+		{
+			// 算法混淆：RS256 服务端收到以 RSA 公钥为 HMAC 密钥签发的 HS256 令牌
+			name:  "HS256 token rejected by RS256 manager",
+			cfg:   rs256,
+			token: func(t *testing.T) string { return signToken(t, jwt.SigningMethodHS256, "rsa-1", rsaPublicDER) },
+		},
+		{
+			name: "alg none rejected",
+			cfg:  rs256,
+			token: func(t *testing.T) string {
+				return signToken(t, jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewTokenManager(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewTokenManager() error = %v", err)
+			}
+			claims, err := m.Parse(tt.token(t), TokenTypeAccess)
+			if tt.wantOK {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if claims.Subject != "user_1" {
+					t.Errorf("subject = %q, want user_1", claims.Subject)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Parse() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestTokenManagerIssueRS256(t *testing.T) {
+	keys := newTestKeys(t)
+	m, err := NewTokenManager(&config.AuthConfig{
+		Algorithm:      AlgorithmRS256,
+		PrivateKeyFile: keys.privateFile,
+		SigningKeyID:   "rsa-1",
+		JWKSFile:       keys.jwksFile,
+		TokenExpire:    time.Hour,
+		RefreshExpire:  24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenManager() error = %v", err)
+	}
+
+	pair, err := m.Issue("user_1", []string{RoleAdmin})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	claims, err := m.Parse(pair.AccessToken, TokenTypeAccess)
+	if err != nil {
+		t.Fatalf("Parse(access) error = %v", err)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != RoleAdmin {
+		t.Errorf("roles = %v, want [admin]", claims.Roles)
+	}
+	if _, err := m.Parse(pair.RefreshToken, TokenTypeAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Parse(refresh as access) error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestKeySetSingleKeyWithoutKid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	k := base64.RawURLEncoding.EncodeToString([]byte(testSymmetricKey))
+	if err := os.WriteFile(path, []byte(`{"keys":[{"kty":"oct","kid":"only","k":"`+k+`"}]}`), 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	ks, err := LoadKeySet(path, time.Minute)
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+
+	tests := []struct {
+		kid    string
+		wantOK bool
+	}{
+		{kid: "", wantOK: true},
+		{kid: "only", wantOK: true},
+		{kid: "other"},
+	}
+	for _, tt := range tests {
+		key, err := ks.Key(tt.kid)
+		if tt.wantOK {
+			if secret, ok := key.([]byte); err != nil || !ok || string(secret) != testSymmetricKey {
+				t.Errorf("Key(%q) = %v, %v, want symmetric key", tt.kid, key, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Key(%q) error = %v, want ErrKeyNotFound", tt.kid, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GuestPrefix 访客用户ID前缀
+const GuestPrefix = "guest_"
+
+// RoleAdmin 管理员角色，可访问 /admin 接口
+const RoleAdmin = "admin"
+
+// Principal 调用方身份
+type Principal struct {
+	UserID string   `json:"user_id"`
+	Guest  bool     `json:"guest"`           // 未登录访客，没有持久化画像
+	Roles  []string `json:"roles,omitempty"` // 来自令牌的 roles 声明
+	// MerchantID 来自令牌的 merchant_id 声明，为空时使用 business.session.merchant_id
+	MerchantID string `json:"merchant_id,omitempty"`
+}
+
+type principalKey struct{}
+
+// NewGuest 生成新的访客身份
+func NewGuest() *Principal {
+	return &Principal{
+		UserID: GuestPrefix + uuid.New().String(),
+		Guest:  true,
+	}
+}
+
+// ParseGuest 解析客户端回传的访客ID，格式不合法时返回 false
+func ParseGuest(id string) (*Principal, bool) {
+	if !strings.HasPrefix(id, GuestPrefix) {
+		return nil, false
+	}
+	if _, err := uuid.Parse(strings.TrimPrefix(id, GuestPrefix)); err != nil {
+		return nil, false
+	}
+	return &Principal{UserID: id, Guest: true}, true
+}
+
+// HasRole 是否拥有指定角色
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGuestID 用户ID是否为访客ID
+func IsGuestID(userID string) bool {
+	return strings.HasPrefix(userID, GuestPrefix)
+}
+
+// WithPrincipal 将调用方身份写入 context
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext 读取调用方身份
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"shopping-guide-backend/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// 令牌类型
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// 支持的签名算法
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// ErrInvalidToken 令牌无效、过期或类型不符
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims JWT 声明
+type Claims struct {
+	jwt.RegisteredClaims
+	TokenType string   `json:"typ"`
+	Roles     []string `json:"roles,omitempty"`
+	// MerchantID 商家ID，由商家平台签发（经 JWKS 校验）的令牌携带，本服务签发的令牌不带
+	MerchantID string `json:"merchant_id,omitempty"`
+}
+
+// TokenPair 登录/刷新签发的令牌
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration // access token 有效期
+}
+
+// TokenManager 按 middleware.auth 配置签发与校验 JWT
+// HS256 使用 jwt_secret（或 JWKS 中 kty=oct 的密钥）；RS256 使用私钥签发、JWKS 中的公钥校验
+type TokenManager struct {
+	cfg        *config.AuthConfig
+	method     jwt.SigningMethod
+	privateKey *rsa.PrivateKey
+	keys       *KeySet
+}
+
+// NewTokenManager 创建令牌管理器，加载签发私钥与 JWKS
+func NewTokenManager(cfg *config.AuthConfig) (*TokenManager, error) {
+	m := &TokenManager{cfg: cfg}
+
+	switch cfg.Algorithm {
+	case "", AlgorithmHS256:
+		m.method = jwt.SigningMethodHS256
+	case AlgorithmRS256:
+		m.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm: %s", cfg.Algorithm)
+	}
+
+	if cfg.JWKSFile != "" {
+		interval := cfg.JWKSReloadInterval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		keys, err := LoadKeySet(cfg.JWKSFile, interval)
+		if err != nil {
+			return nil, err
+		}
+		m.keys = keys
+	}
+
+	if m.method == jwt.SigningMethodRS256 {
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read jwt private key: %w", err)
+			}
+			if m.privateKey, err = jwt.ParseRSAPrivateKeyFromPEM(data); err != nil {
+				return nil, fmt.Errorf("failed to parse jwt private key: %w", err)
+			}
+		}
+		if m.privateKey == nil && m.keys == nil {
+			return nil, fmt.Errorf("RS256 requires private_key_file or jwks_file")
+		}
+	}
+
+	return m, nil
+}
+
+// Issue 为用户签发 access token 与 refresh token
+// roles 只写入 access token；刷新时按用户当前角色重新签发，撤销角色在 access token 过期后生效
+func (m *TokenManager) Issue(userID string, roles []string) (*TokenPair, error) {
+	access, err := m.sign(userID, TokenTypeAccess, roles, m.cfg.TokenExpire)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := m.sign(userID, TokenTypeRefresh, nil, m.cfg.RefreshExpire)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    m.cfg.TokenExpire,
+	}, nil
+}
+
+// Parse 校验令牌签名、有效期、签发方、受众与类型，返回声明
+func (m *TokenManager) Parse(tokenString, tokenType string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{m.method.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(m.cfg.ClockSkew),
+	}
+	if m.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.cfg.Issuer))
+	}
+	if m.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(m.cfg.Audience))
+	}
+
+	var claims Claims
+	if _, err := jwt.ParseWithClaims(tokenString, &claims, m.verificationKey, opts...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.TokenType != tokenType || claims.Subject == "" {
+		return nil, fmt.Errorf("%w: unexpected token type %q", ErrInvalidToken, claims.TokenType)
+	}
+	return &claims, nil
+}
+
+// verificationKey 按令牌头部的 kid 选择校验密钥
+func (m *TokenManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if m.method == jwt.SigningMethodHS256 {
+		if m.keys != nil && kid != "" {
+			key, err := m.keys.Key(kid)
+			if err != nil {
+				return nil, err
+			}
+			if secret, ok := key.([]byte); ok {
+				return secret, nil
+			}
+			return nil, fmt.Errorf("key %q is not a symmetric key", kid)
+		}
+		if m.cfg.JWTSecret == "" {
+			return nil, fmt.Errorf("jwt secret is not configured")
+		}
+		return []byte(m.cfg.JWTSecret), nil
+	}
+
+	if m.keys == nil {
+		return &m.privateKey.PublicKey, nil
+	}
+	key, err := m.keys.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if pub, ok := key.(*rsa.PublicKey); ok {
+		return pub, nil
+	}
+	return nil, fmt.Errorf("key %q is not an RSA public key", kid)
+}
+
+func (m *TokenManager) sign(userID, tokenType string, roles []string, ttl time.Duration) (string, error) {
+	var key interface{}
+	if m.method == jwt.SigningMethodHS256 {
+		if m.cfg.JWTSecret == "" {
+			return "", fmt.Errorf("jwt secret is not configured")
+		}
+		key = []byte(m.cfg.JWTSecret)
+	} else {
+		if m.privateKey == nil {
+			return "", fmt.Errorf("jwt private key is not configured")
+		}
+		key = m.privateKey
+	}
+
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    m.cfg.Issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		TokenType: tokenType,
+		Roles:     roles,
+	}
+	if m.cfg.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{m.cfg.Audience}
+	}
+
+	token := jwt.NewWithClaims(m.method, claims)
+	if m.cfg.SigningKeyID != "" {
+		token.Header["kid"] = m.cfg.SigningKeyID
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/tracing"
+)
+
+// 熔断器状态
+const (
+	breakerClosed   = "closed"
+	breakerHalfOpen = "half_open"
+	breakerOpen     = "open"
+)
+
+// breakerStateValue 状态对应的 dify_circuit_breaker_state 指标值
+var breakerStateValue = map[string]float64{
+	breakerClosed:   0,
+	breakerHalfOpen: 1,
+	breakerOpen:     2,
+}
+
+// ErrCircuitOpen 熔断期间拒绝调用
+var ErrCircuitOpen = errors.New("dify circuit breaker is open")
+
+// circuitBreaker Dify 调用熔断器
+// 连续失败达到 failure_threshold 后打开，open_duration 内直接拒绝；到期后进入半开，只放行一个探测请求，
+// 成功则关闭，失败则重新打开。配置按 business.circuit_breaker 热更新，关闭时不拦截也不计数
+type circuitBreaker struct {
+	store *config.Store
+	now   func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // 半开状态下是否已有探测请求在进行
+}
+
+func newCircuitBreaker(store *config.Store) *circuitBreaker {
+	return &circuitBreaker{
+		store: store,
+		now:   time.Now,
+		state: breakerClosed,
+	}
+}
+
+// allow 调用前检查，熔断期间返回 ErrCircuitOpen；放行时返回的 done 须在调用结束后以调用结果调用一次
+func (b *circuitBreaker) allow(ctx context.Context) (done func(err error), err error) {
+	cfg := b.cfg()
+	if !cfg.Enabled {
+		return func(error) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen && b.now().Sub(b.openedAt) >= cfg.OpenDuration {
+		b.transition(ctx, breakerHalfOpen)
+	}
+	switch {
+	case b.state == breakerOpen, b.state == breakerHalfOpen && b.probing:
+		tracing.AddBreakerRejectedEvent(ctx)
+		return nil, ErrCircuitOpen
+	case b.state == breakerHalfOpen:
+		b.probing = true
+	}
+	return func(err error) { b.record(ctx, err) }, nil
+}
+
+// record 记录调用结果，只有上游故障计为失败；调用方取消的调用不计入
+func (b *circuitBreaker) record(ctx context.Context, err error) {
+	failed, counted := breakerOutcome(err)
+	cfg := b.cfg()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		switch {
+		case !counted:
+		case failed:
+			b.open(ctx)
+		default:
+			b.failures = 0
+			b.transition(ctx, breakerClosed)
+		}
+		return
+	}
+	if !counted || b.state != breakerClosed {
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= cfg.FailureThreshold {
+		b.open(ctx)
+	}
+}
+
+func (b *circuitBreaker) open(ctx context.Context) {
+	b.openedAt = b.now()
+	b.transition(ctx, breakerOpen)
+}
+
+// transition 切换状态，记录 span 事件、日志与指标；调用方持有锁
+func (b *circuitBreaker) transition(ctx context.Context, to string) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	tracing.AddBreakerStateEvent(ctx, from, to)
+	metrics.DifyCircuitBreakerState.Set(breakerStateValue[to])
+
+	log := logger.FromContext(ctx)
+	if to == breakerOpen {
+		log.Warn("dify circuit breaker opened", "from", from, "open_duration", b.cfg().OpenDuration)
+	} else {
+		log.Info("dify circuit breaker state changed", "from", from, "to", to)
+	}
+}
+
+// breakerOutcome 调用结果分类：counted 为 false 表示调用方取消，不反映 Dify 状态；
+// 连接失败、超时、429/5xx 与工作流执行失败计为失败，其他 4xx 说明 Dify 可达，按成功处理
+func breakerOutcome(err error) (failed, counted bool) {
+	switch {
+	case err == nil:
+		return false, true
+	case errors.Is(err, context.Canceled):
+		return false, false
+	}
+	var se *statusError
+	if errors.As(err, &se) && se.StatusCode < http.StatusInternalServerError && se.StatusCode != http.StatusTooManyRequests {
+		return false, true
+	}
+	return true, true
+}
+
+// cfg 当前生效的熔断配置
+func (b *circuitBreaker) cfg() *config.CircuitBreakerConfig {
+	return &b.store.Get().Business.CircuitBreaker
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestBreakerClient 开启熔断的 Dify 客户端，连续失败 2 次熔断，返回可调整的当前时间
+func newTestBreakerClient(t *testing.T, handler http.HandlerFunc) (DifyClient, *time.Time) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfg := &config.Config{}
+	cfg.Dify.BaseURL = srv.URL
+	cfg.Business.Retry.MaxAttempts = 1
+	cfg.Business.CircuitBreaker = config.CircuitBreakerConfig{Enabled: true, FailureThreshold: 2, OpenDuration: time.Minute}
+	c := NewDifyClient(config.NewStore(cfg)).(*difyClient)
+
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusBadGateway)
+	c, now := newTestBreakerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		w.Write([]byte(`{"workflow_run_id":"run-1","data":{"status":"succeeded"}}`))
+	})
+	ctx := context.Background()
+
+	// 连续失败达到阈值后熔断，熔断期间不再请求 Dify
+	for i := 0; i < 3; i++ {
+		if _, err := c.CallWorkflow(ctx, "app", nil, "user_1"); !apperr.Is(err, apperr.KindUpstreamUnavailable) {
+			t.Fatalf("call %d: error = %v, want upstream unavailable", i, err)
+		}
+	}
+	if _, err := c.CallWorkflowStream(ctx, "app", nil, "user_1"); !apperr.Is(err, apperr.KindUpstreamUnavailable) {
+		t.Errorf("CallWorkflowStream() error = %v, want upstream unavailable", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("dify calls = %d, want 2 before the breaker opens", got)
+	}
+
+	// 到期后放行探测请求，探测失败重新熔断
+	*now = now.Add(time.Minute)
+	if _, err := c.CallWorkflow(ctx, "app", nil, "user_1"); err == nil {
+		t.Fatal("probe succeeded, want failure")
+	}
+	if _, err := c.CallWorkflow(ctx, "app", nil, "user_1"); err == nil || calls.Load() != 3 {
+		t.Fatalf("call after failed probe: error = %v, calls = %d", err, calls.Load())
+	}
+
+	// 探测成功后恢复
+	*now = now.Add(time.Minute)
+	status.Store(http.StatusOK)
+	for i := 0; i < 2; i++ {
+		if _, err := c.CallWorkflow(ctx, "app", nil, "user_1"); err != nil {
+			t.Fatalf("call %d after recovery: error = %v", i, err)
+		}
+	}
+	if got := calls.Load(); got != 5 {
+		t.Errorf("dify calls = %d, want 5", got)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestBreakerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	// 4xx 说明 Dify 可达，不计入熔断；调用方取消同样不计入
+	for i := 0; i < 3; i++ {
+		c.CallWorkflow(context.Background(), "app", nil, "user_1")
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 2; i++ {
+		c.CallWorkflow(canceled, "app", nil, "user_1")
+	}
+	if _, err := c.CallWorkflow(context.Background(), "app", nil, "user_1"); err == nil || calls.Load() != 4 {
+		t.Errorf("error = %v, calls = %d, want the request to reach dify", err, calls.Load())
+	}
+}
+
+func TestCircuitBreakerSpanEvents(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	c, now := newTestBreakerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	for i := 0; i < 3; i++ {
+		c.CallWorkflow(context.Background(), "app", nil, "user_1")
+	}
+	*now = now.Add(time.Minute)
+	c.CallWorkflow(context.Background(), "app", nil, "user_1")
+
+	var got []string
+	for _, span := range recorder.Ended() {
+		for _, event := range span.Events() {
+			if !strings.HasPrefix(event.Name, "circuit_breaker.") {
+				continue
+			}
+			name := event.Name
+			for _, attr := range event.Attributes {
+				name += " " + attr.Value.AsString()
+			}
+			got = append(got, name)
+		}
+	}
+	want := []string{
+		tracing.EventBreakerState + " closed open",
+		tracing.EventBreakerRejected,
+		tracing.EventBreakerState + " open half_open",
+		tracing.EventBreakerState + " half_open open",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("events[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 type DifyClient interface {
 	CallWorkflow(ctx context.Context, appID string, inputs map[string]interface{}, user string) (*model.DifyWorkflowResponse, error)
 	CallWorkflowStream(ctx context.Context, appID string, inputs map[string]interface{}, user string) (<-chan model.StreamChunk, error)
+	// Ping 探测 Dify 是否可达，收到非 5xx 响应即视为可达
+	Ping(ctx context.Context) error
 }
 
 // SearchClient 搜推系统客户端接口
@@ -0,0 +1,383 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/requestctx"
+	"shopping-guide-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// difyClient Dify工作流客户端实现
+// Dify 以应用 API Key 区分工作流，appID 即对应应用的 API Key，为空时回退到全局 api_key
+type difyClient struct {
+	cfg        *config.DifyConfig
+	store      *config.Store
+	httpClient *http.Client
+	breaker    *circuitBreaker
+}
+
+// NewDifyClient 创建Dify客户端
+// 阻塞调用按 business.retry 配置对网络错误与 429/5xx 重试，流式调用不重试；重试配置可热更新，地址与密钥修改后需重启
+// dify.timeout 通过每次请求的 context 只约束阻塞调用与探活，流式调用的时长由调用方 context 控制，
+// 不设置 http.Client.Timeout，避免长时间的 SSE 响应被中途截断
+// 工作流调用经 business.circuit_breaker 熔断，熔断期间直接返回上游不可用，探活不受熔断影响
+func NewDifyClient(store *config.Store) DifyClient {
+	return &difyClient{
+		cfg:        &store.Get().Dify,
+		store:      store,
+		httpClient: &http.Client{},
+		breaker:    newCircuitBreaker(store),
+	}
+}
+
+// statusError Dify 返回非 200 状态码
+type statusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("dify api error: status=%d, body=%s", e.StatusCode, e.Body)
+}
+
+// CallWorkflow 以阻塞模式调用工作流
+func (c *difyClient) CallWorkflow(ctx context.Context, appID string, inputs map[string]interface{}, user string) (result *model.DifyWorkflowResponse, err error) {
+	ctx, span := c.startSpan(ctx, "blocking")
+	defer func() {
+		if result != nil {
+			span.SetAttributes(
+				tracing.AttrWorkflowRunID.String(result.WorkflowRunID),
+				tracing.AttrTokensUsed.Int(result.Data.TotalTokens),
+			)
+		}
+		tracing.End(span, err)
+	}()
+
+	done, err := c.breaker.allow(ctx)
+	if err != nil {
+		return nil, upstreamError(err)
+	}
+	// 熔断只统计重试后的最终结果
+	defer func() { done(err) }()
+
+	request := model.DifyWorkflowRequest{
+		Inputs:       inputs,
+		ResponseMode: "blocking",
+		User:         user,
+	}
+	for attempt := 1; ; attempt++ {
+		result, err = c.callBlocking(ctx, appID, request)
+		if err == nil || attempt >= c.retry().MaxAttempts || !retryable(ctx, err) {
+			return result, upstreamError(err)
+		}
+
+		delay := c.backoff(attempt)
+		tracing.AddRetryEvent(ctx, attempt, err)
+		logger.FromContext(ctx).Warn("dify call failed, retrying",
+			"workflow", workflowName(ctx), "attempt", attempt, "delay", delay, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, upstreamError(err)
+		}
+	}
+}
+
+func (c *difyClient) callBlocking(ctx context.Context, appID string, request model.DifyWorkflowRequest) (*model.DifyWorkflowResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, appID, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result model.DifyWorkflowResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w, body=%s", err, string(body))
+	}
+	if result.Data.Status != "succeeded" {
+		return nil, fmt.Errorf("workflow failed: status=%s, error=%s", result.Data.Status, result.Data.Error)
+	}
+
+	return &result, nil
+}
+
+// CallWorkflowStream 以流式模式调用工作流
+// text_chunk 事件转换为 chunk，workflow_finished 转换为 done，失败时发送 error 后关闭通道
+func (c *difyClient) CallWorkflowStream(ctx context.Context, appID string, inputs map[string]interface{}, user string) (<-chan model.StreamChunk, error) {
+	ctx, span := c.startSpan(ctx, "streaming")
+	done, err := c.breaker.allow(ctx)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, upstreamError(err)
+	}
+	resp, err := c.do(ctx, appID, model.DifyWorkflowRequest{
+		Inputs:       inputs,
+		ResponseMode: "streaming",
+		User:         user,
+	})
+	if err != nil {
+		done(err)
+		tracing.End(span, err)
+		return nil, upstreamError(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		err := &statusError{StatusCode: resp.StatusCode, Body: string(body)}
+		done(err)
+		tracing.End(span, err)
+		return nil, upstreamError(err)
+	}
+
+	ch := make(chan model.StreamChunk)
+	go func() {
+		// span 在流结束时关闭，done 事件补充运行ID与 tokens，error 事件记录错误；
+		// 调用方中途取消时不计入熔断
+		var streamErr error
+		defer func() {
+			if ctx.Err() != nil {
+				done(context.Canceled)
+			} else {
+				done(streamErr)
+			}
+			tracing.End(span, streamErr)
+		}()
+		defer close(ch)
+		defer resp.Body.Close()
+
+		send := func(chunk model.StreamChunk) bool {
+			switch chunk.Event {
+			case model.StreamEventError:
+				streamErr = fmt.Errorf("%v", chunk.Data)
+			case model.StreamEventDone:
+				if result, ok := chunk.Data.(*model.DifyWorkflowResponse); ok {
+					span.SetAttributes(
+						tracing.AttrWorkflowRunID.String(result.WorkflowRunID),
+						tracing.AttrTokensUsed.Int(result.Data.TotalTokens),
+					)
+				}
+			}
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+			var raw struct {
+				Event         string          `json:"event"`
+				WorkflowRunID string          `json:"workflow_run_id"`
+				Data          json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal([]byte(payload), &raw); err != nil {
+				continue
+			}
+
+			switch raw.Event {
+			case "text_chunk":
+				var data struct {
+					Text string `json:"text"`
+				}
+				if err := json.Unmarshal(raw.Data, &data); err == nil && data.Text != "" {
+					if !send(model.StreamChunk{Event: model.StreamEventChunk, Data: data.Text}) {
+						return
+					}
+				}
+			case "workflow_finished":
+				var data model.DifyWorkflowRunData
+				if err := json.Unmarshal(raw.Data, &data); err != nil {
+					send(model.StreamChunk{Event: model.StreamEventError, Data: fmt.Sprintf("failed to unmarshal workflow result: %v", err)})
+					return
+				}
+				if data.Status != "succeeded" {
+					send(model.StreamChunk{Event: model.StreamEventError, Data: fmt.Sprintf("workflow failed: status=%s, error=%s", data.Status, data.Error)})
+					return
+				}
+				send(model.StreamChunk{Event: model.StreamEventDone, Data: &model.DifyWorkflowResponse{
+					WorkflowRunID: raw.WorkflowRunID,
+					Data:          data,
+				}})
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			send(model.StreamChunk{Event: model.StreamEventError, Data: fmt.Sprintf("failed to read stream: %v", err)})
+		}
+	}()
+
+	return ch, nil
+}
+
+// Ping 探测 Dify 是否可达
+// 请求 /parameters，未配置或无效的 api_key 返回 401 同样说明服务可达
+func (c *difyClient) Ping(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(c.cfg.BaseURL, "/")+"/parameters", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return &statusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// startSpan 创建 Dify 调用 span，工作流名称来自 WithWorkflowName
+func (c *difyClient) startSpan(ctx context.Context, mode string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "dify.workflow.run",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			tracing.AttrWorkflow.String(workflowName(ctx)),
+			attribute.String("dify.response_mode", mode),
+			semconv.HTTPRequestMethodPost,
+			semconv.URLFull(c.workflowURL()),
+		),
+	)
+}
+
+// withTimeout 单次阻塞请求的超时，调用方 context 的截止时间更早时以调用方为准
+func (c *difyClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.cfg.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.cfg.Timeout)
+}
+
+func (c *difyClient) workflowURL() string {
+	return strings.TrimRight(c.cfg.BaseURL, "/") + "/workflows/run"
+}
+
+// backoff 第 attempt 次失败后的等待时间，按 multiplier 指数增长，不超过 max_delay
+func (c *difyClient) backoff(attempt int) time.Duration {
+	retry := c.retry()
+	delay := retry.InitialDelay
+	for i := 1; i < attempt && retry.Multiplier > 1; i++ {
+		delay = time.Duration(float64(delay) * retry.Multiplier)
+		if retry.MaxDelay > 0 && delay >= retry.MaxDelay {
+			return retry.MaxDelay
+		}
+	}
+	return delay
+}
+
+// retry 当前生效的重试配置
+func (c *difyClient) retry() *config.RetryConfig {
+	return &c.store.Get().Business.Retry
+}
+
+// upstreamError 按类别包装 Dify 调用错误：超时为上游超时，其余为上游不可用；调用方取消时原样返回
+// 原因中的 Dify 响应体与请求地址只进日志，返回给调用方的是固定说明
+func upstreamError(err error) error {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return err
+	case isTimeout(err):
+		return apperr.Wrap(apperr.KindUpstreamTimeout, "assistant service timed out", err)
+	default:
+		return apperr.Wrap(apperr.KindUpstreamUnavailable, "assistant service is unavailable", err)
+	}
+}
+
+// retryable 只重试请求未送达 Dify 的连接错误与 429/503；
+// 超时与其他错误时 Dify 可能已在执行工作流，重试会重复消耗 tokens，调用方取消时也不重试
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode == http.StatusServiceUnavailable
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return false
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+func (c *difyClient) do(ctx context.Context, appID string, request model.DifyWorkflowRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.workflowURL(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	apiKey := appID
+	if apiKey == "" {
+		apiKey = c.cfg.APIKey
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	// 透传请求ID与 trace 上下文，便于在 Dify 侧按请求关联日志；未启用追踪时使用请求关联信息中的 traceparent
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if m, ok := requestctx.FromContext(ctx); ok {
+		req.Header.Set(requestctx.HeaderRequestID, m.RequestID)
+		if req.Header.Get(requestctx.HeaderTraceparent) == "" {
+			req.Header.Set(requestctx.HeaderTraceparent, m.Traceparent())
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	return resp, nil
+}
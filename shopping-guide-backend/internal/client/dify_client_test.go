@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+)
+
+// newTestDifyClient 指向 handler 的 Dify 客户端，dify.timeout 为 timeout，不重试
+func newTestDifyClient(t *testing.T, timeout time.Duration, handler http.HandlerFunc) DifyClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfg := &config.Config{}
+	cfg.Dify.BaseURL = srv.URL
+	cfg.Dify.Timeout = timeout
+	cfg.Business.Retry.MaxAttempts = 1
+	return NewDifyClient(config.NewStore(cfg))
+}
+
+func TestDifyClientBlockingTimeout(t *testing.T) {
+	c := newTestDifyClient(t, 50*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+
+	_, err := c.CallWorkflow(context.Background(), "app", nil, "user_1")
+	if !apperr.Is(err, apperr.KindUpstreamTimeout) {
+		t.Errorf("CallWorkflow() error = %v, want upstream timeout", err)
+	}
+}
+
+func TestDifyClientStreamOutlivesTimeout(t *testing.T) {
+	c := newTestDifyClient(t, 50*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, text := range []string{"你好", "，", "世界"} {
+			fmt.Fprintf(w, "data: {\"event\":\"text_chunk\",\"data\":{\"text\":%q}}\n\n", text)
+			w.(http.Flusher).Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+		fmt.Fprint(w, "data: {\"event\":\"workflow_finished\",\"workflow_run_id\":\"run-1\",\"data\":{\"status\":\"succeeded\",\"total_tokens\":12,\"outputs\":{\"text\":\"你好，世界\"}}}\n\n")
+	})
+
+	ch, err := c.CallWorkflowStream(context.Background(), "app", nil, "user_1")
+	if err != nil {
+		t.Fatalf("CallWorkflowStream() error = %v", err)
+	}
+	var text string
+	var done *model.DifyWorkflowResponse
+	for chunk := range ch {
+		switch chunk.Event {
+		case "chunk":
+			text += chunk.Data.(string)
+		case "done":
+			done = chunk.Data.(*model.DifyWorkflowResponse)
+		case "error":
+			t.Fatalf("stream error after %q: %v", text, chunk.Data)
+		}
+	}
+	if text != "你好，世界" {
+		t.Errorf("text = %q, want 你好，世界", text)
+	}
+	if done == nil || done.WorkflowRunID != "run-1" || done.Data.TotalTokens != 12 {
+		t.Errorf("done = %+v, want run-1 with 12 tokens", done)
+	}
+}
+
+func TestDifyClientStreamHonoursCallerContext(t *testing.T) {
+	c := newTestDifyClient(t, 0, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"event\":\"text_chunk\",\"data\":{\"text\":\"你好\"}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	ch, err := c.CallWorkflowStream(ctx, "app", nil, "user_1")
+	if err != nil {
+		t.Fatalf("CallWorkflowStream() error = %v", err)
+	}
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("stream not closed after caller context expired")
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/requestctx"
+)
+
+// CallRecorder Dify 调用日志的接收方，实现方应异步落库，不阻塞调用链路
+type CallRecorder interface {
+	RecordDifyCall(ctx context.Context, log *model.DifyCallLog)
+}
+
+type workflowNameKey struct{}
+
+// WithWorkflowName 标记本次调用的工作流名称，写入调用日志
+func WithWorkflowName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, workflowNameKey{}, name)
+}
+
+func workflowName(ctx context.Context) string {
+	if name, ok := ctx.Value(workflowNameKey{}).(string); ok {
+		return name
+	}
+	return "unknown"
+}
+
+// recordingDifyClient 记录每次工作流调用日志与指标的 Dify 客户端装饰器
+type recordingDifyClient struct {
+	next     DifyClient
+	recorder CallRecorder
+}
+
+// NewRecordingDifyClient 创建记录调用日志的 Dify 客户端
+func NewRecordingDifyClient(next DifyClient, recorder CallRecorder) DifyClient {
+	return &recordingDifyClient{
+		next:     next,
+		recorder: recorder,
+	}
+}
+
+// CallWorkflow 调用工作流并记录调用日志
+func (c *recordingDifyClient) CallWorkflow(ctx context.Context, appID string, inputs map[string]interface{}, user string) (*model.DifyWorkflowResponse, error) {
+	start := time.Now()
+	resp, err := c.next.CallWorkflow(ctx, appID, inputs, user)
+	c.record(ctx, appID, inputs, start, resp, err)
+	return resp, err
+}
+
+// CallWorkflowStream 流式调用工作流，在收到 done/error 事件或通道关闭时记录调用日志
+func (c *recordingDifyClient) CallWorkflowStream(ctx context.Context, appID string, inputs map[string]interface{}, user string) (<-chan model.StreamChunk, error) {
+	start := time.Now()
+	stream, err := c.next.CallWorkflowStream(ctx, appID, inputs, user)
+	if err != nil {
+		c.record(ctx, appID, inputs, start, nil, err)
+		return nil, err
+	}
+
+	ch := make(chan model.StreamChunk)
+	go func() {
+		defer close(ch)
+		var (
+			resp     *model.DifyWorkflowResponse
+			result   error
+			finished bool
+		)
+		for chunk := range stream {
+			switch chunk.Event {
+			case model.StreamEventDone:
+				resp, _ = chunk.Data.(*model.DifyWorkflowResponse)
+				finished = true
+			case model.StreamEventError:
+				msg, _ := chunk.Data.(string)
+				result, finished = errors.New(msg), true
+			}
+			// 调用方不再读取时继续消费上游，直到上游因 ctx 结束而关闭通道
+			select {
+			case ch <- chunk:
+			case <-ctx.Done():
+			}
+		}
+		if !finished {
+			result = ctx.Err()
+			if result == nil {
+				result = errors.New("stream closed before workflow finished")
+			}
+		}
+		c.record(ctx, appID, inputs, start, resp, result)
+	}()
+	return ch, nil
+}
+
+// Ping 探测 Dify 是否可达，不记录调用日志
+func (c *recordingDifyClient) Ping(ctx context.Context) error {
+	return c.next.Ping(ctx)
+}
+
+func (c *recordingDifyClient) record(ctx context.Context, appID string, inputs map[string]interface{}, start time.Time, resp *model.DifyWorkflowResponse, err error) {
+	log := &model.DifyCallLog{
+		WorkflowName: workflowName(ctx),
+		AppID:        maskAppID(appID),
+		Inputs:       inputs,
+		Status:       model.DifyCallStatusSuccess,
+		LatencyMs:    int(time.Since(start).Milliseconds()),
+		CreatedAt:    start,
+	}
+	if m, ok := requestctx.FromContext(ctx); ok {
+		log.RequestID, log.TraceID = m.RequestID, m.TraceID
+	}
+	if resp != nil {
+		log.WorkflowRunID = resp.WorkflowRunID
+		log.Outputs = resp.Data.Outputs
+		log.TokensUsed = resp.Data.TotalTokens
+	}
+	if err != nil {
+		log.Status = model.DifyCallStatusError
+		log.ErrorMessage = err.Error()
+		if isTimeout(err) {
+			log.Status = model.DifyCallStatusTimeout
+		}
+	}
+	metrics.DifyCallDuration.WithLabelValues(log.WorkflowName, log.Status).Observe(time.Since(start).Seconds())
+	if log.TokensUsed > 0 {
+		metrics.DifyTokens.WithLabelValues(log.WorkflowName).Add(float64(log.TokensUsed))
+	}
+
+	// 调用方的 context 可能已超时或取消，日志写入不应受其影响
+	c.recorder.RecordDifyCall(context.WithoutCancel(ctx), log)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// maskAppID 应用 API Key 脱敏，只保留首尾各 4 位用于区分应用
+func maskAppID(appID string) string {
+	switch {
+	case appID == "":
+		return "default"
+	case len(appID) <= 8:
+		return "****"
+	default:
+		return appID[:4] + "****" + appID[len(appID)-4:]
+	}
+}
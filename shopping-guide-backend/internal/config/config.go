@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -14,22 +16,40 @@ type Config struct {
 	Redis      RedisConfig      `mapstructure:"redis"`
 	MySQL      MySQLConfig      `mapstructure:"mysql"`
 	Log        LogConfig        `mapstructure:"log"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
 	Middleware MiddlewareConfig `mapstructure:"middleware"`
 	Business   BusinessConfig   `mapstructure:"business"`
+
+	env           string         // 加载时的环境名（ENV）
+	secretSources []SecretSource // 各密钥的来源，加载时记录
+}
+
+// Env 加载配置时的环境名，如 dev、prod
+func (c *Config) Env() string {
+	return c.env
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port         int           `mapstructure:"port"`
-	Mode         string        `mapstructure:"mode"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	Port            int           `mapstructure:"port"`
+	Mode            string        `mapstructure:"mode"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`    // 不作用于 SSE 流
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 退出时等待进行中请求（含 SSE）结束的最长时间
+	TrustedProxies  []string      `mapstructure:"trusted_proxies"`  // 可信代理的 IP/CIDR，只信任来自这些地址的 X-Forwarded-For；为空时客户端IP取连接地址
+	Health          HealthConfig  `mapstructure:"health"`
+}
+
+// HealthConfig 就绪检查配置
+type HealthConfig struct {
+	Timeout   time.Duration `mapstructure:"timeout"`    // 单个依赖检查的超时
+	ProbeDify bool          `mapstructure:"probe_dify"` // 是否探测 Dify 可达性，Dify 不可达只降级不摘流
 }
 
 // DifyConfig Dify配置
 type DifyConfig struct {
 	BaseURL   string              `mapstructure:"base_url"`
-	APIKey    string              `mapstructure:"api_key"`
+	APIKey    string              `mapstructure:"api_key" redact:"true"`
 	Timeout   time.Duration       `mapstructure:"timeout"`
 	Workflows DifyWorkflowsConfig `mapstructure:"workflows"`
 }
@@ -42,14 +62,15 @@ type DifyWorkflowsConfig struct {
 
 // DifyWorkflowConfig 单个工作流配置
 type DifyWorkflowConfig struct {
-	AppID   string        `mapstructure:"app_id"`
-	Timeout time.Duration `mapstructure:"timeout"`
+	AppID         string        `mapstructure:"app_id" redact:"true"` // 工作流 API Key
+	Timeout       time.Duration `mapstructure:"timeout"`
+	PromptVersion string        `mapstructure:"prompt_version"` // 提示词版本，用于推荐转化归因
 }
 
 // RedisConfig Redis配置
 type RedisConfig struct {
 	Addr            string        `mapstructure:"addr"`
-	Password        string        `mapstructure:"password"`
+	Password        string        `mapstructure:"password" redact:"true"`
 	DB              int           `mapstructure:"db"`
 	PoolSize        int           `mapstructure:"pool_size"`
 	MinIdleConns    int           `mapstructure:"min_idle_conns"`
@@ -64,7 +85,7 @@ type MySQLConfig struct {
 	Host            string        `mapstructure:"host"`
 	Port            int           `mapstructure:"port"`
 	User            string        `mapstructure:"user"`
-	Password        string        `mapstructure:"password"`
+	Password        string        `mapstructure:"password" redact:"true"`
 	Database        string        `mapstructure:"database"`
 	Charset         string        `mapstructure:"charset"`
 	ParseTime       bool          `mapstructure:"parse_time"`
@@ -84,6 +105,28 @@ type LogConfig struct {
 	MaxBackups int    `mapstructure:"max_backups"`
 	MaxAge     int    `mapstructure:"max_age"`
 	Compress   bool   `mapstructure:"compress"`
+
+	Persist LogPersistConfig `mapstructure:"persist"`
+}
+
+// LogPersistConfig 对话日志与 Dify 调用日志落库配置，由后台 worker 异步写入 MySQL
+type LogPersistConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Workers   int           `mapstructure:"workers"`
+	QueueSize int           `mapstructure:"queue_size"` // 队列满时丢弃日志并告警，不阻塞请求
+	Timeout   time.Duration `mapstructure:"timeout"`    // 单条日志写库超时
+}
+
+// TracingConfig 链路追踪配置（OpenTelemetry）
+type TracingConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	ServiceName string            `mapstructure:"service_name"`
+	Exporter    string            `mapstructure:"exporter"`              // otlp/stdout/file
+	Endpoint    string            `mapstructure:"endpoint"`              // OTLP/HTTP 地址，如 otel-collector:4318
+	Insecure    bool              `mapstructure:"insecure"`              // OTLP 不使用 TLS
+	Headers     map[string]string `mapstructure:"headers" redact:"true"` // OTLP 请求头，如鉴权
+	FilePath    string            `mapstructure:"file_path"`             // exporter 为 file 时的输出文件
+	SampleRatio float64           `mapstructure:"sample_ratio"`          // 根 span 采样率，上游已采样的请求跟随上游
 }
 
 // MiddlewareConfig 中间件配置
@@ -94,46 +137,174 @@ type MiddlewareConfig struct {
 }
 
 // CORSConfig 跨域配置
+// 顶层策略作用于所有接口，groups 按路由组前缀（如 /admin、/internal）整体替换顶层策略
 type CORSConfig struct {
-	AllowOrigins     []string      `mapstructure:"allow_origins"`
+	CORSPolicy `mapstructure:",squash"`
+	Groups     map[string]CORSPolicy `mapstructure:"groups"`
+}
+
+// CORSPolicy 跨域策略，allow_origins 为空时不允许跨域访问
+type CORSPolicy struct {
+	AllowOrigins     []string      `mapstructure:"allow_origins"` // * 允许任意来源；支持 https://*.shop.example.com 匹配子域名
 	AllowMethods     []string      `mapstructure:"allow_methods"`
-	AllowHeaders     []string      `mapstructure:"allow_headers"`
+	AllowHeaders     []string      `mapstructure:"allow_headers"` // * 时回显预检请求的 Access-Control-Request-Headers
 	ExposeHeaders    []string      `mapstructure:"expose_headers"`
-	AllowCredentials bool          `mapstructure:"allow_credentials"`
-	MaxAge           time.Duration `mapstructure:"max_age"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"` // 不能与 * 来源同时使用
+	MaxAge           time.Duration `mapstructure:"max_age"`           // 预检结果缓存时间
 }
 
 // RateLimitConfig 限流配置
+// 计算周期为 redis.rate_limit_window，周期内允许 requests_per_minute 次请求
 type RateLimitConfig struct {
-	Enabled           bool `mapstructure:"enabled"`
-	RequestsPerMinute int  `mapstructure:"requests_per_minute"`
-	Burst             int  `mapstructure:"burst"`
+	Enabled           bool             `mapstructure:"enabled"`
+	RequestsPerMinute int              `mapstructure:"requests_per_minute"`
+	Burst             int              `mapstructure:"burst"`
+	Routes            []RouteRateLimit `mapstructure:"routes"` // 按接口覆盖默认限额
+}
+
+// RouteRateLimit 单个接口的限流配置
+type RouteRateLimit struct {
+	Method            string `mapstructure:"method"` // 为空时匹配所有方法
+	Path              string `mapstructure:"path"`   // 路由模板，如 /api/v1/sessions/:session_id
+	RequestsPerMinute int    `mapstructure:"requests_per_minute"`
+	Burst             int    `mapstructure:"burst"`
 }
 
 // AuthConfig 鉴权配置
 type AuthConfig struct {
-	Enabled     bool          `mapstructure:"enabled"`
-	JWTSecret   string        `mapstructure:"jwt_secret"`
-	TokenExpire time.Duration `mapstructure:"token_expire"`
+	Enabled            bool          `mapstructure:"enabled"`
+	Algorithm          string        `mapstructure:"algorithm"` // HS256 / RS256，默认 HS256
+	JWTSecret          string        `mapstructure:"jwt_secret" redact:"true"`
+	PrivateKeyFile     string        `mapstructure:"private_key_file"` // RS256 签发私钥（PEM），只校验不签发时可不配置
+	SigningKeyID       string        `mapstructure:"signing_key_id"`   // 签发令牌的 kid
+	JWKSFile           string        `mapstructure:"jwks_file"`        // 校验公钥/密钥集合，支持按 kid 轮换
+	JWKSReloadInterval time.Duration `mapstructure:"jwks_reload_interval"`
+	Issuer             string        `mapstructure:"issuer"`
+	Audience           string        `mapstructure:"audience"`
+	ClockSkew          time.Duration `mapstructure:"clock_skew"` // 校验 exp/nbf/iat 时容忍的时钟偏差
+	TokenExpire        time.Duration `mapstructure:"token_expire"`
+	RefreshExpire      time.Duration `mapstructure:"refresh_expire"`
+	ExemptPaths        []string      `mapstructure:"exempt_paths"` // 不鉴权的路径，支持前缀匹配（以 * 结尾）
+	GuestPaths         []string      `mapstructure:"guest_paths"`  // 允许不带令牌以访客身份访问的路径
+	// InternalToken /internal 接口的服务令牌，调用方通过 X-Internal-Token 请求头携带
+	InternalToken string `mapstructure:"internal_token" redact:"true"`
+	// TrustUserHeader 未携带令牌时信任 X-User-ID 请求头冒充任意用户，仅供本地调试，只允许在 dev 环境开启
+	TrustUserHeader bool `mapstructure:"trust_user_header"`
 }
 
 // BusinessConfig 业务配置
 type BusinessConfig struct {
-	Session SessionConfig `mapstructure:"session"`
-	Product ProductConfig `mapstructure:"product"`
-	Retry   RetryConfig   `mapstructure:"retry"`
+	Session        SessionConfig        `mapstructure:"session"`
+	Product        ProductConfig        `mapstructure:"product"`
+	Ranking        RankingConfig        `mapstructure:"ranking"`
+	Grounding      GroundingConfig      `mapstructure:"grounding"`
+	Enrichment     EnrichmentConfig     `mapstructure:"enrichment"`
+	Style          StyleConfig          `mapstructure:"style"`
+	Quota          QuotaConfig          `mapstructure:"quota"`
+	Retry          RetryConfig          `mapstructure:"retry"`
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
 }
 
 // SessionConfig 会话配置
 type SessionConfig struct {
 	MaxMessages  int    `mapstructure:"max_messages"`
 	DefaultStyle string `mapstructure:"default_style"`
+	MerchantID   string `mapstructure:"merchant_id"` // 令牌没有 merchant_id 声明时会话所属的商家，单商家部署时配置
 }
 
 // ProductConfig 商品配置
 type ProductConfig struct {
-	TopK        int  `mapstructure:"top_k"`
-	EnableCache bool `mapstructure:"enable_cache"`
+	TopK        int                 `mapstructure:"top_k"`
+	EnableCache bool                `mapstructure:"enable_cache"`
+	Search      ProductSearchConfig `mapstructure:"search"`
+}
+
+// ProductSearchConfig 商品检索配置
+type ProductSearchConfig struct {
+	Mode            string          `mapstructure:"mode"`             // keyword/vector/hybrid
+	HybridAlpha     float64         `mapstructure:"hybrid_alpha"`     // 混合检索时向量得分的权重(0~1)
+	IndexPath       string          `mapstructure:"index_path"`       // 向量索引持久化文件
+	RefreshInterval time.Duration   `mapstructure:"refresh_interval"` // 索引自动刷新间隔，0 表示只在启动后首次检索和管理接口触发时构建
+	Embedding       EmbeddingConfig `mapstructure:"embedding"`
+}
+
+// EmbeddingConfig 向量化配置
+type EmbeddingConfig struct {
+	Provider  string        `mapstructure:"provider"` // openai/hash
+	BaseURL   string        `mapstructure:"base_url"`
+	APIKey    string        `mapstructure:"api_key" redact:"true"`
+	Model     string        `mapstructure:"model"`
+	Dimension int           `mapstructure:"dimension"`
+	BatchSize int           `mapstructure:"batch_size"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// RankingConfig 推荐排序配置
+type RankingConfig struct {
+	Enabled           bool           `mapstructure:"enabled"`
+	Debug             bool           `mapstructure:"debug"`               // 是否允许请求返回得分明细
+	RecallMultiplier  int            `mapstructure:"recall_multiplier"`   // 召回数量 = top_k * recall_multiplier
+	HistoryWindow     time.Duration  `mapstructure:"history_window"`      // 行为数据回溯窗口
+	LowStockThreshold int            `mapstructure:"low_stock_threshold"` // 库存达到该值时库存得分为满分
+	Weights           RankingWeights `mapstructure:"weights"`
+}
+
+// RankingWeights 排序特征权重
+type RankingWeights struct {
+	Relevance        float64 `mapstructure:"relevance"`         // 检索相关性
+	Interest         float64 `mapstructure:"interest"`          // 画像兴趣匹配
+	CategoryAffinity float64 `mapstructure:"category_affinity"` // 历史行为类目偏好
+	Demographic      float64 `mapstructure:"demographic"`       // 年龄/性别适配
+	PriceBand        float64 `mapstructure:"price_band"`        // 与历史购买价位的接近程度
+	Popularity       float64 `mapstructure:"popularity"`        // 全站热度
+	Stock            float64 `mapstructure:"stock"`             // 库存充足程度
+	Diversity        float64 `mapstructure:"diversity"`         // 子类目多样性
+}
+
+// GroundingConfig 回复中商品与价格的校验配置
+type GroundingConfig struct {
+	Enabled         bool     `mapstructure:"enabled"`
+	Policy          string   `mapstructure:"policy"`           // flag/strip，对商品库中不存在的商品的处理方式
+	CorrectPrices   bool     `mapstructure:"correct_prices"`   // 是否用商品库价格替换错误报价
+	PriceTolerance  float64  `mapstructure:"price_tolerance"`  // 报价允许的相对误差
+	MinMatchScore   float64  `mapstructure:"min_match_score"`  // 商品匹配的最低置信度
+	IgnoreTerms     []string `mapstructure:"ignore_terms"`     // 不视为商品型号的规格词，如 USB3、IP67
+	FallbackMessage string   `mapstructure:"fallback_message"` // 回复被全部删除时的兜底话术
+}
+
+// EnrichmentConfig 从对话中自动补全用户画像的配置
+type EnrichmentConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Extractor       string        `mapstructure:"extractor"`        // rules/dify，dify 使用 dify.workflows.executors.profile_extractor
+	MinConfidence   float64       `mapstructure:"min_confidence"`   // 低于该置信度的抽取结果丢弃
+	HalfLife        time.Duration `mapstructure:"half_life"`        // 自动补全字段的置信度半衰期
+	PruneConfidence float64       `mapstructure:"prune_confidence"` // 衰减后低于该置信度的字段被移除
+	MaxInterests    int           `mapstructure:"max_interests"`
+	Workers         int           `mapstructure:"workers"`
+	QueueSize       int           `mapstructure:"queue_size"`
+	Timeout         time.Duration `mapstructure:"timeout"` // 单次补全（抽取+写库）的超时
+}
+
+// QuotaConfig 大模型 token 配额配置，额度为 0 表示不限
+type QuotaConfig struct {
+	Enabled               bool          `mapstructure:"enabled"`
+	UserDailyTokens       int64         `mapstructure:"user_daily_tokens"`
+	UserMonthlyTokens     int64         `mapstructure:"user_monthly_tokens"`
+	GuestDailyTokens      int64         `mapstructure:"guest_daily_tokens"` // 访客按客户端IP计量的额度
+	GuestMonthlyTokens    int64         `mapstructure:"guest_monthly_tokens"`
+	MerchantDailyTokens   int64         `mapstructure:"merchant_daily_tokens"`
+	MerchantMonthlyTokens int64         `mapstructure:"merchant_monthly_tokens"`
+	WarnRatio             float64       `mapstructure:"warn_ratio"`         // 用量达到该比例时发出预警事件
+	DegradeRatio          float64       `mapstructure:"degrade_ratio"`      // 用量达到该比例时切换到低成本模式
+	DegradedTool          string        `mapstructure:"degraded_tool"`      // 低成本模式跳过 Planner，直接使用该 Executor
+	DegradedMaxChars      int           `mapstructure:"degraded_max_chars"` // 低成本模式的回复长度上限
+	EventChannel          string        `mapstructure:"event_channel"`      // 预警事件发布的 Redis 频道，为空时只打日志
+	RollupInterval        time.Duration `mapstructure:"rollup_interval"`    // Redis 用量汇总到 MySQL 的间隔
+}
+
+// StyleConfig 导购风格配置
+type StyleConfig struct {
+	Path string `mapstructure:"path"` // 风格定义文件
 }
 
 // RetryConfig 重试配置
@@ -141,13 +312,30 @@ type RetryConfig struct {
 	MaxAttempts  int           `mapstructure:"max_attempts"`
 	InitialDelay time.Duration `mapstructure:"initial_delay"`
 	MaxDelay     time.Duration `mapstructure:"max_delay"`
-	Multiplier   int           `mapstructure:"multiplier"`
+	Multiplier   float64       `mapstructure:"multiplier"` // 退避倍数，支持小数如 1.5
+}
+
+// CircuitBreakerConfig Dify 调用熔断配置
+type CircuitBreakerConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold"` // 连续失败多少次后熔断
+	OpenDuration     time.Duration `mapstructure:"open_duration"`     // 熔断持续时间，到期后放行一个探测请求
 }
 
 var globalConfig *Config
 
 // Load 加载配置
 func Load(configPath string, env string) (*Config, error) {
+	cfg, err := load(configPath, env)
+	if err != nil {
+		return nil, err
+	}
+	globalConfig = cfg
+	return cfg, nil
+}
+
+// load 读取默认配置与环境配置并应用环境变量覆盖
+func load(configPath string, env string) (*Config, error) {
 	v := viper.New()
 
 	// 设置配置文件
@@ -165,13 +353,14 @@ func Load(configPath string, env string) (*Config, error) {
 		v.SetConfigName(fmt.Sprintf("config.%s", env))
 		if err := v.MergeInConfig(); err != nil {
 			// 环境配置可选，不存在时不报错
-			fmt.Printf("No environment config found for %s, using default\n", env)
+			slog.Info("no environment config found, using default", "env", env)
 		}
 	}
 
-	// 支持环境变量覆盖
+	// 支持环境变量覆盖：APP_ 前缀，键名中的 . 替换为 _，如 APP_REDIS_ADDR 覆盖 redis.addr
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
-	v.SetEnvPrefix("APP")
 
 	// 解析配置
 	var cfg Config
@@ -179,21 +368,14 @@ func Load(configPath string, env string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	// 从环境变量读取敏感信息
-	if apiKey := v.GetString("DIFY_API_KEY"); apiKey != "" {
-		cfg.Dify.APIKey = apiKey
-	}
-	if jwtSecret := v.GetString("JWT_SECRET"); jwtSecret != "" {
-		cfg.Middleware.Auth.JWTSecret = jwtSecret
-	}
-	if mysqlPass := v.GetString("MYSQL_PASSWORD"); mysqlPass != "" {
-		cfg.MySQL.Password = mysqlPass
-	}
-	if redisPass := v.GetString("REDIS_PASSWORD"); redisPass != "" {
-		cfg.Redis.Password = redisPass
+	// 密钥支持 _FILE 文件、旧环境变量名与外部密钥源
+	sources, err := resolveSecrets(&cfg)
+	if err != nil {
+		return nil, err
 	}
+	cfg.secretSources = sources
+	cfg.env = env
 
-	globalConfig = &cfg
 	return &cfg, nil
 }
 
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+// redactedValue 脱敏后的占位值
+const redactedValue = "******"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Redacted 以 YAML 键名输出配置，带 redact 标签的字段（密钥、密码、API Key）非空时替换为占位值，时长输出为 30s 形式
+func (c *Config) Redacted() map[string]interface{} {
+	return redactValue(reflect.ValueOf(*c)).(map[string]interface{})
+}
+
+func redactValue(v reflect.Value) interface{} {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := field.Tag.Get("mapstructure")
+			if name == "" || name == "-" {
+				continue
+			}
+			if name == ",squash" {
+				for k, val := range redactValue(v.Field(i)).(map[string]interface{}) {
+					out[k] = val
+				}
+				continue
+			}
+			if field.Tag.Get("redact") == "true" {
+				out[name] = redactSecret(v.Field(i))
+				continue
+			}
+			out[name] = redactValue(v.Field(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = redactValue(iter.Value())
+		}
+		return out
+	case reflect.Slice:
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = redactValue(v.Index(i))
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// redactSecret 空值原样输出以便看出未配置，非空值替换为占位值；map 只保留键
+func redactSecret(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() == 0 {
+			return ""
+		}
+		return redactedValue
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = redactedValue
+		}
+		return out
+	default:
+		return redactedValue
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	const secret = "s3cr3t"
+
+	cfg := &Config{}
+	cfg.Dify.Workflows.Executors = map[string]DifyWorkflowConfig{"shopping_guide": {}}
+	cfg.Tracing.Headers = map[string]string{"Authorization": "Bearer " + secret}
+	fields := secretFields(cfg)
+	for _, f := range fields {
+		f.set(secret)
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"dify.api_key", redactedValue},
+		{"dify.workflows.planner.app_id", redactedValue},
+		{"dify.workflows.executors.shopping_guide.app_id", redactedValue},
+		{"redis.password", redactedValue},
+		{"mysql.password", redactedValue},
+		{"middleware.auth.jwt_secret", redactedValue},
+		{"middleware.auth.internal_token", redactedValue},
+		{"business.product.search.embedding.api_key", redactedValue},
+		{"tracing.headers.Authorization", redactedValue},
+	}
+
+	// 新增 redact 字段时需要补充到上表
+	var covered, collected []string
+	for _, tt := range tests {
+		if !strings.HasPrefix(tt.path, "tracing.headers.") {
+			covered = append(covered, tt.path)
+		}
+	}
+	for _, f := range fields {
+		collected = append(collected, f.path)
+	}
+	sort.Strings(covered)
+	sort.Strings(collected)
+	if strings.Join(covered, ",") != strings.Join(collected, ",") {
+		t.Fatalf("redact fields not covered by test table:\n got  %v\n want %v", covered, collected)
+	}
+
+	redacted := cfg.Redacted()
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := lookup(redacted, tt.path)
+			if !ok {
+				t.Fatalf("path %s not found in redacted config", tt.path)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("marshal redacted config: %v", err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Errorf("redacted config leaks secret: %s", data)
+	}
+}
+
+func TestRedactedKeepsEmptySecrets(t *testing.T) {
+	got, ok := lookup((&Config{}).Redacted(), "mysql.password")
+	if !ok || got != "" {
+		t.Errorf("mysql.password = %v, want empty string so unset secrets stay visible", got)
+	}
+}
+
+// lookup 按 . 分隔的路径读取 Redacted 的结果
+func lookup(m map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
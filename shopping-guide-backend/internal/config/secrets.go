@@ -0,0 +1,185 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envPrefix 环境变量前缀，键名中的 . 替换为 _，如 redis.addr 对应 APP_REDIS_ADDR
+const envPrefix = "APP"
+
+// secretProviderTimeout 单个密钥从外部密钥源读取的超时
+const secretProviderTimeout = 10 * time.Second
+
+// SecretProvider 外部密钥源（如 Vault、云厂商 KMS），通过 RegisterSecretProvider 注册
+type SecretProvider interface {
+	// Name 密钥源名称，用于日志中标明密钥来源
+	Name() string
+	// Secret 按 YAML 路径（如 mysql.password）读取密钥，不存在时返回 ok=false
+	Secret(ctx context.Context, path string) (value string, ok bool, err error)
+}
+
+// SecretSource 密钥的来源，只记录来源不记录值
+type SecretSource struct {
+	Path   string
+	Source string // config / env:<NAME> / file:<PATH> / provider:<NAME> / unset
+}
+
+var (
+	providerMu     sync.RWMutex
+	secretProvider SecretProvider
+)
+
+// RegisterSecretProvider 注册外部密钥源，须在 Load 之前调用；热更新重新加载配置时同样使用
+func RegisterSecretProvider(p SecretProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	secretProvider = p
+}
+
+// legacyEnvNames 历史上使用的密钥环境变量名，继续兼容
+var legacyEnvNames = map[string][]string{
+	"dify.api_key":                              {"DIFY_API_KEY"},
+	"redis.password":                            {"REDIS_PASSWORD"},
+	"mysql.password":                            {"MYSQL_PASSWORD"},
+	"middleware.auth.jwt_secret":                {"APP_JWT_SECRET", "JWT_SECRET"},
+	"business.product.search.embedding.api_key": {"APP_EMBEDDING_API_KEY", "EMBEDDING_API_KEY"},
+}
+
+// EnvName 配置路径对应的环境变量名
+func EnvName(path string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// SecretSources 各密钥的来源，按配置结构顺序排列
+func (c *Config) SecretSources() []SecretSource {
+	return c.secretSources
+}
+
+// resolveSecrets 按优先级为带 redact 标签的字符串字段取值：
+// <ENV>_FILE 指向的文件（Kubernetes Secret 挂载）> <ENV> 环境变量 > 兼容的旧环境变量名 > 外部密钥源 > 配置文件
+func resolveSecrets(cfg *Config) ([]SecretSource, error) {
+	providerMu.RLock()
+	provider := secretProvider
+	providerMu.RUnlock()
+
+	fields := secretFields(cfg)
+	sources := make([]SecretSource, 0, len(fields))
+	for _, f := range fields {
+		source, err := resolveSecret(f, provider)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, SecretSource{Path: f.path, Source: source})
+	}
+	return sources, nil
+}
+
+func resolveSecret(f secretField, provider SecretProvider) (string, error) {
+	env := EnvName(f.path)
+	if file := os.Getenv(env + "_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s from %s_FILE: %w", f.path, env, err)
+		}
+		f.set(strings.TrimSpace(string(data)))
+		return "file:" + file, nil
+	}
+	// APP_ 前缀的环境变量已由 viper 覆盖，这里只记录来源
+	if os.Getenv(env) != "" {
+		return "env:" + env, nil
+	}
+	for _, name := range legacyEnvNames[f.path] {
+		if value := os.Getenv(name); value != "" {
+			f.set(value)
+			return "env:" + name, nil
+		}
+	}
+	if provider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), secretProviderTimeout)
+		value, ok, err := provider.Secret(ctx, f.path)
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s from secret provider %s: %w", f.path, provider.Name(), err)
+		}
+		if ok {
+			f.set(value)
+			return "provider:" + provider.Name(), nil
+		}
+	}
+	if f.get() != "" {
+		return "config", nil
+	}
+	return "unset", nil
+}
+
+// secretField 可写的密钥字段
+type secretField struct {
+	path string
+	get  func() string
+	set  func(string)
+}
+
+// secretFields 收集带 redact 标签的字符串字段（map 类型的密钥如 tracing.headers 不支持单独注入）
+func secretFields(cfg *Config) []secretField {
+	var fields []secretField
+	collectSecretFields(reflect.ValueOf(cfg).Elem(), "", &fields)
+	return fields
+}
+
+func collectSecretFields(v reflect.Value, prefix string, out *[]secretField) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := field.Tag.Get("mapstructure")
+			if name == "" || name == "-" {
+				continue
+			}
+			if name == ",squash" {
+				collectSecretFields(v.Field(i), prefix, out)
+				continue
+			}
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+			fv := v.Field(i)
+			if field.Tag.Get("redact") == "true" {
+				if fv.Kind() == reflect.String {
+					*out = append(*out, secretField{path: path, get: fv.String, set: fv.SetString})
+				}
+				continue
+			}
+			collectSecretFields(fv, path, out)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			// map 元素不可寻址，复制一份修改后写回
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			var sub []secretField
+			collectSecretFields(elem, prefix+"."+key.String(), &sub)
+			for _, f := range sub {
+				set, key := f.set, key
+				f.set = func(s string) {
+					set(s)
+					v.SetMapIndex(key, elem)
+				}
+				*out = append(*out, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store 当前生效的配置
+// 热更新时整体替换为新的 *Config，读取方每次操作开始时调用 Get 取一次，保证同一次操作内配置一致
+type Store struct {
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	version     int64
+	loadedAt    time.Time
+	subscribers []func(cfg *Config)
+}
+
+// NewStore 创建配置存储，cfg 为启动时加载并校验过的配置
+func NewStore(cfg *Config) *Store {
+	s := &Store{version: 1, loadedAt: time.Now()}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get 返回当前生效的配置，返回值只读
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Version 当前配置版本（启动时为 1，每次热更新加 1）与生效时间
+func (s *Store) Version() (int64, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, s.loadedAt
+}
+
+// Subscribe 注册配置变更回调，热更新成功后按注册顺序同步调用
+func (s *Store) Subscribe(fn func(cfg *Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Reload 用新加载的配置热更新
+// 只更新可热更新的部分（见 mergeReloadable），其余改动忽略并告警；合并结果校验失败时保留原配置并返回错误
+func (s *Store) Reload(next *Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.current.Load()
+	merged, ignored := mergeReloadable(current, next)
+	if err := merged.Validate(); err != nil {
+		return err
+	}
+	if len(ignored) > 0 {
+		slog.Warn("config changes require restart and were ignored", "paths", ignored)
+	}
+
+	s.current.Store(merged)
+	s.version++
+	s.loadedAt = time.Now()
+	for _, fn := range s.subscribers {
+		fn(merged)
+	}
+	slog.Info("config reloaded", "version", s.version)
+	return nil
+}
+
+// mergeReloadable 在当前配置的基础上应用可热更新的部分：
+//   - business（商品检索的索引文件与向量化、画像补全的抽取器与 worker、用量汇总间隔在启动时使用，不更新）
+//   - middleware.rate_limit
+//   - dify 各工作流的 timeout
+//
+// 返回合并后的配置与被忽略的改动路径
+func mergeReloadable(current, next *Config) (*Config, []string) {
+	merged := *current
+
+	merged.Business = next.Business
+	pinned := &merged.Business
+	pinned.Product.Search.IndexPath = current.Business.Product.Search.IndexPath
+	pinned.Product.Search.Embedding = current.Business.Product.Search.Embedding
+	pinned.Enrichment.Extractor = current.Business.Enrichment.Extractor
+	pinned.Enrichment.Workers = current.Business.Enrichment.Workers
+	pinned.Enrichment.QueueSize = current.Business.Enrichment.QueueSize
+	pinned.Quota.RollupInterval = current.Business.Quota.RollupInterval
+
+	merged.Middleware.RateLimit = next.Middleware.RateLimit
+
+	merged.Dify.Workflows.Planner.Timeout = next.Dify.Workflows.Planner.Timeout
+	merged.Dify.Workflows.Executors = make(map[string]DifyWorkflowConfig, len(current.Dify.Workflows.Executors))
+	for name, wf := range current.Dify.Workflows.Executors {
+		if n, ok := next.Dify.Workflows.Executors[name]; ok {
+			wf.Timeout = n.Timeout
+		}
+		merged.Dify.Workflows.Executors[name] = wf
+	}
+
+	// 找出未生效的改动：与新配置逐项比较
+	var ignored []string
+	sections := []struct {
+		path      string
+		got, want interface{}
+	}{
+		{"server", merged.Server, next.Server},
+		{"dify", merged.Dify, next.Dify},
+		{"redis", merged.Redis, next.Redis},
+		{"mysql", merged.MySQL, next.MySQL},
+		{"log", merged.Log, next.Log},
+		{"tracing", merged.Tracing, next.Tracing},
+		{"middleware.cors", merged.Middleware.CORS, next.Middleware.CORS},
+		{"middleware.auth", merged.Middleware.Auth, next.Middleware.Auth},
+		{"business.product.search.index_path", merged.Business.Product.Search.IndexPath, next.Business.Product.Search.IndexPath},
+		{"business.product.search.embedding", merged.Business.Product.Search.Embedding, next.Business.Product.Search.Embedding},
+		{"business.enrichment.extractor", merged.Business.Enrichment.Extractor, next.Business.Enrichment.Extractor},
+		{"business.enrichment.workers", merged.Business.Enrichment.Workers, next.Business.Enrichment.Workers},
+		{"business.enrichment.queue_size", merged.Business.Enrichment.QueueSize, next.Business.Enrichment.QueueSize},
+		{"business.quota.rollup_interval", merged.Business.Quota.RollupInterval, next.Business.Quota.RollupInterval},
+	}
+	for _, sec := range sections {
+		if !reflect.DeepEqual(sec.got, sec.want) {
+			ignored = append(ignored, sec.path)
+		}
+	}
+	return &merged, ignored
+}
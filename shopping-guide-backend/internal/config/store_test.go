@@ -0,0 +1,154 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// reloadTestConfig 热更新测试使用的基础配置，每次调用返回独立的副本
+func reloadTestConfig() *Config {
+	cfg := &Config{}
+	cfg.Server.Port = 8080
+	cfg.Middleware.RateLimit.RequestsPerMinute = 100
+	cfg.Middleware.Auth.Enabled = true
+	cfg.Business.Product.TopK = 10
+	cfg.Business.Product.Search.IndexPath = "data/index.bin"
+	cfg.Business.Enrichment.Workers = 2
+	cfg.Business.Quota.RollupInterval = 5 * time.Minute
+	cfg.Dify.Workflows.Planner.Timeout = 10 * time.Second
+	cfg.Dify.Workflows.Executors = map[string]DifyWorkflowConfig{
+		"shopping_guide": {AppID: "app-1", Timeout: 30 * time.Second},
+	}
+	return cfg
+}
+
+func TestMergeReloadable(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(next *Config)
+		check       func(t *testing.T, merged *Config)
+		wantIgnored []string
+	}{
+		{
+			name:   "no changes",
+			change: func(next *Config) {},
+		},
+		{
+			name:   "business applied",
+			change: func(next *Config) { next.Business.Product.TopK = 20 },
+			check: func(t *testing.T, merged *Config) {
+				if merged.Business.Product.TopK != 20 {
+					t.Errorf("top_k = %d, want 20", merged.Business.Product.TopK)
+				}
+			},
+		},
+		{
+			name:   "rate limit applied",
+			change: func(next *Config) { next.Middleware.RateLimit.RequestsPerMinute = 50 },
+			check: func(t *testing.T, merged *Config) {
+				if merged.Middleware.RateLimit.RequestsPerMinute != 50 {
+					t.Errorf("requests_per_minute = %d, want 50", merged.Middleware.RateLimit.RequestsPerMinute)
+				}
+			},
+		},
+		{
+			name: "workflow timeouts applied",
+			change: func(next *Config) {
+				next.Dify.Workflows.Planner.Timeout = 5 * time.Second
+				next.Dify.Workflows.Executors["shopping_guide"] = DifyWorkflowConfig{AppID: "app-1", Timeout: time.Minute}
+			},
+			check: func(t *testing.T, merged *Config) {
+				if got := merged.Dify.Workflows.Planner.Timeout; got != 5*time.Second {
+					t.Errorf("planner timeout = %s, want 5s", got)
+				}
+				if got := merged.Dify.Workflows.Executors["shopping_guide"].Timeout; got != time.Minute {
+					t.Errorf("executor timeout = %s, want 1m", got)
+				}
+			},
+		},
+		{
+			name: "workflow app id ignored",
+			change: func(next *Config) {
+				next.Dify.Workflows.Executors["shopping_guide"] = DifyWorkflowConfig{AppID: "app-2", Timeout: 30 * time.Second}
+			},
+			check: func(t *testing.T, merged *Config) {
+				if got := merged.Dify.Workflows.Executors["shopping_guide"].AppID; got != "app-1" {
+					t.Errorf("executor app_id = %s, want app-1", got)
+				}
+			},
+			wantIgnored: []string{"dify"},
+		},
+		{
+			name:   "new executor ignored",
+			change: func(next *Config) { next.Dify.Workflows.Executors["qa_assistant"] = DifyWorkflowConfig{AppID: "app-3"} },
+			check: func(t *testing.T, merged *Config) {
+				if _, ok := merged.Dify.Workflows.Executors["qa_assistant"]; ok {
+					t.Error("new executor should not be added without restart")
+				}
+			},
+			wantIgnored: []string{"dify"},
+		},
+		{
+			name:   "server ignored",
+			change: func(next *Config) { next.Server.Port = 9090 },
+			check: func(t *testing.T, merged *Config) {
+				if merged.Server.Port != 8080 {
+					t.Errorf("port = %d, want 8080", merged.Server.Port)
+				}
+			},
+			wantIgnored: []string{"server"},
+		},
+		{
+			name:   "auth ignored",
+			change: func(next *Config) { next.Middleware.Auth.Enabled = false },
+			check: func(t *testing.T, merged *Config) {
+				if !merged.Middleware.Auth.Enabled {
+					t.Error("auth should stay enabled until restart")
+				}
+			},
+			wantIgnored: []string{"middleware.auth"},
+		},
+		{
+			name: "startup-only business fields pinned",
+			change: func(next *Config) {
+				next.Business.Product.TopK = 20
+				next.Business.Product.Search.IndexPath = "data/other.bin"
+				next.Business.Enrichment.Workers = 8
+				next.Business.Quota.RollupInterval = time.Minute
+			},
+			check: func(t *testing.T, merged *Config) {
+				if merged.Business.Product.TopK != 20 {
+					t.Errorf("top_k = %d, want 20", merged.Business.Product.TopK)
+				}
+				if merged.Business.Product.Search.IndexPath != "data/index.bin" {
+					t.Errorf("index_path = %s, want data/index.bin", merged.Business.Product.Search.IndexPath)
+				}
+				if merged.Business.Enrichment.Workers != 2 {
+					t.Errorf("workers = %d, want 2", merged.Business.Enrichment.Workers)
+				}
+				if merged.Business.Quota.RollupInterval != 5*time.Minute {
+					t.Errorf("rollup_interval = %s, want 5m", merged.Business.Quota.RollupInterval)
+				}
+			},
+			wantIgnored: []string{"business.product.search.index_path", "business.enrichment.workers", "business.quota.rollup_interval"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, next := reloadTestConfig(), reloadTestConfig()
+			tt.change(next)
+
+			merged, ignored := mergeReloadable(current, next)
+			if !reflect.DeepEqual(ignored, tt.wantIgnored) {
+				t.Errorf("ignored = %v, want %v", ignored, tt.wantIgnored)
+			}
+			if tt.check != nil {
+				tt.check(t, merged)
+			}
+			if !reflect.DeepEqual(current, reloadTestConfig()) {
+				t.Error("current config was modified")
+			}
+		})
+	}
+}
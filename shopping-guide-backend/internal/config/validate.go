@@ -0,0 +1,466 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 必须配置的 Executor 工作流；comparison 为空时由答疑助手兜底，profile_extractor 仅在 dify 抽取时需要
+var requiredExecutors = []string{"product_recommendation", "shopping_guide", "qa_assistant"}
+
+// envDev 本地开发环境名，调试开关只允许在该环境开启
+const envDev = "dev"
+
+// Issue 单个配置问题，Path 为 YAML 路径
+type Issue struct {
+	Path    string
+	Message string
+}
+
+// ValidationError 配置校验错误，包含全部问题
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config (%d issues):", len(e.Issues))
+	for _, issue := range e.Issues {
+		fmt.Fprintf(&b, "\n  - %s: %s", issue.Path, issue.Message)
+	}
+	return b.String()
+}
+
+// ValidateOptions 校验选项
+type ValidateOptions struct {
+	// SkipSecrets 不检查密钥类字段（api_key、app_id、jwt_secret），供 CI 在未注入密钥时检查配置文件
+	SkipSecrets bool
+}
+
+// Validate 校验配置，按启用的功能检查必填项、取值范围、时长与地址格式
+// 返回 *ValidationError，列出全部问题
+func (c *Config) Validate() error {
+	return c.ValidateWith(ValidateOptions{})
+}
+
+// ValidateWith 按选项校验配置
+func (c *Config) ValidateWith(opts ValidateOptions) error {
+	v := &validator{opts: opts}
+
+	c.Server.validate(v)
+	c.Dify.validate(v, &c.Business.Enrichment)
+	c.Redis.validate(v, c.Middleware.RateLimit.Enabled)
+	c.MySQL.validate(v)
+	c.Log.validate(v)
+	c.Tracing.validate(v)
+	c.Middleware.CORS.validate(v)
+	c.Middleware.RateLimit.validate(v)
+	c.Middleware.Auth.validate(v, c.env)
+	c.Business.validate(v)
+
+	if len(v.issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: v.issues}
+}
+
+// validator 收集配置问题
+type validator struct {
+	opts   ValidateOptions
+	issues []Issue
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(path, "is required")
+	}
+}
+
+// secret 必填的密钥，可通过环境变量或 _FILE 文件注入
+func (v *validator) secret(path, value string) {
+	if v.opts.SkipSecrets || value != "" {
+		return
+	}
+	env := EnvName(path)
+	v.addf(path, "is required (set it via %s or %s_FILE)", env, env)
+}
+
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(path, "must be one of %s, got %q", strings.Join(allowed, "/"), value)
+}
+
+func (v *validator) positiveInt(path string, value int) {
+	if value <= 0 {
+		v.addf(path, "must be greater than 0, got %d", value)
+	}
+}
+
+func (v *validator) nonNegativeInt(path string, value int) {
+	if value < 0 {
+		v.addf(path, "must not be negative, got %d", value)
+	}
+}
+
+func (v *validator) positiveDuration(path string, value time.Duration) {
+	if value <= 0 {
+		v.addf(path, "must be a positive duration (e.g. 30s), got %s", value)
+	}
+}
+
+func (v *validator) nonNegativeDuration(path string, value time.Duration) {
+	if value < 0 {
+		v.addf(path, "must not be negative, got %s", value)
+	}
+}
+
+func (v *validator) ratio(path string, value float64) {
+	if value < 0 || value > 1 {
+		v.addf(path, "must be between 0 and 1, got %v", value)
+	}
+}
+
+func (v *validator) httpURL(path, value string) {
+	if value == "" {
+		v.addf(path, "is required")
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		v.addf(path, "is not a valid URL: %v", err)
+		return
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(path, "must be an absolute http(s) URL, got %q", value)
+	}
+}
+
+func (c *ServerConfig) validate(v *validator) {
+	if c.Port <= 0 || c.Port > 65535 {
+		v.addf("server.port", "must be between 1 and 65535, got %d", c.Port)
+	}
+	if c.Mode != "" {
+		v.oneOf("server.mode", c.Mode, "debug", "release", "test")
+	}
+	v.nonNegativeDuration("server.read_timeout", c.ReadTimeout)
+	v.nonNegativeDuration("server.write_timeout", c.WriteTimeout)
+	v.nonNegativeDuration("server.shutdown_timeout", c.ShutdownTimeout)
+	v.nonNegativeDuration("server.health.timeout", c.Health.Timeout)
+	for i, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			v.addf(fmt.Sprintf("server.trusted_proxies[%d]", i), "must be an IP or CIDR, got %q", proxy)
+		}
+	}
+}
+
+func (c *DifyConfig) validate(v *validator, enrichment *EnrichmentConfig) {
+	v.httpURL("dify.base_url", c.BaseURL)
+	v.positiveDuration("dify.timeout", c.Timeout)
+
+	// 工作流未配置 app_id 时使用 dify.api_key
+	workflow := func(path string, wf DifyWorkflowConfig, required bool) {
+		if required && wf.AppID == "" && c.APIKey == "" && !v.opts.SkipSecrets {
+			v.addf(path+".app_id", "is required when dify.api_key is not set")
+		}
+		v.nonNegativeDuration(path+".timeout", wf.Timeout)
+	}
+	workflow("dify.workflows.planner", c.Workflows.Planner, true)
+	for _, name := range requiredExecutors {
+		if _, ok := c.Workflows.Executors[name]; !ok {
+			v.addf("dify.workflows.executors."+name, "is required")
+		}
+	}
+	names := make([]string, 0, len(c.Workflows.Executors))
+	for name := range c.Workflows.Executors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		required := false
+		for _, r := range requiredExecutors {
+			required = required || name == r
+		}
+		if name == "profile_extractor" {
+			required = enrichment.Enabled && enrichment.Extractor == "dify"
+		}
+		workflow("dify.workflows.executors."+name, c.Workflows.Executors[name], required)
+	}
+	if enrichment.Enabled && enrichment.Extractor == "dify" {
+		if _, ok := c.Workflows.Executors["profile_extractor"]; !ok {
+			v.addf("dify.workflows.executors.profile_extractor", "is required when business.enrichment.extractor is dify")
+		}
+	}
+}
+
+func (c *RedisConfig) validate(v *validator, rateLimitEnabled bool) {
+	v.required("redis.addr", c.Addr)
+	v.nonNegativeInt("redis.db", c.DB)
+	v.nonNegativeInt("redis.pool_size", c.PoolSize)
+	v.nonNegativeInt("redis.min_idle_conns", c.MinIdleConns)
+	v.positiveDuration("redis.session_ttl", c.SessionTTL)
+	v.nonNegativeDuration("redis.cache_ttl", c.CacheTTL)
+	if rateLimitEnabled {
+		v.positiveDuration("redis.rate_limit_window", c.RateLimitWindow)
+	}
+}
+
+func (c *MySQLConfig) validate(v *validator) {
+	v.required("mysql.host", c.Host)
+	if c.Port <= 0 || c.Port > 65535 {
+		v.addf("mysql.port", "must be between 1 and 65535, got %d", c.Port)
+	}
+	v.required("mysql.user", c.User)
+	v.required("mysql.database", c.Database)
+	v.nonNegativeInt("mysql.max_idle_conns", c.MaxIdleConns)
+	v.nonNegativeInt("mysql.max_open_conns", c.MaxOpenConns)
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		v.addf("mysql.max_idle_conns", "must not exceed mysql.max_open_conns (%d), got %d", c.MaxOpenConns, c.MaxIdleConns)
+	}
+	v.nonNegativeDuration("mysql.conn_max_lifetime", c.ConnMaxLifetime)
+}
+
+func (c *LogConfig) validate(v *validator) {
+	if c.Level != "" {
+		v.oneOf("log.level", strings.ToLower(c.Level), "debug", "info", "warn", "error")
+	}
+	if c.Format != "" {
+		v.oneOf("log.format", strings.ToLower(c.Format), "json", "console", "text")
+	}
+	if c.Output != "" {
+		v.oneOf("log.output", strings.ToLower(c.Output), "stdout", "stderr", "file")
+	}
+	if strings.EqualFold(c.Output, "file") {
+		v.required("log.file_path", c.FilePath)
+	}
+	if c.Persist.Enabled {
+		v.nonNegativeInt("log.persist.workers", c.Persist.Workers)
+		v.nonNegativeInt("log.persist.queue_size", c.Persist.QueueSize)
+		v.nonNegativeDuration("log.persist.timeout", c.Persist.Timeout)
+	}
+}
+
+func (c *TracingConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	switch c.Exporter {
+	case "", "otlp":
+		v.required("tracing.endpoint", c.Endpoint)
+	case "file":
+		v.required("tracing.file_path", c.FilePath)
+	case "stdout":
+	default:
+		v.addf("tracing.exporter", "must be one of otlp/stdout/file, got %q", c.Exporter)
+	}
+	v.ratio("tracing.sample_ratio", c.SampleRatio)
+}
+
+func (c *CORSConfig) validate(v *validator) {
+	c.CORSPolicy.validate(v, "middleware.cors")
+	groups := make([]string, 0, len(c.Groups))
+	for group := range c.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		path := fmt.Sprintf("middleware.cors.groups[%s]", group)
+		if !strings.HasPrefix(group, "/") {
+			v.addf(path, "route group must start with /")
+		}
+		policy := c.Groups[group]
+		policy.validate(v, path)
+	}
+}
+
+func (c *CORSPolicy) validate(v *validator, path string) {
+	for i, origin := range c.AllowOrigins {
+		originPath := fmt.Sprintf("%s.allow_origins[%d]", path, i)
+		if origin == "*" {
+			// 浏览器不接受携带凭证的请求使用 * 来源，回显任意来源则等于向所有站点开放登录态
+			if c.AllowCredentials {
+				v.addf(originPath, "\"*\" cannot be combined with allow_credentials, list the allowed origins instead")
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 {
+			v.addf(originPath, "at most one * is allowed, got %q", origin)
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			v.addf(originPath, "must be * or an http(s) origin such as https://*.shop.example.com, got %q", origin)
+		}
+	}
+	v.nonNegativeDuration(path+".max_age", c.MaxAge)
+}
+
+func (c *RateLimitConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.positiveInt("middleware.rate_limit.requests_per_minute", c.RequestsPerMinute)
+	v.nonNegativeInt("middleware.rate_limit.burst", c.Burst)
+	for i, r := range c.Routes {
+		path := fmt.Sprintf("middleware.rate_limit.routes[%d]", i)
+		if !strings.HasPrefix(r.Path, "/") {
+			v.addf(path+".path", "must be a route template starting with /, got %q", r.Path)
+		}
+		if r.Method != "" {
+			v.oneOf(path+".method", strings.ToUpper(r.Method), "GET", "POST", "PUT", "PATCH", "DELETE")
+		}
+		v.positiveInt(path+".requests_per_minute", r.RequestsPerMinute)
+		v.nonNegativeInt(path+".burst", r.Burst)
+	}
+}
+
+func (c *AuthConfig) validate(v *validator, env string) {
+	if c.TrustUserHeader && env != envDev {
+		v.addf("middleware.auth.trust_user_header", "is only allowed when ENV=%s, got ENV=%q", envDev, env)
+	}
+	if env != envDev {
+		v.secret("middleware.auth.internal_token", c.InternalToken)
+	}
+	if !c.Enabled {
+		return
+	}
+	switch c.Algorithm {
+	case "", "HS256":
+		if c.JWKSFile == "" {
+			v.secret("middleware.auth.jwt_secret", c.JWTSecret)
+		}
+	case "RS256":
+		if c.PrivateKeyFile == "" && c.JWKSFile == "" {
+			v.addf("middleware.auth.private_key_file", "private_key_file or jwks_file is required for RS256")
+		}
+	default:
+		v.addf("middleware.auth.algorithm", "must be one of HS256/RS256, got %q", c.Algorithm)
+	}
+	v.positiveDuration("middleware.auth.token_expire", c.TokenExpire)
+	v.positiveDuration("middleware.auth.refresh_expire", c.RefreshExpire)
+	v.nonNegativeDuration("middleware.auth.clock_skew", c.ClockSkew)
+	v.nonNegativeDuration("middleware.auth.jwks_reload_interval", c.JWKSReloadInterval)
+}
+
+func (c *BusinessConfig) validate(v *validator) {
+	v.positiveInt("business.session.max_messages", c.Session.MaxMessages)
+	v.required("business.session.default_style", c.Session.DefaultStyle)
+	v.required("business.style.path", c.Style.Path)
+
+	// 商品检索
+	v.positiveInt("business.product.top_k", c.Product.TopK)
+	search := &c.Product.Search
+	if search.Mode != "" {
+		v.oneOf("business.product.search.mode", search.Mode, "keyword", "vector", "hybrid")
+	}
+	v.ratio("business.product.search.hybrid_alpha", search.HybridAlpha)
+	v.nonNegativeDuration("business.product.search.refresh_interval", search.RefreshInterval)
+	embedding := &search.Embedding
+	switch embedding.Provider {
+	case "openai":
+		v.httpURL("business.product.search.embedding.base_url", embedding.BaseURL)
+		v.required("business.product.search.embedding.model", embedding.Model)
+		v.secret("business.product.search.embedding.api_key", embedding.APIKey)
+		v.nonNegativeInt("business.product.search.embedding.batch_size", embedding.BatchSize)
+		v.nonNegativeDuration("business.product.search.embedding.timeout", embedding.Timeout)
+	case "", "hash":
+		v.positiveInt("business.product.search.embedding.dimension", embedding.Dimension)
+	default:
+		v.addf("business.product.search.embedding.provider", "must be one of openai/hash, got %q", embedding.Provider)
+	}
+
+	if c.Ranking.Enabled {
+		if c.Ranking.RecallMultiplier < 1 {
+			v.addf("business.ranking.recall_multiplier", "must be at least 1, got %d", c.Ranking.RecallMultiplier)
+		}
+		v.nonNegativeDuration("business.ranking.history_window", c.Ranking.HistoryWindow)
+		v.nonNegativeInt("business.ranking.low_stock_threshold", c.Ranking.LowStockThreshold)
+		w := c.Ranking.Weights
+		weights := []struct {
+			name  string
+			value float64
+		}{
+			{"relevance", w.Relevance}, {"interest", w.Interest}, {"category_affinity", w.CategoryAffinity},
+			{"demographic", w.Demographic}, {"price_band", w.PriceBand}, {"popularity", w.Popularity},
+			{"stock", w.Stock}, {"diversity", w.Diversity},
+		}
+		for _, weight := range weights {
+			if weight.value < 0 {
+				v.addf("business.ranking.weights."+weight.name, "must not be negative, got %v", weight.value)
+			}
+		}
+	}
+
+	if c.Grounding.Enabled {
+		v.oneOf("business.grounding.policy", c.Grounding.Policy, "flag", "strip")
+		v.ratio("business.grounding.price_tolerance", c.Grounding.PriceTolerance)
+		v.ratio("business.grounding.min_match_score", c.Grounding.MinMatchScore)
+		if c.Grounding.Policy == "strip" {
+			v.required("business.grounding.fallback_message", c.Grounding.FallbackMessage)
+		}
+	}
+
+	if c.Enrichment.Enabled {
+		v.oneOf("business.enrichment.extractor", c.Enrichment.Extractor, "rules", "dify")
+		v.ratio("business.enrichment.min_confidence", c.Enrichment.MinConfidence)
+		v.ratio("business.enrichment.prune_confidence", c.Enrichment.PruneConfidence)
+		v.nonNegativeDuration("business.enrichment.half_life", c.Enrichment.HalfLife)
+		v.nonNegativeInt("business.enrichment.max_interests", c.Enrichment.MaxInterests)
+		v.nonNegativeInt("business.enrichment.workers", c.Enrichment.Workers)
+		v.nonNegativeInt("business.enrichment.queue_size", c.Enrichment.QueueSize)
+		v.nonNegativeDuration("business.enrichment.timeout", c.Enrichment.Timeout)
+	}
+
+	if q := &c.Quota; q.Enabled {
+		limits := []struct {
+			name  string
+			value int64
+		}{
+			{"user_daily_tokens", q.UserDailyTokens}, {"user_monthly_tokens", q.UserMonthlyTokens},
+			{"guest_daily_tokens", q.GuestDailyTokens}, {"guest_monthly_tokens", q.GuestMonthlyTokens},
+			{"merchant_daily_tokens", q.MerchantDailyTokens}, {"merchant_monthly_tokens", q.MerchantMonthlyTokens},
+		}
+		for _, limit := range limits {
+			if limit.value < 0 {
+				v.addf("business.quota."+limit.name, "must not be negative (0 means unlimited), got %d", limit.value)
+			}
+		}
+		v.ratio("business.quota.warn_ratio", q.WarnRatio)
+		v.ratio("business.quota.degrade_ratio", q.DegradeRatio)
+		if q.DegradeRatio > 0 {
+			v.required("business.quota.degraded_tool", q.DegradedTool)
+		}
+		v.nonNegativeInt("business.quota.degraded_max_chars", q.DegradedMaxChars)
+		v.nonNegativeDuration("business.quota.rollup_interval", q.RollupInterval)
+	}
+
+	r := &c.Retry
+	v.nonNegativeInt("business.retry.max_attempts", r.MaxAttempts)
+	v.nonNegativeDuration("business.retry.initial_delay", r.InitialDelay)
+	v.nonNegativeDuration("business.retry.max_delay", r.MaxDelay)
+	if r.MaxDelay > 0 && r.MaxDelay < r.InitialDelay {
+		v.addf("business.retry.max_delay", "must not be less than business.retry.initial_delay (%s), got %s", r.InitialDelay, r.MaxDelay)
+	}
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		v.addf("business.retry.multiplier", "must be at least 1 (0 means constant delay), got %v", r.Multiplier)
+	}
+
+	if cb := &c.CircuitBreaker; cb.Enabled {
+		v.positiveInt("business.circuit_breaker.failure_threshold", cb.FailureThreshold)
+		v.positiveDuration("business.circuit_breaker.open_duration", cb.OpenDuration)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Watch 监听默认配置、环境配置与风格文件，变更后重新加载并热更新 store
+// 风格文件变更同样触发一次热更新，由订阅方重新加载风格定义；加载或校验失败时保留当前配置
+func Watch(store *Store, configPath, env string) error {
+	files := []string{filepath.Join(configPath, "config.yaml")}
+	if env != "" {
+		envFile := filepath.Join(configPath, fmt.Sprintf("config.%s.yaml", env))
+		if _, err := os.Stat(envFile); err == nil {
+			files = append(files, envFile)
+		}
+	}
+	if path := store.Get().Business.Style.Path; path != "" {
+		files = append(files, path)
+	}
+
+	reload := func(e fsnotify.Event) {
+		next, err := load(configPath, env)
+		if err == nil {
+			err = store.Reload(next)
+		}
+		if err != nil {
+			slog.Error("config reload rejected, keeping current config", "file", e.Name, "error", err)
+		}
+	}
+
+	for _, f := range files {
+		v := viper.New()
+		v.SetConfigFile(f)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", f, err)
+		}
+		v.OnConfigChange(reload)
+		v.WatchConfig()
+	}
+	return nil
+}
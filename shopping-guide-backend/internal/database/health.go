@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// PingRedis 返回 Redis 连通性检查
+func PingRedis(rdb *redis.Client) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	}
+}
+
+// PingMySQL 返回 MySQL 连通性检查
+func PingMySQL(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get sql.DB: %w", err)
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
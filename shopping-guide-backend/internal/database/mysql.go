@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/tracing"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -13,11 +15,16 @@ import (
 func InitMySQL(cfg *config.MySQLConfig) (*gorm.DB, error) {
 	dsn := cfg.GetDSN()
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError 将唯一键冲突等驱动错误转换为 gorm.ErrDuplicatedKey 等通用错误
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect mysql: %w", err)
 	}
 
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
@@ -32,6 +39,9 @@ func InitMySQL(cfg *config.MySQLConfig) (*gorm.DB, error) {
 	if err := sqlDB.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping mysql: %w", err)
 	}
+	if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		return nil, fmt.Errorf("failed to register mysql pool metrics: %w", err)
+	}
 
 	return db, nil
 }
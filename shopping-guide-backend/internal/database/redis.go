@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/tracing"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -20,11 +22,16 @@ func InitRedis(cfg *config.RedisConfig) (*redis.Client, error) {
 		MaxRetries:   cfg.MaxRetries,
 	})
 
+	client.AddHook(tracing.NewRedisHook())
+
 	// 测试连接
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("failed to connect redis: %w", err)
 	}
+	if err := metrics.RegisterRedisPoolStats(client); err != nil {
+		return nil, fmt.Errorf("failed to register redis pool metrics: %w", err)
+	}
 
 	return client, nil
 }
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminHandler 管理处理器实现
+type adminHandler struct {
+	healthService service.HealthService
+	store         *config.Store
+	metrics       http.Handler
+}
+
+// NewAdminHandler 创建管理处理器
+func NewAdminHandler(healthService service.HealthService, store *config.Store) AdminHandler {
+	return &adminHandler{
+		healthService: healthService,
+		store:         store,
+		metrics:       metrics.Handler(),
+	}
+}
+
+// Livez 存活检查，不检查依赖，避免依赖故障导致 Pod 被反复重启
+func (h *adminHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": model.HealthStatusOK})
+}
+
+// Health 就绪检查，关键依赖异常时返回 503，非关键依赖异常返回 200 与 degraded
+func (h *adminHandler) Health(c *gin.Context) {
+	report := h.healthService.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status == model.HealthStatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// Config 当前生效的配置，密钥与密码脱敏
+func (h *adminHandler) Config(c *gin.Context) {
+	version, loadedAt := h.store.Version()
+	c.JSON(http.StatusOK, model.NewSuccessResponse(&model.ConfigSnapshot{
+		Version:  version,
+		LoadedAt: loadedAt,
+		Config:   h.store.Get().Redacted(),
+	}))
+}
+
+// Metrics Prometheus 指标
+func (h *adminHandler) Metrics(c *gin.Context) {
+	h.metrics.ServeHTTP(c.Writer, c.Request)
+}
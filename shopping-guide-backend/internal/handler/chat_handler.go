@@ -2,7 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/middleware"
 	"shopping-guide-backend/internal/model"
 	"shopping-guide-backend/internal/service"
 
@@ -32,9 +37,10 @@ func (h *chatHandler) Chat(c *gin.Context) {
 	// 调用Service层处理业务逻辑
 	resp, err := h.chatService.Chat(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(model.CodeInternalError, err.Error()))
+		respondError(c, err)
 		return
 	}
+	c.Set(middleware.ContextKeySessionID, resp.SessionID)
 
 	// 返回成功响应
 	c.JSON(http.StatusOK, model.NewSuccessResponse(resp))
@@ -48,20 +54,34 @@ func (h *chatHandler) ChatStream(c *gin.Context) {
 		return
 	}
 
-	// 设置SSE响应头
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-
-	// 获取流式响应通道
+	// 获取流式响应通道，编排失败时按普通 JSON 返回错误
 	stream, err := h.chatService.ChatStream(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(model.CodeInternalError, err.Error()))
+		respondError(c, err)
 		return
 	}
+	c.Set(middleware.ContextKeySessionID, req.SessionID)
+
+	// server.write_timeout 只约束普通请求，SSE 流的时长由 Executor 工作流的 timeout 限制，这里清除本请求的写超时
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.FromContext(c.Request.Context()).Warn("failed to clear write deadline for stream", "error", err)
+	}
+
+	// 设置SSE响应头
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
 
 	// 发送流式响应
+	metrics.SSEActiveStreams.Inc()
+	defer metrics.SSEActiveStreams.Dec()
 	for chunk := range stream {
+		// 流中途的错误按错误类别转换为统一错误响应
+		if err, ok := chunk.Data.(error); ok {
+			_ = c.Error(err)
+			_, code := errorStatus(apperr.KindOf(err))
+			chunk.Data = model.NewErrorResponse(code, apperr.Message(err))
+		}
 		c.SSEvent(chunk.Event, chunk.Data)
 		c.Writer.Flush()
 	}
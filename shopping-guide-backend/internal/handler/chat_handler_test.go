@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// slowChatService 流式回复逐块推送，总时长由 interval 控制；streamErr 非空时以 error 事件结束
+type slowChatService struct {
+	chunks    []string
+	interval  time.Duration
+	streamErr error
+}
+
+func (s *slowChatService) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+	return nil, nil
+}
+
+func (s *slowChatService) ChatStream(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error) {
+	ch := make(chan model.StreamChunk)
+	go func() {
+		defer close(ch)
+		for _, text := range s.chunks {
+			time.Sleep(s.interval)
+			ch <- model.StreamChunk{Event: model.StreamEventChunk, Data: text}
+		}
+		if s.streamErr != nil {
+			ch <- model.StreamChunk{Event: model.StreamEventError, Data: s.streamErr}
+			return
+		}
+		ch <- model.StreamChunk{Event: model.StreamEventDone, Data: &model.StreamDone{SessionID: "sess-1"}}
+	}()
+	return ch, nil
+}
+
+func TestChatStreamOutlivesWriteTimeout(t *testing.T) {
+	r := gin.New()
+	h := NewChatHandler(&slowChatService{chunks: []string{"第一段", "第二段", "第三段"}, interval: 50 * time.Millisecond})
+	r.POST("/api/v1/chat/stream", h.ChatStream)
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 80 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/v1/chat/stream", "application/json", strings.NewReader(`{"query":"推荐自行车"}`))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("stream cut before done: %v, got %q", err, body)
+	}
+	for _, want := range []string{"第三段", "event:" + model.StreamEventDone} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("stream = %q, missing %q", body, want)
+		}
+	}
+}
+
+func TestChatStreamErrorEvent(t *testing.T) {
+	r := gin.New()
+	h := NewChatHandler(&slowChatService{
+		chunks:    []string{"第一段"},
+		streamErr: fmt.Errorf("executor execute failed: %w", apperr.Wrap(apperr.KindUpstreamTimeout, "assistant service timed out", errors.New("status=504 body=<html>"))),
+	})
+	r.POST("/api/v1/chat/stream", h.ChatStream)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/chat/stream", strings.NewReader(`{"query":"推荐自行车"}`)))
+	want := "event:error\ndata:{\"code\":504,\"message\":\"assistant service timed out\"}"
+	if body := w.Body.String(); !strings.Contains(body, want) || strings.Contains(body, "<html>") {
+		t.Errorf("stream = %q, want %q", body, want)
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError 按错误类别返回 HTTP 状态码与响应码
+// 响应只包含 apperr.Message 给出的说明，完整错误链通过 c.Error 交给请求日志记录，不返回给调用方
+func respondError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	status, code := errorStatus(apperr.KindOf(err))
+	c.JSON(status, model.NewErrorResponse(code, apperr.Message(err)))
+}
+
+// errorStatus 错误类别对应的 HTTP 状态码与响应码
+func errorStatus(kind apperr.Kind) (status, code int) {
+	switch kind {
+	case apperr.KindNotFound:
+		return http.StatusNotFound, model.CodeNotFound
+	case apperr.KindInvalidInput:
+		return http.StatusBadRequest, model.CodeInvalidParams
+	case apperr.KindUnauthorized:
+		return http.StatusUnauthorized, model.CodeUnauthorized
+	case apperr.KindForbidden:
+		return http.StatusForbidden, model.CodeForbidden
+	case apperr.KindConflict:
+		return http.StatusConflict, model.CodeInvalidParams
+	case apperr.KindRateLimited:
+		return http.StatusTooManyRequests, model.CodeRateLimited
+	case apperr.KindUpstreamUnavailable:
+		return http.StatusServiceUnavailable, model.CodeServiceUnavailable
+	case apperr.KindUpstreamTimeout:
+		return http.StatusGatewayTimeout, model.CodeGatewayTimeout
+	default:
+		return http.StatusInternalServerError, model.CodeInternalError
+	}
+}
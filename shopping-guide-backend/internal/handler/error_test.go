@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    int
+		wantMessage string
+	}{
+		{name: "not found", err: apperr.New(apperr.KindNotFound, "session not found"),
+			wantStatus: http.StatusNotFound, wantCode: model.CodeNotFound, wantMessage: "session not found"},
+		{name: "invalid input", err: fmt.Errorf("preview: %w", apperr.New(apperr.KindInvalidInput, "unknown ranking feature: price")),
+			wantStatus: http.StatusBadRequest, wantCode: model.CodeInvalidParams, wantMessage: "unknown ranking feature: price"},
+		{name: "unauthorized", err: apperr.New(apperr.KindUnauthorized, ""),
+			wantStatus: http.StatusUnauthorized, wantCode: model.CodeUnauthorized, wantMessage: "unauthenticated"},
+		{name: "forbidden", err: apperr.New(apperr.KindForbidden, "session belongs to another user"),
+			wantStatus: http.StatusForbidden, wantCode: model.CodeForbidden, wantMessage: "session belongs to another user"},
+		{name: "conflict", err: apperr.New(apperr.KindConflict, "user already exists"),
+			wantStatus: http.StatusConflict, wantCode: model.CodeInvalidParams, wantMessage: "user already exists"},
+		{name: "rate limited", err: apperr.New(apperr.KindRateLimited, "token quota exceeded"),
+			wantStatus: http.StatusTooManyRequests, wantCode: model.CodeRateLimited, wantMessage: "token quota exceeded"},
+		{name: "upstream unavailable", err: apperr.Wrap(apperr.KindUpstreamUnavailable, "assistant service is unavailable", errors.New("status=502")),
+			wantStatus: http.StatusServiceUnavailable, wantCode: model.CodeServiceUnavailable, wantMessage: "assistant service is unavailable"},
+		{name: "upstream timeout", err: apperr.Wrap(apperr.KindUpstreamTimeout, "assistant service timed out", errors.New("deadline")),
+			wantStatus: http.StatusGatewayTimeout, wantCode: model.CodeGatewayTimeout, wantMessage: "assistant service timed out"},
+		{name: "untyped", err: errors.New("dial tcp 10.0.0.3:3306: connection refused"),
+			wantStatus: http.StatusInternalServerError, wantCode: model.CodeInternalError, wantMessage: "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondError(c, tt.err)
+
+			var resp model.APIResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("body = %q: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.wantStatus || resp.Code != tt.wantCode || resp.Message != tt.wantMessage {
+				t.Errorf("status = %d, resp = %+v, want %d %d %q", w.Code, resp, tt.wantStatus, tt.wantCode, tt.wantMessage)
+			}
+			// 完整错误链交给请求日志
+			if len(c.Errors) != 1 || !errors.Is(c.Errors[0].Err, tt.err) {
+				t.Errorf("c.Errors = %v", c.Errors)
+			}
+		})
+	}
+}
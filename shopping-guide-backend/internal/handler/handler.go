@@ -16,13 +16,59 @@ type SessionHandler interface {
 	DeleteSession(c *gin.Context)
 }
 
+// UserHandler 用户账号处理器接口
+type UserHandler interface {
+	Register(c *gin.Context)
+	Login(c *gin.Context)
+	Refresh(c *gin.Context)
+}
+
 // ProductHandler 商品处理器接口
 type ProductHandler interface {
 	SearchProducts(c *gin.Context)
+	// RebuildIndex 立即重建商品检索索引，商品上新或修改后调用
+	RebuildIndex(c *gin.Context)
+}
+
+// RecommendationHandler 推荐处理器接口
+type RecommendationHandler interface {
+	TrackEvent(c *gin.Context)
+	ConversionMetrics(c *gin.Context)
+}
+
+// ProfileHandler 用户画像处理器接口
+type ProfileHandler interface {
+	GetProfile(c *gin.Context)
+	UpdateProfile(c *gin.Context)
+	PatchProfile(c *gin.Context)
+	ListHistory(c *gin.Context)
+}
+
+// UsageHandler token 用量处理器接口
+type UsageHandler interface {
+	UserUsage(c *gin.Context)
+	AdminUserUsage(c *gin.Context)
+	MerchantUsage(c *gin.Context)
+}
+
+// RankingHandler 排序处理器接口
+type RankingHandler interface {
+	Preview(c *gin.Context)
+}
+
+// StyleHandler 导购风格处理器接口
+type StyleHandler interface {
+	List(c *gin.Context)
+	Preview(c *gin.Context)
 }
 
 // AdminHandler 管理处理器接口
 type AdminHandler interface {
+	// Livez 存活检查，进程能处理请求即返回 200
+	Livez(c *gin.Context)
+	// Health 就绪检查，检查 Redis、MySQL 等依赖
 	Health(c *gin.Context)
 	Metrics(c *gin.Context)
+	// Config 当前生效的配置版本与脱敏后的配置
+	Config(c *gin.Context)
 }
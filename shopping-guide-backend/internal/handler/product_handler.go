@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productHandler 商品处理器实现
+type productHandler struct {
+	productService service.ProductService
+}
+
+// NewProductHandler 创建商品处理器
+func NewProductHandler(productService service.ProductService) ProductHandler {
+	return &productHandler{
+		productService: productService,
+	}
+}
+
+// SearchProducts 商品检索（供Dify调用）
+func (h *productHandler) SearchProducts(c *gin.Context) {
+	var req model.ProductSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, err.Error()))
+		return
+	}
+
+	switch req.Mode {
+	case "", model.SearchModeKeyword, model.SearchModeVector, model.SearchModeHybrid:
+	default:
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, "invalid search mode: "+req.Mode))
+		return
+	}
+
+	resp, err := h.productService.SearchProducts(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(resp))
+}
+
+// RebuildIndex 重建商品检索索引（管理接口）
+func (h *productHandler) RebuildIndex(c *gin.Context) {
+	if err := h.productService.RebuildIndex(c.Request.Context()); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(nil))
+}
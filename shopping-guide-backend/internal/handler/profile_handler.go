@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 画像变更记录分页参数
+const (
+	defaultProfileHistoryLimit = 50
+	maxProfileHistoryLimit     = 200
+)
+
+// profileHandler 用户画像处理器实现
+type profileHandler struct {
+	profileService service.ProfileService
+}
+
+// NewProfileHandler 创建用户画像处理器
+func NewProfileHandler(profileService service.ProfileService) ProfileHandler {
+	return &profileHandler{
+		profileService: profileService,
+	}
+}
+
+// GetProfile 获取用户画像
+func (h *profileHandler) GetProfile(c *gin.Context) {
+	if !authorizeUserAccess(c) {
+		return
+	}
+	profile, err := h.profileService.GetProfile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(profile))
+}
+
+// UpdateProfile 全量更新用户画像，画像不存在时创建
+func (h *profileHandler) UpdateProfile(c *gin.Context) {
+	if !authorizeUserAccess(c) {
+		return
+	}
+	var req model.ProfileUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, err.Error()))
+		return
+	}
+
+	profile := &model.UserProfile{
+		PreferredStyle:     req.PreferredStyle,
+		Age:                req.Age,
+		Gender:             req.Gender,
+		Interests:          req.Interests,
+		BudgetMin:          req.BudgetMin,
+		BudgetMax:          req.BudgetMax,
+		Sizes:              req.Sizes,
+		EnrichmentDisabled: req.EnrichmentDisabled,
+	}
+	if profile.Interests == nil {
+		profile.Interests = []string{}
+	}
+	if err := h.profileService.UpdateProfile(c.Request.Context(), c.Param("id"), profile); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(profile))
+}
+
+// PatchProfile 部分更新用户画像
+func (h *profileHandler) PatchProfile(c *gin.Context) {
+	if !authorizeUserAccess(c) {
+		return
+	}
+	var req model.ProfilePatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, err.Error()))
+		return
+	}
+
+	profile, err := h.profileService.PatchProfile(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(profile))
+}
+
+// ListHistory 画像变更记录
+func (h *profileHandler) ListHistory(c *gin.Context) {
+	if !authorizeUserAccess(c) {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultProfileHistoryLimit)))
+	if err != nil || limit <= 0 || limit > maxProfileHistoryLimit {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, "limit must be between 1 and 200"))
+		return
+	}
+
+	histories, err := h.profileService.ListHistory(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(histories))
+}
+
+// authorizeUserAccess 只允许已登录用户访问自己的画像、用量等数据
+func authorizeUserAccess(c *gin.Context) bool {
+	principal, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse(model.CodeUnauthorized, "unauthenticated"))
+		return false
+	}
+	if principal.Guest || principal.UserID != c.Param("id") {
+		c.JSON(http.StatusForbidden, model.NewErrorResponse(model.CodeForbidden, "cannot access data of another user"))
+		return false
+	}
+	return true
+}
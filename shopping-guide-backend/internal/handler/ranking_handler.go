@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rankingHandler 排序处理器实现
+type rankingHandler struct {
+	rankingService service.RankingService
+}
+
+// NewRankingHandler 创建排序处理器
+func NewRankingHandler(rankingService service.RankingService) RankingHandler {
+	return &rankingHandler{
+		rankingService: rankingService,
+	}
+}
+
+// Preview 按指定权重预览排序结果及每个商品的得分明细
+func (h *rankingHandler) Preview(c *gin.Context) {
+	var req model.RankingPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, err.Error()))
+		return
+	}
+
+	resp, err := h.rankingService.Preview(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(resp))
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMetricsWindow 转化统计默认时间窗口
+const defaultMetricsWindow = 7 * 24 * time.Hour
+
+// recommendationHandler 推荐处理器实现
+type recommendationHandler struct {
+	recommendationService service.RecommendationService
+}
+
+// NewRecommendationHandler 创建推荐处理器
+func NewRecommendationHandler(recommendationService service.RecommendationService) RecommendationHandler {
+	return &recommendationHandler{
+		recommendationService: recommendationService,
+	}
+}
+
+// TrackEvent 上报推荐商品的用户行为
+func (h *recommendationHandler) TrackEvent(c *gin.Context) {
+	recID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || recID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, "invalid recommendation id"))
+		return
+	}
+
+	var req model.RecommendationEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, err.Error()))
+		return
+	}
+
+	if err := h.recommendationService.TrackEvent(c.Request.Context(), recID, req.Action); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(nil))
+}
+
+// ConversionMetrics 推荐转化统计
+// group_by: tool/product/prompt_version，since: RFC3339 时间，默认最近7天
+func (h *recommendationHandler) ConversionMetrics(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "tool")
+	switch groupBy {
+	case "tool", "product", "prompt_version":
+	default:
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, "group_by must be one of tool/product/prompt_version"))
+		return
+	}
+
+	since := time.Now().Add(-defaultMetricsWindow)
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, "since must be RFC3339 time"))
+			return
+		}
+		since = t
+	}
+
+	stats, err := h.recommendationService.ConversionMetrics(c.Request.Context(), groupBy, since)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(gin.H{
+		"group_by": groupBy,
+		"since":    since.Format(time.RFC3339),
+		"stats":    stats,
+	}))
+}
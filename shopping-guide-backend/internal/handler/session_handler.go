@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionHandler 会话处理器实现
+type sessionHandler struct {
+	sessionService service.SessionService
+}
+
+// NewSessionHandler 创建会话处理器
+func NewSessionHandler(sessionService service.SessionService) SessionHandler {
+	return &sessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// CreateSession 为调用方创建会话，可指定会话级风格与商家
+func (h *sessionHandler) CreateSession(c *gin.Context) {
+	principal, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse(model.CodeUnauthorized, "unauthenticated"))
+		return
+	}
+
+	var req model.SessionCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, err.Error()))
+		return
+	}
+	req.UserID = principal.UserID
+	req.MerchantID = principal.MerchantID
+
+	session, err := h.sessionService.CreateSession(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(&model.SessionCreateResponse{
+		SessionID: session.SessionID,
+		CreatedAt: session.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: session.ExpiresAt.Format(time.RFC3339),
+	}))
+}
+
+// GetSession 获取调用方自己的会话
+func (h *sessionHandler) GetSession(c *gin.Context) {
+	session, ok := h.ownedSession(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, model.NewSuccessResponse(session))
+}
+
+// ListSessions 会话列表
+func (h *sessionHandler) ListSessions(c *gin.Context) {
+	// TODO: 需要按用户维护会话索引（user:sessions:{user_id}）
+	c.JSON(http.StatusNotImplemented, model.NewErrorResponse(model.CodeInternalError, "listing sessions is not supported yet"))
+}
+
+// DeleteSession 删除调用方自己的会话
+func (h *sessionHandler) DeleteSession(c *gin.Context) {
+	session, ok := h.ownedSession(c)
+	if !ok {
+		return
+	}
+	if err := h.sessionService.DeleteSession(c.Request.Context(), session.SessionID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, model.NewSuccessResponse(nil))
+}
+
+// ownedSession 读取路径中的会话并校验归属，失败时已写入响应
+func (h *sessionHandler) ownedSession(c *gin.Context) (*model.Session, bool) {
+	principal, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse(model.CodeUnauthorized, "unauthenticated"))
+		return nil, false
+	}
+
+	session, err := h.sessionService.GetSession(c.Request.Context(), c.Param("session_id"))
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(model.CodeNotFound, "session not found"))
+		return nil, false
+	}
+	if session.UserID != principal.UserID {
+		c.JSON(http.StatusForbidden, model.NewErrorResponse(model.CodeForbidden, "session belongs to another user"))
+		return nil, false
+	}
+	return session, true
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/style"
+
+	"github.com/gin-gonic/gin"
+)
+
+// styleHandler 导购风格处理器实现
+type styleHandler struct {
+	styles *style.Registry
+}
+
+// NewStyleHandler 创建导购风格处理器
+func NewStyleHandler(styles *style.Registry) StyleHandler {
+	return &styleHandler{
+		styles: styles,
+	}
+}
+
+// List 所有风格定义
+func (h *styleHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, model.NewSuccessResponse(h.styles.List()))
+}
+
+// Preview 用样例回复预览风格策略的效果，以及传给工作流的风格参数
+func (h *styleHandler) Preview(c *gin.Context) {
+	var req model.StylePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, err.Error()))
+		return
+	}
+
+	s, ok := h.styles.Get(req.Style)
+	if !ok {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse(model.CodeNotFound, "style not found: "+req.Style))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(&model.StylePreviewResponse{
+		Style:  s.Name,
+		Output: s.Apply(req.Answer),
+		Inputs: s.Inputs(),
+	}))
+}
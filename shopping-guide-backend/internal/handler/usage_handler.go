@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 用量历史查询天数
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 90
+)
+
+// usageHandler token 用量处理器实现
+type usageHandler struct {
+	quotaService service.QuotaService
+}
+
+// NewUsageHandler 创建 token 用量处理器
+func NewUsageHandler(quotaService service.QuotaService) UsageHandler {
+	return &usageHandler{
+		quotaService: quotaService,
+	}
+}
+
+// UserUsage 用户查询自己的用量
+func (h *usageHandler) UserUsage(c *gin.Context) {
+	if !authorizeUserAccess(c) {
+		return
+	}
+	h.usage(c, model.QuotaScopeUser)
+}
+
+// AdminUserUsage 管理端查询用户用量
+func (h *usageHandler) AdminUserUsage(c *gin.Context) {
+	h.usage(c, model.QuotaScopeUser)
+}
+
+// MerchantUsage 管理端查询商家用量
+func (h *usageHandler) MerchantUsage(c *gin.Context) {
+	h.usage(c, model.QuotaScopeMerchant)
+}
+
+func (h *usageHandler) usage(c *gin.Context, scope string) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultUsageDays)))
+	if err != nil || days <= 0 || days > maxUsageDays {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse(model.CodeInvalidParams, "days must be between 1 and 90"))
+		return
+	}
+
+	resp, err := h.quotaService.Usage(c.Request.Context(), scope, c.Param("id"), days)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewSuccessResponse(resp))
+}
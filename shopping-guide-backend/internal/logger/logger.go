@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"shopping-guide-backend/internal/config"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type loggerKey struct{}
+
+// New 按日志配置创建 slog 日志器
+// format 为 json 或 console（文本）；output 为 file 时按 max_size/max_age/max_backups 滚动并可压缩旧文件
+// 返回的 io.Closer 用于退出时关闭日志文件
+func New(cfg *config.LogConfig) (*slog.Logger, io.Closer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil && cfg.Level != "" {
+		return nil, nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+
+	var w io.Writer
+	var closer io.Closer = nopCloser{}
+	switch strings.ToLower(cfg.Output) {
+	case "", "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	case "file":
+		if cfg.FilePath == "" {
+			return nil, nil, fmt.Errorf("log file_path is required when output is file")
+		}
+		rotator := &lumberjack.Logger{
+			Filename:   cfg.FilePath,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		}
+		w, closer = rotator, rotator
+	default:
+		return nil, nil, fmt.Errorf("unsupported log output: %s", cfg.Output)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "console", "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, nil, fmt.Errorf("unsupported log format: %s", cfg.Format)
+	}
+
+	return slog.New(handler), closer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// WithContext 把日志器放入 context，后续通过 FromContext 取出
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext 取出 context 中的日志器（带请求ID、用户等请求级字段），没有时返回默认日志器
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With 为 context 中的日志器追加字段
+func With(ctx context.Context, args ...any) context.Context {
+	return WithContext(ctx, FromContext(ctx).With(args...))
+}
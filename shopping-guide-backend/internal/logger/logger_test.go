@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"shopping-guide-backend/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, closer, err := New(&config.LogConfig{Level: "warn", Format: "json", Output: "file", FilePath: path, MaxSize: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	l.Info("dropped")
+	l.Warn("kept", "user_id", "user_1")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("log lines = %q, want only the warn record", lines)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("log line is not json: %v", err)
+	}
+	if record["msg"] != "kept" || record["level"] != "WARN" || record["user_id"] != "user_1" {
+		t.Errorf("record = %v", record)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.LogConfig
+		wantErr string
+	}{
+		{name: "invalid level", cfg: config.LogConfig{Level: "verbose"}, wantErr: "invalid log level"},
+		{name: "file without path", cfg: config.LogConfig{Output: "file"}, wantErr: "file_path is required"},
+		{name: "unsupported output", cfg: config.LogConfig{Output: "syslog"}, wantErr: "unsupported log output"},
+		{name: "unsupported format", cfg: config.LogConfig{Format: "xml"}, wantErr: "unsupported log format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := New(&tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithContext(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+	ctx = With(ctx, "request_id", "req-1")
+	ctx = With(ctx, "user_id", "user_1")
+
+	FromContext(ctx).Info("hello")
+	if out := buf.String(); !strings.Contains(out, "request_id=req-1") || !strings.Contains(out, "user_id=user_1") {
+		t.Errorf("log = %q", out)
+	}
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext() without logger is not the default logger")
+	}
+}
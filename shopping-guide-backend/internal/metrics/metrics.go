@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 指标名前缀
+const namespace = "shopping_guide"
+
+// latencyBuckets 对话与 Dify 调用耗时分桶（秒），覆盖 100ms 到 60s
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 8, 10, 15, 20, 30, 60}
+
+var (
+	// HTTPRequestDuration HTTP 请求耗时，route 为路由模板，未匹配路由记为 unmatched
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// ChatTurnDuration 一轮对话（编排全流程）耗时
+	ChatTurnDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "chat_turn_duration_seconds",
+		Help:      "End-to-end chat turn latency by executor tool.",
+		Buckets:   latencyBuckets,
+	}, []string{"tool"})
+
+	// ChatTurnFailures 失败的对话轮次，reason 为 unauthenticated/forbidden/quota_exceeded/error
+	ChatTurnFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_turn_failures_total",
+		Help:      "Failed chat turns by reason.",
+	}, []string{"reason"})
+
+	// PlannerToolTotal Planner 选择的工具分布，不在已知工具列表中的记为 other
+	PlannerToolTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "planner_tool_total",
+		Help:      "Planner tool selections.",
+	}, []string{"tool"})
+
+	// DifyCallDuration Dify 工作流调用耗时，status 为 success/error/timeout
+	DifyCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dify_call_duration_seconds",
+		Help:      "Dify workflow call latency by workflow and status.",
+		Buckets:   latencyBuckets,
+	}, []string{"workflow", "status"})
+
+	// DifyTokens Dify 工作流消耗的 tokens
+	DifyTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dify_tokens_total",
+		Help:      "Tokens consumed by Dify workflows.",
+	}, []string{"workflow"})
+
+	// DifyCircuitBreakerState Dify 熔断器状态：0 关闭、1 半开、2 打开
+	DifyCircuitBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "dify_circuit_breaker_state",
+		Help:      "Dify circuit breaker state (0 closed, 1 half-open, 2 open).",
+	})
+
+	// SessionsCreated 新建会话数，user_type 为 user/guest
+	SessionsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_created_total",
+		Help:      "Sessions created by user type.",
+	}, []string{"user_type"})
+
+	// SessionsDeleted 主动删除的会话数（过期由 Redis TTL 清理，不计入）
+	SessionsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_deleted_total",
+		Help:      "Sessions deleted via API.",
+	})
+
+	// SSEActiveStreams 正在推送的 SSE 流
+	SSEActiveStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sse_active_streams",
+		Help:      "Active server-sent event streams.",
+	})
+
+	// Panics 捕获的 panic 数，source 为 http 或后台任务名
+	Panics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_total",
+		Help:      "Panics recovered in HTTP handlers and background goroutines.",
+	}, []string{"source"})
+
+	// GroundingResponses 经过商品校验的回复数，with_issues 表示存在未命中商品或报价不符
+	GroundingResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grounding_responses_total",
+		Help:      "Responses checked by grounding.",
+	}, []string{"with_issues"})
+
+	// GroundingMentions 商品提及数，result 为 matched/unmatched
+	GroundingMentions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grounding_mentions_total",
+		Help:      "Product mentions found by grounding.",
+	}, []string{"result"})
+
+	// GroundingPriceMismatches 报价与商品库不符的次数
+	GroundingPriceMismatches = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grounding_price_mismatches_total",
+		Help:      "Quoted prices that differ from the catalog.",
+	})
+)
+
+// Handler Prometheus 抓取接口
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDBStats 注册 MySQL 连接池指标，重复注册时忽略
+func RegisterDBStats(db *sql.DB) error {
+	return register(collectors.NewDBStatsCollector(db, "mysql"))
+}
+
+// RegisterRedisPoolStats 注册 Redis 连接池指标，重复注册时忽略
+func RegisterRedisPoolStats(rdb *redis.Client) error {
+	return register(&redisPoolCollector{rdb: rdb})
+}
+
+func register(c prometheus.Collector) error {
+	if err := prometheus.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return err
+		}
+	}
+	return nil
+}
+
+// redisPoolCollector 抓取时读取 go-redis 连接池统计
+type redisPoolCollector struct {
+	rdb *redis.Client
+}
+
+var (
+	redisPoolHits = prometheus.NewDesc(namespace+"_redis_pool_hits_total",
+		"Times a free connection was found in the pool.", nil, nil)
+	redisPoolMisses = prometheus.NewDesc(namespace+"_redis_pool_misses_total",
+		"Times a free connection was not found in the pool.", nil, nil)
+	redisPoolTimeouts = prometheus.NewDesc(namespace+"_redis_pool_timeouts_total",
+		"Times a wait for a connection timed out.", nil, nil)
+	redisPoolTotalConns = prometheus.NewDesc(namespace+"_redis_pool_total_connections",
+		"Total connections in the pool.", nil, nil)
+	redisPoolIdleConns = prometheus.NewDesc(namespace+"_redis_pool_idle_connections",
+		"Idle connections in the pool.", nil, nil)
+	redisPoolStaleConns = prometheus.NewDesc(namespace+"_redis_pool_stale_connections_total",
+		"Stale connections removed from the pool.", nil, nil)
+)
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- redisPoolHits
+	ch <- redisPoolMisses
+	ch <- redisPoolTimeouts
+	ch <- redisPoolTotalConns
+	ch <- redisPoolIdleConns
+	ch <- redisPoolStaleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.rdb.PoolStats()
+	ch <- prometheus.MustNewConstMetric(redisPoolHits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(redisPoolMisses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(redisPoolTimeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(redisPoolTotalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(redisPoolIdleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(redisPoolStaleConns, prometheus.CounterValue, float64(stats.StaleConns))
+}
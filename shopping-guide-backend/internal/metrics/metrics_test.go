@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// scrape 抓取一次 /metrics 输出
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestRegisterRedisPoolStats(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	for i := 0; i < 2; i++ {
+		if err := RegisterRedisPoolStats(rdb); err != nil {
+			t.Fatalf("RegisterRedisPoolStats() #%d error = %v", i+1, err)
+		}
+	}
+	if err := rdb.Ping(rdb.Context()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	body := scrape(t)
+	for _, want := range []string{
+		"shopping_guide_redis_pool_total_connections 1",
+		"shopping_guide_redis_pool_misses_total 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestMetricsExposed(t *testing.T) {
+	PlannerToolTotal.WithLabelValues("QA_ASSISTANT_MODULE").Inc()
+	DifyCallDuration.WithLabelValues("planner", "success").Observe(0.3)
+
+	body := scrape(t)
+	for _, want := range []string{
+		`shopping_guide_planner_tool_total{tool="QA_ASSISTANT_MODULE"} 1`,
+		`shopping_guide_dify_call_duration_seconds_bucket{status="success",workflow="planner",le="0.5"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
@@ -1,49 +1,579 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/panics"
+	"shopping-guide-backend/internal/ratelimit"
+	"shopping-guide-backend/internal/requestctx"
+	"shopping-guide-backend/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
 
 // 中间件函数类型定义
 
-// Auth 鉴权中间件
-func Auth() gin.HandlerFunc {
+// 身份相关请求/响应头
+const (
+	HeaderGuestID = "X-Guest-ID" // 访客ID，由服务端下发，客户端后续请求回传
+	HeaderUserID  = "X-User-ID"  // 仅在开启 trust_user_header（本地调试）时信任
+
+	HeaderInternalToken = "X-Internal-Token" // 内部接口（供 Dify 调用）的服务令牌
+)
+
+// ContextKeyPrincipal gin.Context 中保存调用方身份的键
+const ContextKeyPrincipal = "principal"
+
+// Auth JWT 鉴权中间件
+// 校验 Authorization: Bearer <access_token>，通过后将登录用户身份写入 gin.Context 与请求 context；
+// exempt_paths 中的路径不鉴权，guest_paths 中的路径未携带令牌时放行，由 Identity 按访客处理
+func Auth(cfg *config.AuthConfig, tokens *auth.TokenManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !cfg.Enabled || c.Request.Method == http.MethodOptions || matchPath(cfg.ExemptPaths, path) {
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			if matchPath(cfg.GuestPaths, path) {
+				c.Next()
+				return
+			}
+			abortUnauthorized(c, "missing bearer token")
+			return
+		}
+
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			abortUnauthorized(c, "invalid authorization header")
+			return
+		}
+		claims, err := tokens.Parse(strings.TrimSpace(tokenString), auth.TokenTypeAccess)
+		if err != nil {
+			abortUnauthorized(c, "invalid or expired token")
+			return
+		}
+
+		principal := &auth.Principal{UserID: claims.Subject, Roles: claims.Roles, MerchantID: claims.MerchantID}
+		c.Set(ContextKeyPrincipal, principal)
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, model.NewErrorResponse(model.CodeUnauthorized, message))
+}
+
+// AdminOnly 管理接口鉴权中间件，需放在 Auth 之后
+// 只放行令牌中带 admin 角色的用户；关闭鉴权时一律拒绝，仅开启 trust_user_header（dev 环境）时放行便于本地调试
+func AdminOnly(cfg *config.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled && cfg.TrustUserHeader {
+			c.Next()
+			return
+		}
+		principal, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			abortUnauthorized(c, "missing bearer token")
+			return
+		}
+		if !principal.HasRole(auth.RoleAdmin) {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.NewErrorResponse(model.CodeForbidden, "admin role required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// InternalOnly 内部接口鉴权中间件，校验 X-Internal-Token 与 middleware.auth.internal_token 一致
+// 未配置 internal_token 时放行，配置校验保证只有 dev 环境可以不配置
+func InternalOnly(cfg *config.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.InternalToken == "" {
+			c.Next()
+			return
+		}
+		token := c.GetHeader(HeaderInternalToken)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.InternalToken)) != 1 {
+			abortUnauthorized(c, "invalid internal token")
+			return
+		}
+		c.Next()
+	}
+}
+
+// matchPath 路径匹配，规则以 * 结尾时按前缀匹配，否则精确匹配
+func matchPath(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity 身份解析中间件
+// 前置鉴权中间件已写入身份时直接使用；否则按访客处理：回传了合法访客ID时沿用，没有则分配新的访客ID
+// 开启 trust_user_header（仅 dev 环境）时，未携带令牌的请求信任 X-User-ID 请求头，便于本地调试
+func Identity(cfg *config.AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现鉴权逻辑
+		principal, ok := auth.FromContext(c.Request.Context())
+		if !ok && cfg.TrustUserHeader {
+			if userID := c.GetHeader(HeaderUserID); userID != "" {
+				principal, ok = &auth.Principal{UserID: userID, Guest: auth.IsGuestID(userID)}, true
+			}
+		}
+		if !ok {
+			principal, ok = auth.ParseGuest(c.GetHeader(HeaderGuestID))
+			if !ok {
+				principal = auth.NewGuest()
+			}
+			c.Header(HeaderGuestID, principal.UserID)
+		}
+
+		c.Set(ContextKeyPrincipal, principal)
+		ctx := auth.WithPrincipal(c.Request.Context(), principal)
+		c.Request = c.Request.WithContext(logger.With(ctx, "user_id", principal.UserID))
 		c.Next()
 	}
 }
 
-// RateLimit 限流中间件
-func RateLimit() gin.HandlerFunc {
+// 限流响应头
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset" // 距额度完全恢复的秒数
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// RateLimit 限流中间件，需放在 Identity 之后
+// 按调用方与接口限流，计数保存在 Redis 中由所有副本共享；访客ID由客户端回传、可随意更换，访客按客户端IP限流
+// Redis 不可用时放行请求，避免限流组件故障导致服务整体不可用；限额按 middleware.rate_limit 热更新
+func RateLimit(store *config.Store, limiter ratelimit.Limiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现限流逻辑
+		cfg := store.Get()
+		if !cfg.Middleware.RateLimit.Enabled || limiter == nil {
+			c.Next()
+			return
+		}
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		limit := routeLimit(&cfg.Middleware.RateLimit, cfg.Redis.RateLimitWindow, c.Request.Method, path)
+		key := rateLimitSubject(c) + ":" + c.Request.Method + ":" + path
+
+		result, err := limiter.Allow(c.Request.Context(), key, limit)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn("rate limit check failed, request allowed", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+		c.Header(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+		c.Header(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			c.Header(HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, model.NewErrorResponse(model.CodeRateLimited, "too many requests"))
+			return
+		}
 		c.Next()
 	}
 }
 
-// Logger 日志中间件
+// routeLimit 接口限额，未单独配置时使用默认限额
+func routeLimit(cfg *config.RateLimitConfig, window time.Duration, method, path string) ratelimit.Limit {
+	if window <= 0 {
+		window = time.Minute
+	}
+	limit := ratelimit.Limit{Rate: cfg.RequestsPerMinute, Period: window, Burst: cfg.Burst}
+	for _, route := range cfg.Routes {
+		if route.Path == path && (route.Method == "" || strings.EqualFold(route.Method, method)) {
+			limit.Rate, limit.Burst = route.RequestsPerMinute, route.Burst
+			break
+		}
+	}
+	return limit
+}
+
+// rateLimitSubject 限流主体：登录用户按用户ID，访客与未识别身份的请求按客户端IP
+// 客户端IP只采信 server.trusted_proxies 转发的 X-Forwarded-For，其余取连接地址
+func rateLimitSubject(c *gin.Context) string {
+	if principal, ok := auth.FromContext(c.Request.Context()); ok && !principal.Guest {
+		return principal.UserID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// 请求日志使用的 gin.Context 键
+const (
+	ContextKeyRequestID = "request_id"
+	ContextKeySessionID = "session_id" // 由处理器在确定会话后写入
+)
+
+// RequestID 请求关联中间件，需放在最前
+// 沿用请求头中的 X-Request-ID，没有或不合法时生成；接受 W3C traceparent，沿用上游网关的 trace-id。
+// 请求ID写回响应头，关联信息放入请求 context，由 service 写入日志表并透传给 Dify
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m := requestctx.New(c.GetHeader(requestctx.HeaderRequestID), c.GetHeader(requestctx.HeaderTraceparent))
+		m.ClientIP = c.ClientIP()
+		c.Set(ContextKeyRequestID, m.RequestID)
+		c.Header(requestctx.HeaderRequestID, m.RequestID)
+
+		ctx := requestctx.WithMetadata(c.Request.Context(), m)
+		c.Request = c.Request.WithContext(logger.With(ctx, "request_id", m.RequestID))
+		c.Next()
+	}
+}
+
+// Tracing 链路追踪中间件，需放在 RequestID 之后
+// 从请求头提取 W3C trace 上下文并创建服务端 span；启用追踪时以 span 的 trace-id/span-id 更新请求关联信息，
+// 使日志、日志表与导出的链路一致
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		parent := trace.SpanContextFromContext(ctx)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				tracing.AttrRequestID.String(c.GetString(ContextKeyRequestID)),
+			),
+		)
+		defer span.End()
+
+		if m, ok := requestctx.FromContext(ctx); ok {
+			// 未启用追踪时 noop span 沿用上游的 span context，此时保留 RequestID 中间件生成的值
+			if sc := span.SpanContext(); sc.IsValid() && sc.SpanID() != parent.SpanID() {
+				m.TraceID, m.SpanID, m.TraceFlags = sc.TraceID().String(), sc.SpanID().String(), sc.TraceFlags().String()
+			}
+			ctx = logger.With(ctx, "trace_id", m.TraceID)
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// Metrics HTTP 请求指标中间件，按方法、路由模板与状态码记录耗时
+// 非标准方法记为 other，避免客户端随意构造方法名导致标签基数膨胀
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(metricMethod(c.Request.Method), route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// metricMethod 指标使用的方法标签，非标准方法归为 other
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "other"
+	}
+}
+
+// Logger 请求日志中间件，需放在 RequestID 之后
+// 请求结束后记录方法、路由、状态码、耗时、用户、会话与请求ID
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: 实现日志逻辑
+		start := time.Now()
+
 		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"request_id", c.GetString(ContextKeyRequestID),
+		}
+		if m, ok := requestctx.FromContext(c.Request.Context()); ok {
+			attrs = append(attrs, "trace_id", m.TraceID)
+		}
+		if principal, ok := auth.FromContext(c.Request.Context()); ok {
+			attrs = append(attrs, "user_id", principal.UserID)
+		}
+		sessionID := c.GetString(ContextKeySessionID)
+		if sessionID == "" {
+			sessionID = c.Param("session_id")
+		}
+		if sessionID != "" {
+			attrs = append(attrs, "session_id", sessionID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.Default().Log(c.Request.Context(), level, "http request", attrs...)
 	}
 }
 
-// Recovery 恢复中间件
+// Recovery 恢复中间件，需放在 RequestID 与 Logger 之后
+// 记录 panic 堆栈（带请求ID）并计入 panics_total，返回 500 与统一错误响应；
+// 响应已开始写出时无法再改状态码：SSE 流补发 error 事件后结束，其他响应直接中止。客户端断开导致的写失败不按 panic 处理
 func Recovery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				// TODO: 处理panic
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// http.ErrAbortHandler 用于有意中止响应，交给 net/http 处理
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			ctx := c.Request.Context()
+			if isBrokenPipe(rec) {
+				logger.FromContext(ctx).Warn("client connection closed", "error", rec)
+				c.Abort()
+				return
+			}
+
+			panics.Report(ctx, panics.SourceHTTP, rec)
+			resp := model.NewErrorResponse(model.CodeInternalError, "internal server error")
+			if !c.Writer.Written() {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, resp)
+				return
+			}
+			if strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") {
+				c.SSEvent(model.StreamEventError, resp)
+				c.Writer.Flush()
 			}
+			c.Abort()
 		}()
 		c.Next()
 	}
 }
 
-// CORS 跨域中间件
-func CORS() gin.HandlerFunc {
+// isBrokenPipe 是否为客户端断开连接后写响应失败
+func isBrokenPipe(rec any) bool {
+	err, ok := rec.(error)
+	if !ok {
+		return false
+	}
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// defaultCORSMethods 策略未配置 allow_methods 时允许的方法
+var defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORS 跨域中间件，需注册在引擎上（r.Use）以处理未注册 OPTIONS 路由的预检请求，并放在 Auth 之前
+// 按请求路径选择策略：匹配 groups 中最长的路由组前缀，没有匹配时使用顶层策略。
+// 预检请求直接返回 204，来源不被允许时返回 403；普通请求来源不被允许时不输出跨域头，由浏览器拦截
+func CORS(cfg *config.CORSConfig) gin.HandlerFunc {
+	root := newCORSPolicy(&cfg.CORSPolicy)
+	groups := make(map[string]*corsPolicy, len(cfg.Groups))
+	for prefix, policy := range cfg.Groups {
+		policy := policy
+		groups[strings.TrimSuffix(prefix, "/")] = newCORSPolicy(&policy)
+	}
+
 	return func(c *gin.Context) {
-		// TODO: 实现跨域逻辑
-		c.Next()
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		policy := root
+		matched := 0
+		path := c.Request.URL.Path
+		for prefix, p := range groups {
+			if len(prefix) > matched && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+				policy, matched = p, len(prefix)
+			}
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !policy.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatusJSON(http.StatusForbidden, model.NewErrorResponse(model.CodeForbidden, "origin not allowed"))
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if policy.allowAll && !policy.credentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if policy.exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", policy.methods)
+		if policy.anyHeader {
+			if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+		} else if policy.headers != "" {
+			h.Set("Access-Control-Allow-Headers", policy.headers)
+		}
+		if policy.maxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(policy.maxAge))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// corsPolicy 预处理后的跨域策略
+type corsPolicy struct {
+	allowAll    bool
+	origins     map[string]bool
+	wildcards   [][2]string // 含 * 的来源拆成 * 前后两段
+	credentials bool
+
+	methods       string
+	headers       string
+	anyHeader     bool
+	exposeHeaders string
+	maxAge        int // 秒
+}
+
+func newCORSPolicy(cfg *config.CORSPolicy) *corsPolicy {
+	p := &corsPolicy{
+		origins:       make(map[string]bool, len(cfg.AllowOrigins)),
+		credentials:   cfg.AllowCredentials,
+		exposeHeaders: strings.Join(cfg.ExposeHeaders, ", "),
+		maxAge:        int(cfg.MaxAge / time.Second),
+	}
+	for _, origin := range cfg.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			p.allowAll = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			p.wildcards = append(p.wildcards, [2]string{prefix, suffix})
+		default:
+			p.origins[origin] = true
+		}
+	}
+
+	methods := cfg.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	p.methods = strings.ToUpper(strings.Join(methods, ", "))
+	for _, header := range cfg.AllowHeaders {
+		if header == "*" {
+			p.anyHeader = true
+		}
+	}
+	p.headers = strings.Join(cfg.AllowHeaders, ", ")
+	return p
+}
+
+// allowOrigin 来源是否被允许；* 只匹配域名字符，https://*.shop.example.com 匹配任意层级的子域名，不匹配 shop.example.com 本身
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		prefix, suffix := w[0], w[1]
+		if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+		if isHostLabel(origin[len(prefix) : len(origin)-len(suffix)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHostLabel 是否只包含域名字符，避免 * 匹配到端口、路径或其他站点
+func isHostLabel(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
 	}
+	return !strings.HasPrefix(s, ".") && !strings.HasSuffix(s, ".")
 }
@@ -0,0 +1,381 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMatchPath(t *testing.T) {
+	patterns := []string{"/health", "/api/v1/auth/*", "/api/v1/sessions*", "/internal/*"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/health", true},
+		{"/health/", false},
+		{"/healthz", false},
+		{"/api/v1/auth/login", true},
+		{"/api/v1/auth/", true},
+		{"/api/v1/auth", false},
+		{"/api/v1/sessions", true},
+		{"/api/v1/sessions/abc", true},
+		{"/api/v1/sessionsX", true},
+		{"/internal/products/search", true},
+		{"/internal", false},
+		{"/api/v1/chat", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := matchPath(patterns, tt.path); got != tt.want {
+			t.Errorf("matchPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if matchPath(nil, "/health") {
+		t.Error("matchPath(nil) = true, want false")
+	}
+}
+
+// authTestManager 测试用的 HS256 令牌管理器
+func authTestManager(t *testing.T, cfg *config.AuthConfig) *auth.TokenManager {
+	t.Helper()
+	cfg.JWTSecret = "test-secret"
+	cfg.TokenExpire = time.Hour
+	cfg.RefreshExpire = time.Hour
+	tokens, err := auth.NewTokenManager(cfg)
+	if err != nil {
+		t.Fatalf("NewTokenManager() error = %v", err)
+	}
+	return tokens
+}
+
+// serve 依次经过 handlers 处理请求，返回状态码与最终写入 context 的身份
+func serve(handlers []gin.HandlerFunc, method, path string, header http.Header) (int, *auth.Principal) {
+	var principal *auth.Principal
+	r := gin.New()
+	handlers = append(handlers, func(c *gin.Context) {
+		principal, _ = auth.FromContext(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+	r.Handle(method, path, handlers...)
+
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, principal
+}
+
+func TestAuth(t *testing.T) {
+	cfg := &config.AuthConfig{
+		Enabled:     true,
+		ExemptPaths: []string{"/health", "/api/v1/auth/*"},
+		GuestPaths:  []string{"/api/v1/chat"},
+	}
+	tokens := authTestManager(t, cfg)
+	pair, err := tokens.Issue("user_1", []string{auth.RoleAdmin})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+
+	tests := []struct {
+		name       string
+		cfg        *config.AuthConfig
+		method     string
+		path       string
+		header     http.Header
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "exempt path without token", path: "/health", wantStatus: http.StatusNoContent},
+		{name: "exempt prefix without token", path: "/api/v1/auth/login", wantStatus: http.StatusNoContent},
+		{name: "exempt path ignores invalid token", path: "/health", header: bearer("bad"), wantStatus: http.StatusNoContent},
+		{name: "guest path without token", path: "/api/v1/chat", wantStatus: http.StatusNoContent},
+		{name: "guest path with invalid token", path: "/api/v1/chat", header: bearer("bad"), wantStatus: http.StatusUnauthorized},
+		{name: "guest path with token", path: "/api/v1/chat", header: bearer(pair.AccessToken), wantStatus: http.StatusNoContent, wantUser: "user_1"},
+		{name: "protected path without token", path: "/api/v1/users/user_1/profile", wantStatus: http.StatusUnauthorized},
+		{name: "protected path with token", path: "/api/v1/users/user_1/profile", header: bearer(pair.AccessToken), wantStatus: http.StatusNoContent, wantUser: "user_1"},
+		{name: "refresh token rejected", path: "/api/v1/users/user_1/profile", header: bearer(pair.RefreshToken), wantStatus: http.StatusUnauthorized},
+		{name: "non-bearer scheme", path: "/api/v1/users/user_1/profile", header: http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}}, wantStatus: http.StatusUnauthorized},
+		{name: "preflight skipped", method: http.MethodOptions, path: "/api/v1/users/user_1/profile", wantStatus: http.StatusNoContent},
+		{name: "auth disabled", cfg: &config.AuthConfig{}, path: "/api/v1/users/user_1/profile", wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			if tt.cfg != nil {
+				c = tt.cfg
+			}
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			status, principal := serve([]gin.HandlerFunc{Auth(c, tokens)}, method, tt.path, tt.header)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			var user string
+			if principal != nil {
+				user = principal.UserID
+			}
+			if user != tt.wantUser {
+				t.Errorf("principal = %q, want %q", user, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestAdminOnly(t *testing.T) {
+	cfg := &config.AuthConfig{Enabled: true}
+	tokens := authTestManager(t, cfg)
+	admin, err := tokens.Issue("admin_1", []string{auth.RoleAdmin})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	user, err := tokens.Issue("user_1", nil)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		cfg        *config.AuthConfig
+		token      string
+		wantStatus int
+	}{
+		{name: "admin role", cfg: cfg, token: admin.AccessToken, wantStatus: http.StatusNoContent},
+		{name: "without admin role", cfg: cfg, token: user.AccessToken, wantStatus: http.StatusForbidden},
+		{name: "without token", cfg: cfg, wantStatus: http.StatusUnauthorized},
+		{name: "auth disabled", cfg: &config.AuthConfig{}, wantStatus: http.StatusUnauthorized},
+		{name: "auth disabled with trusted user header", cfg: &config.AuthConfig{TrustUserHeader: true}, wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "Bearer "+tt.token)
+			}
+			status, _ := serve([]gin.HandlerFunc{Auth(tt.cfg, tokens), AdminOnly(tt.cfg)}, http.MethodGet, "/admin/config", header)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestInternalOnly(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		token      string
+		wantStatus int
+	}{
+		{name: "matching token", configured: "svc-token", token: "svc-token", wantStatus: http.StatusNoContent},
+		{name: "wrong token", configured: "svc-token", token: "svc-tokenX", wantStatus: http.StatusUnauthorized},
+		{name: "missing token", configured: "svc-token", wantStatus: http.StatusUnauthorized},
+		{name: "not configured", wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set(HeaderInternalToken, tt.token)
+			}
+			cfg := &config.AuthConfig{InternalToken: tt.configured}
+			status, _ := serve([]gin.HandlerFunc{InternalOnly(cfg)}, http.MethodPost, "/internal/products/search", header)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCORSPolicyAllowOrigin(t *testing.T) {
+	policy := newCORSPolicy(&config.CORSPolicy{
+		AllowOrigins: []string{"https://*.shop.example.com", "https://admin.example.com", "http://localhost:*"},
+	})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://admin.example.com", true},
+		{"HTTPS://ADMIN.EXAMPLE.COM", true},
+		{"https://admin.example.com:8443", false},
+		{"https://x.shop.example.com", true},
+		{"https://a.b.shop.example.com", true},
+		{"https://X.Shop.Example.com", true},
+		{"https://shop.example.com", false},
+		{"https://.shop.example.com", false},
+		{"https://x.shop.example.com.evil.com", false},
+		{"https://evilshop.example.com", false},
+		{"https://evil.com/.shop.example.com", false},
+		{"https://evil.com?.shop.example.com", false},
+		{"https://evil.com#.shop.example.com", false},
+		{"https://user@evil.com.shop.example.com", false},
+		{"https://x_y.shop.example.com", false},
+		{"https://x..shop.example.com", false},
+		{"http://x.shop.example.com", false},
+		{"https://x.shop.example.com:443", false},
+		{"http://localhost:3000", true},
+		{"http://localhost:3000/evil", false},
+		{"http://localhost:", false},
+		{"null", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := policy.allowOrigin(tt.origin); got != tt.want {
+			t.Errorf("allowOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	if !newCORSPolicy(&config.CORSPolicy{AllowOrigins: []string{"*"}}).allowOrigin("https://any.example.org") {
+		t.Error("allowOrigin with * = false, want true")
+	}
+	if newCORSPolicy(&config.CORSPolicy{}).allowOrigin("https://x.shop.example.com") {
+		t.Error("allowOrigin with empty allow_origins = true, want false")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	r := gin.New()
+	r.Use(RequestID(), Logger())
+	r.GET("/api/v1/sessions/:session_id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	r.POST("/api/v1/chat", func(c *gin.Context) {
+		c.Set(ContextKeySessionID, "sess-2")
+		c.Status(http.StatusBadGateway)
+	})
+
+	tests := []struct {
+		method, path  string
+		wantLevel     string
+		wantRoute     string
+		wantSessionID string
+	}{
+		{http.MethodGet, "/api/v1/sessions/sess-1", "WARN", "/api/v1/sessions/:session_id", "sess-1"},
+		{http.MethodPost, "/api/v1/chat", "ERROR", "/api/v1/chat", "sess-2"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set(requestctx.HeaderRequestID, "req-1")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("%s: log is not json: %v", tt.path, err)
+		}
+		if record["msg"] != "http request" || record["level"] != tt.wantLevel || record["route"] != tt.wantRoute ||
+			record["path"] != tt.path || record["session_id"] != tt.wantSessionID || record["request_id"] != "req-1" {
+			t.Errorf("%s: record = %v", tt.path, record)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/api/v1/sessions/:session_id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/sessions/sess-1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/sessions/sess-2", nil),
+		httptest.NewRequest("PROPFIND", "/unknown", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	// 路由按模板聚合，非标准方法与未匹配路由各自归为一类
+	for _, want := range []string{
+		`shopping_guide_http_request_duration_seconds_count{method="GET",route="/api/v1/sessions/:session_id",status="204"} 2`,
+		`shopping_guide_http_request_duration_seconds_count{method="other",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	r := gin.New()
+	r.Use(RequestID(), Recovery())
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.SSEvent(model.StreamEventChunk, "推荐")
+		c.Writer.Flush()
+		panic("boom")
+	})
+	r.GET("/broken-pipe", func(c *gin.Context) {
+		panic(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+
+	t.Run("error envelope", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		var resp model.APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("body = %q: %v", w.Body.String(), err)
+		}
+		if w.Code != http.StatusInternalServerError || resp.Code != model.CodeInternalError || resp.Message != "internal server error" {
+			t.Errorf("status = %d, resp = %+v", w.Code, resp)
+		}
+		if w.Header().Get(requestctx.HeaderRequestID) == "" {
+			t.Error("request id header missing")
+		}
+	})
+
+	t.Run("stream gets error event", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.HasPrefix(body, "event:chunk") || !strings.Contains(body, "event:error\ndata:{\"code\":500") {
+			t.Errorf("status = %d, body = %q", w.Code, body)
+		}
+	})
+
+	t.Run("broken pipe is not reported", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/broken-pipe", nil))
+		if w.Body.Len() != 0 {
+			t.Errorf("body = %q, want nothing written", w.Body.String())
+		}
+	})
+}
@@ -1,10 +1,11 @@
 package model
 
 // ChatRequest 对话请求
+// 用户身份由鉴权中间件解析，不从请求体读取
 type ChatRequest struct {
-	SessionID string `json:"session_id" binding:"required"`
+	SessionID string `json:"session_id"` // 为空时创建新会话
 	Query     string `json:"query" binding:"required"`
-	UserID    string `json:"user_id" binding:"required"`
+	Debug     bool   `json:"debug,omitempty"` // 返回排序得分明细，需开启 business.ranking.debug
 	//Context   map[string]interface{} `json:"context,omitempty"`
 }
 
@@ -19,16 +20,24 @@ type ChatResponse struct {
 
 // ChatMetadata 对话元数据
 type ChatMetadata struct {
-	PlannerResult PlannerResult `json:"planner_result"`
-	LatencyMs     int64         `json:"latency_ms"`
-	TokensUsed    int           `json:"tokens_used"`
+	RequestID     string           `json:"request_id"` // 请求ID，与响应头 X-Request-ID 一致
+	PlannerResult PlannerResult    `json:"planner_result"`
+	LatencyMs     int64            `json:"latency_ms"`
+	TokensUsed    int              `json:"tokens_used"`
+	Ranking       []ScoreBreakdown `json:"ranking,omitempty"`    // 推荐排序得分明细（debug 模式）
+	Comparison    *ComparisonTable `json:"comparison,omitempty"` // 商品对比表
+	Grounding     *GroundingReport `json:"grounding,omitempty"`  // 商品与价格校验结果
+	Style         string           `json:"style"`                // 本轮使用的导购风格
+	Quota         *QuotaStatus     `json:"quota,omitempty"`      // token 配额状态，degraded 表示本轮使用了低成本模式
 }
 
 // SessionCreateRequest 创建会话请求
 type SessionCreateRequest struct {
-	UserID              string   `json:"user_id" binding:"required"`
+	UserID              string   `json:"-"` // 由调用方身份填充
 	BusinessInstruction string   `json:"business_instruction"`
 	ProductCategories   []string `json:"product_categories"`
+	Style               string   `json:"style"` // 会话级风格，优先于用户偏好
+	MerchantID          string   `json:"-"`     // 商家ID，由调用方身份填充，不接受客户端传入
 }
 
 // SessionCreateResponse 创建会话响应
@@ -61,3 +70,16 @@ type SessionSummary struct {
 	LastMessage    string `json:"last_message"`
 	LastUpdateTime string `json:"last_update_time"`
 }
+
+// StylePreviewRequest 导购风格预览请求
+type StylePreviewRequest struct {
+	Style  string `json:"style" binding:"required"`
+	Answer string `json:"answer" binding:"required"` // 样例回复
+}
+
+// StylePreviewResponse 导购风格预览响应
+type StylePreviewResponse struct {
+	Style  string                 `json:"style"`
+	Output string                 `json:"output"` // 按 emoji 与长度策略处理后的回复
+	Inputs map[string]interface{} `json:"inputs"` // 传给工作流的风格参数
+}
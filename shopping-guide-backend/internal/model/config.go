@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// ConfigSnapshot 当前生效的配置
+type ConfigSnapshot struct {
+	Version  int64                  `json:"version"`   // 启动时为 1，每次热更新加 1
+	LoadedAt time.Time              `json:"loaded_at"` // 生效时间
+	Config   map[string]interface{} `json:"config"`    // 以 YAML 键名输出，密钥脱敏
+}
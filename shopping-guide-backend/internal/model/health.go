@@ -0,0 +1,28 @@
+package model
+
+// 健康状态
+const (
+	HealthStatusOK          = "ok"          // 所有依赖正常
+	HealthStatusDegraded    = "degraded"    // 非关键依赖异常，仍可接收流量
+	HealthStatusUnavailable = "unavailable" // 关键依赖异常，应摘除流量
+)
+
+// 依赖检查结果
+const (
+	DependencyStatusUp   = "up"
+	DependencyStatusDown = "down"
+)
+
+// HealthReport 就绪检查结果
+type HealthReport struct {
+	Status string             `json:"status"`
+	Checks []DependencyStatus `json:"checks"`
+}
+
+// DependencyStatus 单个依赖的检查结果
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"` // 关键依赖异常时服务不可用
+	LatencyMs int64  `json:"latency_ms"`
+}
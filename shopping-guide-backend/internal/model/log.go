@@ -5,6 +5,8 @@ import "time"
 // ChatLog 对话日志
 type ChatLog struct {
 	LogID               int64                  `json:"log_id" gorm:"primaryKey;autoIncrement;column:log_id"`
+	RequestID           string                 `json:"request_id" gorm:"column:request_id;index"`
+	TraceID             string                 `json:"trace_id" gorm:"column:trace_id"`
 	SessionID           string                 `json:"session_id" gorm:"column:session_id;index"`
 	UserID              string                 `json:"user_id" gorm:"column:user_id;index"`
 	Query               string                 `json:"query" gorm:"column:query;type:text"`
@@ -23,11 +25,20 @@ func (ChatLog) TableName() string {
 	return "chat_logs"
 }
 
+// Dify调用状态
+const (
+	DifyCallStatusSuccess = "success"
+	DifyCallStatusError   = "error"
+	DifyCallStatusTimeout = "timeout"
+)
+
 // DifyCallLog Dify调用日志
 type DifyCallLog struct {
 	LogID         int64                  `json:"log_id" gorm:"primaryKey;autoIncrement;column:log_id"`
+	RequestID     string                 `json:"request_id" gorm:"column:request_id;index"`
+	TraceID       string                 `json:"trace_id" gorm:"column:trace_id"`
 	WorkflowName  string                 `json:"workflow_name" gorm:"column:workflow_name;index"`
-	AppID         string                 `json:"app_id" gorm:"column:app_id;index"`
+	AppID         string                 `json:"app_id" gorm:"column:app_id;index"` // 应用 API Key 脱敏后的值
 	WorkflowRunID string                 `json:"workflow_run_id" gorm:"column:workflow_run_id"`
 	Inputs        map[string]interface{} `json:"inputs" gorm:"serializer:json;column:inputs"`
 	Outputs       map[string]interface{} `json:"outputs" gorm:"serializer:json;column:outputs"`
@@ -45,16 +56,76 @@ func (DifyCallLog) TableName() string {
 
 // ProductRecommendation 商品推荐记录
 type ProductRecommendation struct {
-	RecID      int64     `json:"rec_id" gorm:"primaryKey;autoIncrement;column:rec_id"`
-	SessionID  string    `json:"session_id" gorm:"column:session_id;index"`
-	UserID     string    `json:"user_id" gorm:"column:user_id;index"`
-	ProductID  string    `json:"product_id" gorm:"column:product_id;index"`
-	Reason     string    `json:"reason" gorm:"column:reason;type:text"`
-	UserAction string    `json:"user_action" gorm:"column:user_action;index"` // view/click/add_cart/purchase
-	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+	RecID         int64     `json:"rec_id" gorm:"primaryKey;autoIncrement;column:rec_id"`
+	SessionID     string    `json:"session_id" gorm:"column:session_id;index"`
+	UserID        string    `json:"user_id" gorm:"column:user_id;index"`
+	ProductID     string    `json:"product_id" gorm:"column:product_id;index"`
+	Reason        string    `json:"reason" gorm:"column:reason;type:text"`
+	Tool          string    `json:"tool" gorm:"column:tool;index"`                     // 产生推荐的Executor
+	PromptVersion string    `json:"prompt_version" gorm:"column:prompt_version;index"` // Executor工作流提示词版本
+	Position      int       `json:"position" gorm:"column:position"`                   // 在推荐列表中的位置（从0开始）
+	UserAction    string    `json:"user_action" gorm:"column:user_action;index"`       // view/click/add_cart/purchase，记录漏斗中最深的行为
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
 }
 
 // TableName 指定表名
 func (ProductRecommendation) TableName() string {
 	return "product_recommendations"
 }
+
+// 推荐商品的用户行为，按转化漏斗由浅到深排列
+const (
+	UserActionView     = "view"
+	UserActionClick    = "click"
+	UserActionAddCart  = "add_cart"
+	UserActionPurchase = "purchase"
+)
+
+// UserActionRank 行为在转化漏斗中的深度，未知行为返回 0
+func UserActionRank(action string) int {
+	switch action {
+	case UserActionView:
+		return 1
+	case UserActionClick:
+		return 2
+	case UserActionAddCart:
+		return 3
+	case UserActionPurchase:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// RecommendationEvent 推荐商品的用户行为事件（明细）
+type RecommendationEvent struct {
+	EventID   int64     `json:"event_id" gorm:"primaryKey;autoIncrement;column:event_id"`
+	RecID     int64     `json:"rec_id" gorm:"column:rec_id;index"`
+	UserID    string    `json:"user_id" gorm:"column:user_id;index"`
+	Action    string    `json:"action" gorm:"column:action;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;index"`
+}
+
+// TableName 指定表名
+func (RecommendationEvent) TableName() string {
+	return "recommendation_events"
+}
+
+// RecommendationEventRequest 推荐行为上报请求
+type RecommendationEventRequest struct {
+	Action string `json:"action" binding:"required,oneof=view click add_cart purchase"`
+}
+
+// ConversionStat 推荐转化统计
+type ConversionStat struct {
+	Dimension    string  `json:"dimension"` // 分组维度取值（tool/product_id/prompt_version）
+	Recommended  int64   `json:"recommended"`
+	Views        int64   `json:"views"`
+	Clicks       int64   `json:"clicks"`
+	AddCarts     int64   `json:"add_carts"`
+	Purchases    int64   `json:"purchases"`
+	ClickRate    float64 `json:"click_rate"`
+	AddCartRate  float64 `json:"add_cart_rate"`
+	PurchaseRate float64 `json:"purchase_rate"`
+}
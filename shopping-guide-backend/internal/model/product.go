@@ -34,25 +34,110 @@ type CategoryInfo struct {
 	SubCategories map[string][]string `json:"sub_categories"`
 }
 
+// 商品检索模式
+const (
+	SearchModeKeyword = "keyword" // BM25 关键词检索
+	SearchModeVector  = "vector"  // 向量语义检索
+	SearchModeHybrid  = "hybrid"  // BM25 与向量得分加权融合
+)
+
 // ProductSearchRequest 商品搜索请求
 type ProductSearchRequest struct {
 	Query    string                 `json:"query"`
 	Category string                 `json:"category"`
-	TopK     int                    `json:"top_k"`
+	TopK     int                    `json:"top_k" binding:"omitempty,min=0,max=100"` // 为空时使用配置默认值，最多 100
+	Mode     string                 `json:"mode"`                                    // keyword/vector/hybrid，为空时使用配置默认值
 	Filters  map[string]interface{} `json:"filters"`
 }
 
 // ProductSearchResponse 商品搜索响应
 type ProductSearchResponse struct {
-	Products []Product `json:"products"`
-	Total    int       `json:"total"`
+	Products []Product          `json:"products"`
+	Scores   map[string]float64 `json:"scores,omitempty"` // product_id -> 检索得分
+	Mode     string             `json:"mode"`
+	Total    int                `json:"total"`
 }
 
 // RecommendedProduct 推荐商品
 type RecommendedProduct struct {
+	RecommendationID int64   `json:"recommendation_id,omitempty"` // 推荐记录ID，前端上报行为时回传
+	ProductID        string  `json:"product_id"`
+	Name             string  `json:"name"`
+	Price            float64 `json:"price"`
+	Image            string  `json:"image"`
+	Reason           string  `json:"reason"`
+}
+
+// ScoreBreakdown 排序得分明细
+type ScoreBreakdown struct {
+	ProductID string             `json:"product_id"`
+	Score     float64            `json:"score"`
+	Features  map[string]float64 `json:"features"` // 特征原始值（0~1）
+	Weighted  map[string]float64 `json:"weighted"` // 特征加权后的贡献
+}
+
+// RankingPreviewRequest 排序预览请求（供运营调试权重）
+type RankingPreviewRequest struct {
+	UserID  string             `json:"user_id" binding:"required"`
+	Query   string             `json:"query" binding:"required"`
+	TopK    int                `json:"top_k"`
+	Weights map[string]float64 `json:"weights"` // 按特征名覆盖配置中的权重
+}
+
+// RankingPreviewResponse 排序预览响应
+type RankingPreviewResponse struct {
+	Weights    map[string]float64 `json:"weights"` // 实际生效的权重
+	Products   []Product          `json:"products"`
+	Breakdowns []ScoreBreakdown   `json:"breakdowns"`
+}
+
+// ProductMatch 文本中提及的商品
+type ProductMatch struct {
+	Product  Product `json:"product"`
+	Mention  string  `json:"mention"`  // 文本中的命中片段
+	Position int     `json:"position"` // 命中片段在文本中的字节偏移
+	Score    float64 `json:"score"`    // 匹配置信度（0~1）
+}
+
+// ComparisonTable 商品对比表，Rows 中每行的 Values 与 Products 顺序一致
+type ComparisonTable struct {
+	Products []ComparisonProduct `json:"products"`
+	Rows     []ComparisonRow     `json:"rows"`
+}
+
+// ComparisonProduct 对比表中的商品
+type ComparisonProduct struct {
 	ProductID string  `json:"product_id"`
 	Name      string  `json:"name"`
 	Price     float64 `json:"price"`
 	Image     string  `json:"image"`
-	Reason    string  `json:"reason"`
+}
+
+// ComparisonRow 对比表中的一项属性
+type ComparisonRow struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values"`  // 商品缺少该属性时为空字符串
+	Differs   bool     `json:"differs"` // 各商品取值是否不同
+}
+
+// 商品校验处理方式
+const (
+	GroundingPolicyFlag  = "flag"
+	GroundingPolicyStrip = "strip"
+)
+
+// GroundingReport 回复中商品与价格的校验结果
+type GroundingReport struct {
+	Matched     []string     `json:"matched"`      // 命中商品库的商品ID
+	Unmatched   []string     `json:"unmatched"`    // 商品库中不存在的商品提及
+	PriceIssues []PriceIssue `json:"price_issues"` // 与商品库不一致的报价
+	Stripped    bool         `json:"stripped"`     // 是否删除了包含未知商品的内容
+}
+
+// PriceIssue 与商品库不一致的报价
+type PriceIssue struct {
+	ProductID string  `json:"product_id"`
+	Mentioned float64 `json:"mentioned"`
+	Actual    float64 `json:"actual"`
+	Corrected bool    `json:"corrected"`
 }
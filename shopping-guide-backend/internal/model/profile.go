@@ -1,16 +1,164 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// 画像字段（画像事实与变更记录中使用）
+const (
+	ProfileFieldPreferredStyle = "preferred_style"
+	ProfileFieldAge            = "age"
+	ProfileFieldGender         = "gender"
+	ProfileFieldInterest       = "interest"
+	ProfileFieldBudgetMin      = "budget_min"
+	ProfileFieldBudgetMax      = "budget_max"
+	ProfileFieldSizePrefix     = "size." // size.clothing / size.shoe
+	ProfileFieldEnrichment     = "enrichment_disabled"
+)
+
+// 画像变更来源
+const (
+	ProfileSourceManual = "manual" // 用户或运营通过接口修改
+	ProfileSourceRules  = "rules"  // 本地规则抽取
+	ProfileSourceDify   = "dify"   // Dify 工作流抽取
+	ProfileSourceDecay  = "decay"  // 长期未再提及，置信度衰减后移除
+)
+
 // UserProfile 用户画像
 type UserProfile struct {
-	ID             int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	UserID         string `gorm:"column:user_id;uniqueIndex" json:"user_id"`
-	PreferredStyle string `gorm:"column:preferred_style" json:"preferred_style"` // xiaohongshu/dongyuhui
-	Age            int    `gorm:"column:age" json:"age"`
-	Gender         string `gorm:"column:gender" json:"gender"`
-	Interests      string `gorm:"column:interests;type:json" json:"interests"` // JSON 字符串，由应用层处理
+	ID                 int64                    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID             string                   `gorm:"column:user_id;uniqueIndex" json:"user_id"`
+	PreferredStyle     string                   `gorm:"column:preferred_style" json:"preferred_style"` // xiaohongshu/dongyuhui
+	Age                int                      `gorm:"column:age" json:"age"`
+	Gender             string                   `gorm:"column:gender" json:"gender"`
+	Interests          []string                 `gorm:"column:interests;serializer:json" json:"interests"`
+	BudgetMin          float64                  `gorm:"column:budget_min" json:"budget_min"`
+	BudgetMax          float64                  `gorm:"column:budget_max" json:"budget_max"`
+	Sizes              map[string]string        `gorm:"column:sizes;serializer:json" json:"sizes"`             // 尺码偏好，如 {"clothing":"M","shoe":"42"}
+	Signals            map[string]ProfileSignal `gorm:"column:signals;serializer:json" json:"signals"`         // 自动补全字段的来源与置信度，键为字段名，兴趣为 interest:<兴趣>
+	EnrichmentDisabled bool                     `gorm:"column:enrichment_disabled" json:"enrichment_disabled"` // 关闭后不再从对话中补全画像
+	UpdatedAt          time.Time                `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// ProfileSignal 自动补全字段的来源与置信度
+// 没有 signal 的非空字段视为人工设置，不会被自动补全覆盖，也不会衰减
+type ProfileSignal struct {
+	Value      string    `json:"value"`
+	Source     string    `json:"source"`
+	Confidence float64   `json:"confidence"`
+	SessionID  string    `json:"session_id,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ProfileFact 从对话中抽取的画像事实
+type ProfileFact struct {
+	Field      string  `json:"field"` // interest/budget_min/budget_max/gender/size.<类别>
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ProfileHistory 用户画像变更记录
+type ProfileHistory struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID     string    `gorm:"column:user_id;index" json:"user_id"`
+	Field      string    `gorm:"column:field" json:"field"`
+	OldValue   string    `gorm:"column:old_value" json:"old_value"`
+	NewValue   string    `gorm:"column:new_value" json:"new_value"`
+	Source     string    `gorm:"column:source" json:"source"`
+	Confidence float64   `gorm:"column:confidence" json:"confidence"`
+	SessionID  string    `gorm:"column:session_id" json:"session_id"`
+	CreatedAt  time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ProfileHistory) TableName() string {
+	return "user_profile_histories"
 }
 
 // TableName 指定表名
 func (UserProfile) TableName() string {
 	return "user_profiles"
 }
+
+// UnmarshalJSON 兼容旧版本以 JSON 字符串存储的 interests（如 Redis 中尚未过期的会话）
+func (p *UserProfile) UnmarshalJSON(data []byte) error {
+	type alias UserProfile
+	aux := struct {
+		*alias
+		Interests json.RawMessage `json:"interests"`
+	}{alias: (*alias)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	p.Interests = nil
+	if len(aux.Interests) == 0 || string(aux.Interests) == "null" {
+		return nil
+	}
+	if aux.Interests[0] == '"' {
+		var legacy string
+		if err := json.Unmarshal(aux.Interests, &legacy); err != nil {
+			return err
+		}
+		if legacy == "" {
+			return nil
+		}
+		aux.Interests = json.RawMessage(legacy)
+	}
+	return json.Unmarshal(aux.Interests, &p.Interests)
+}
+
+// UserPortrait 传给 Dify 工作流的用户画像
+type UserPortrait struct {
+	Age       int               `json:"age"`
+	Gender    string            `json:"gender"`
+	Interests []string          `json:"interests"`
+	BudgetMin float64           `json:"budget_min,omitempty"`
+	BudgetMax float64           `json:"budget_max,omitempty"`
+	Sizes     map[string]string `json:"sizes,omitempty"`
+}
+
+// Portrait 生成用户画像 JSON 字符串
+func (p *UserProfile) Portrait() (string, error) {
+	portrait := UserPortrait{
+		Age:       p.Age,
+		Gender:    p.Gender,
+		Interests: p.Interests,
+		BudgetMin: p.BudgetMin,
+		BudgetMax: p.BudgetMax,
+		Sizes:     p.Sizes,
+	}
+	if portrait.Interests == nil {
+		portrait.Interests = []string{}
+	}
+	data, err := json.Marshal(portrait)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ProfileUpdateRequest 全量更新用户画像请求
+type ProfileUpdateRequest struct {
+	PreferredStyle     string            `json:"preferred_style"`
+	Age                int               `json:"age" binding:"min=0,max=150"`
+	Gender             string            `json:"gender" binding:"omitempty,oneof=male female other unknown"`
+	Interests          []string          `json:"interests" binding:"max=50,dive,min=1,max=32"`
+	BudgetMin          float64           `json:"budget_min" binding:"min=0"`
+	BudgetMax          float64           `json:"budget_max" binding:"min=0"`
+	Sizes              map[string]string `json:"sizes" binding:"max=10"`
+	EnrichmentDisabled bool              `json:"enrichment_disabled"`
+}
+
+// ProfilePatchRequest 部分更新用户画像请求，未传的字段保持不变
+type ProfilePatchRequest struct {
+	PreferredStyle     *string            `json:"preferred_style"`
+	Age                *int               `json:"age" binding:"omitempty,min=0,max=150"`
+	Gender             *string            `json:"gender" binding:"omitempty,oneof=male female other unknown"`
+	Interests          *[]string          `json:"interests" binding:"omitempty,max=50,dive,min=1,max=32"`
+	BudgetMin          *float64           `json:"budget_min" binding:"omitempty,min=0"`
+	BudgetMax          *float64           `json:"budget_max" binding:"omitempty,min=0"`
+	Sizes              *map[string]string `json:"sizes" binding:"omitempty,max=10"`
+	EnrichmentDisabled *bool              `json:"enrichment_disabled"`
+}
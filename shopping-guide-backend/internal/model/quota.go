@@ -0,0 +1,72 @@
+package model
+
+import "time"
+
+// 配额主体类型
+const (
+	QuotaScopeUser     = "user"
+	QuotaScopeGuest    = "guest" // 访客按客户端IP计量
+	QuotaScopeMerchant = "merchant"
+)
+
+// 配额周期
+const (
+	QuotaPeriodDaily   = "daily"
+	QuotaPeriodMonthly = "monthly"
+)
+
+// 配额状态，按各项用量中占比最高的一项确定
+const (
+	QuotaLevelNormal   = "normal"
+	QuotaLevelWarning  = "warning"  // 达到预警比例
+	QuotaLevelDegraded = "degraded" // 达到降级比例，切换到低成本模式
+	QuotaLevelExceeded = "exceeded" // 超出配额，拒绝调用大模型
+)
+
+// QuotaEventWarning 用量首次达到预警比例时发出的事件类型
+const QuotaEventWarning = "quota_warning"
+
+// TokenUsage 按天汇总的 token 用量
+type TokenUsage struct {
+	ID        int64     `json:"-" gorm:"primaryKey;autoIncrement;column:id"`
+	Scope     string    `json:"scope" gorm:"column:scope;uniqueIndex:uk_usage"`
+	SubjectID string    `json:"subject_id" gorm:"column:subject_id;uniqueIndex:uk_usage"`
+	Day       time.Time `json:"day" gorm:"column:day;type:date;uniqueIndex:uk_usage"`
+	Tokens    int64     `json:"tokens" gorm:"column:tokens"`
+	Requests  int64     `json:"requests" gorm:"column:requests"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// TableName 指定表名
+func (TokenUsage) TableName() string {
+	return "token_usages"
+}
+
+// QuotaUsage 单项配额的当前用量
+type QuotaUsage struct {
+	Scope     string  `json:"scope"`
+	SubjectID string  `json:"subject_id"`
+	Period    string  `json:"period"`
+	Used      int64   `json:"used"`
+	Limit     int64   `json:"limit"` // 0 表示不限
+	Ratio     float64 `json:"ratio"`
+}
+
+// QuotaStatus 配额状态
+type QuotaStatus struct {
+	Level  string       `json:"level"`
+	Usages []QuotaUsage `json:"usages"`
+}
+
+// QuotaEvent 配额事件
+type QuotaEvent struct {
+	Type string `json:"type"`
+	QuotaUsage
+	At time.Time `json:"at"`
+}
+
+// UsageResponse 用量查询响应
+type UsageResponse struct {
+	Status  *QuotaStatus `json:"status"`
+	History []TokenUsage `json:"history"` // 按天汇总，时间倒序
+}
@@ -17,6 +17,7 @@ const (
 	CodeRateLimited        = 429
 	CodeInternalError      = 500
 	CodeServiceUnavailable = 503
+	CodeGatewayTimeout     = 504
 )
 
 // NewSuccessResponse 创建成功响应
@@ -9,6 +9,8 @@ type Session struct {
 	Messages            []Message              `json:"messages"`
 	ProductStorage      map[string]interface{} `json:"product_storage"`
 	BusinessInstruction string                 `json:"business_instruction"`
+	Style               string                 `json:"style,omitempty"`       // 会话级风格
+	MerchantID          string                 `json:"merchant_id,omitempty"` // 商家ID
 	UserProfile         UserProfile            `json:"user_profile"`
 	CreatedAt           time.Time              `json:"created_at"`
 	UpdatedAt           time.Time              `json:"updated_at"`
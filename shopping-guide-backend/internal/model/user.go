@@ -4,16 +4,46 @@ import "time"
 
 // User 用户模型
 type User struct {
-	UserID    string      `json:"user_id" gorm:"primaryKey;column:user_id"`
-	Username  string      `json:"username" gorm:"column:username"`
-	Email     string      `json:"email" gorm:"column:email"`
-	Phone     string      `json:"phone" gorm:"column:phone"`
-	Profile   UserProfile `json:"profile" gorm:"serializer:json;column:profile"`
-	CreatedAt time.Time   `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt time.Time   `json:"updated_at" gorm:"column:updated_at"`
+	UserID       string       `json:"user_id" gorm:"primaryKey;column:user_id"`
+	Username     string       `json:"username" gorm:"column:username"`
+	Email        *string      `json:"email,omitempty" gorm:"column:email;uniqueIndex"` // 未填写时为 NULL，唯一索引允许多个 NULL
+	Phone        *string      `json:"phone,omitempty" gorm:"column:phone;uniqueIndex"`
+	PasswordHash string       `json:"-" gorm:"column:password_hash"`
+	Roles        []string     `json:"roles,omitempty" gorm:"column:roles;serializer:json"` // 角色，如 admin，只能由运维直接写库授予
+	Profile      *UserProfile `json:"profile,omitempty" gorm:"foreignKey:UserID;references:UserID"`
+	CreatedAt    time.Time    `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" gorm:"column:updated_at"`
 }
 
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
 }
+
+// RegisterRequest 注册请求，邮箱与手机号至少填写一个
+type RegisterRequest struct {
+	Username string `json:"username" binding:"max=64"`
+	Email    string `json:"email" binding:"required_without=Phone,omitempty,email,max=255"`
+	Phone    string `json:"phone" binding:"required_without=Email,omitempty,numeric,min=6,max=20"`
+	Password string `json:"password" binding:"required,min=8,max=72"` // bcrypt 最多处理 72 字节
+}
+
+// LoginRequest 登录请求，account 为邮箱或手机号
+type LoginRequest struct {
+	Account  string `json:"account" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse 登录/刷新令牌响应
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"` // Bearer
+	ExpiresIn    int64  `json:"expires_in"` // access_token 有效期（秒）
+	User         *User  `json:"user,omitempty"`
+}
@@ -0,0 +1,42 @@
+// Package panics 捕获 panic 并记录堆栈、计入 panics_total，供 HTTP 中间件与后台 goroutine 按需使用
+package panics
+
+import (
+	"context"
+	"runtime/debug"
+
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/metrics"
+)
+
+// 捕获来源，对应 panics_total 的 source 标签
+const (
+	SourceHTTP         = "http"
+	SourceChatStream   = "chat_stream"
+	SourceLogService   = "log_service"
+	SourceEnrichment   = "profile_enrichment"
+	SourceQuota        = "quota_rollup"
+	SourceProductIndex = "product_index"
+)
+
+// Recover 捕获当前 goroutine 的 panic，须直接 defer 调用：
+//
+//	defer panics.Recover(ctx, panics.SourceLogService, nil)
+//
+// 以 context 中的日志器（带请求ID）记录堆栈并计数；onPanic 非空时在记录后调用，用于返回错误或通知调用方
+func Recover(ctx context.Context, source string, onPanic func(rec any)) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	Report(ctx, source, rec)
+	if onPanic != nil {
+		onPanic(rec)
+	}
+}
+
+// Report 记录已捕获的 panic，供自行调用 recover 的场景使用
+func Report(ctx context.Context, source string, rec any) {
+	metrics.Panics.WithLabelValues(source).Inc()
+	logger.FromContext(ctx).Error("panic recovered", "source", source, "panic", rec, "stack", string(debug.Stack()))
+}
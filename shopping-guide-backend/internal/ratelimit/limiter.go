@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// KeyPrefix 限流计数键前缀，完整键为 rate_limit:{user_id}:{endpoint}
+const KeyPrefix = "rate_limit:"
+
+// Limit 限流规则：每个 Period 允许 Rate 次请求，最多允许 Burst 次突发
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// Result 限流判定结果
+type Result struct {
+	Allowed    bool
+	Limit      int           // 突发容量，即额度完全恢复时可立即发起的请求数，与 Remaining 同一口径
+	Remaining  int           // 当前可立即发起的请求数
+	RetryAfter time.Duration // 被限流时距可重试的时间
+	ResetAfter time.Duration // 距额度完全恢复的时间
+}
+
+// Limiter 分布式限流器
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// gcraScript GCRA（令牌桶的等价形式）限流脚本
+// 每个键只保存一个字符串：理论到达时间 TAT（微秒），过期时间为额度完全恢复所需时间；
+// 使用 Redis 服务端时间，多副本之间不受各自时钟偏差影响
+// KEYS[1] 限流键；ARGV[1] 发放一个令牌的间隔（微秒）；ARGV[2] 突发容量
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - interval * burst
+local diff = now - allow_at
+
+if diff < 0 then
+  return {0, 0, -diff, tat - now}
+end
+
+local ttl = new_tat - now
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil(ttl / 1000))
+return {1, math.floor(diff / interval), 0, ttl}
+`)
+
+// redisLimiter 基于 Redis + Lua 的限流器实现，多副本共享计数
+type redisLimiter struct {
+	rdb *redis.Client
+}
+
+// NewRedisLimiter 创建 Redis 限流器
+func NewRedisLimiter(rdb *redis.Client) Limiter {
+	return &redisLimiter{
+		rdb: rdb,
+	}
+}
+
+// Allow 判定一次请求是否放行
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return nil, fmt.Errorf("invalid rate limit: %d per %s", limit.Rate, limit.Period)
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Rate
+	}
+	interval := limit.Period.Microseconds() / int64(limit.Rate)
+	if interval <= 0 {
+		interval = 1
+	}
+
+	values, err := gcraScript.Run(ctx, l.rdb, []string{KeyPrefix + key}, interval, burst).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
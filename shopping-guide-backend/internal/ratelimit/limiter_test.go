@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestLimiter(t *testing.T) (Limiter, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewRedisLimiter(rdb), mr
+}
+
+func TestRedisLimiterAllow(t *testing.T) {
+	// 每分钟 6 次即每 10 秒发放一个令牌
+	limit := Limit{Rate: 6, Period: time.Minute, Burst: 3}
+
+	type step struct {
+		advance       time.Duration // 本次请求前经过的时间
+		wantAllowed   bool
+		wantRemaining int
+	}
+	tests := []struct {
+		name  string
+		limit Limit
+		steps []step
+	}{
+		{
+			name:  "burst then deny",
+			limit: limit,
+			steps: []step{
+				{wantAllowed: true, wantRemaining: 2},
+				{wantAllowed: true, wantRemaining: 1},
+				{wantAllowed: true, wantRemaining: 0},
+				{wantAllowed: false, wantRemaining: 0},
+				{wantAllowed: false, wantRemaining: 0},
+			},
+		},
+		{
+			name:  "one token per interval",
+			limit: limit,
+			steps: []step{
+				{wantAllowed: true, wantRemaining: 2},
+				{wantAllowed: true, wantRemaining: 1},
+				{wantAllowed: true, wantRemaining: 0},
+				{advance: 5 * time.Second, wantAllowed: false, wantRemaining: 0},
+				{advance: 5 * time.Second, wantAllowed: true, wantRemaining: 0},
+				{wantAllowed: false, wantRemaining: 0},
+			},
+		},
+		{
+			name:  "reset after full recovery",
+			limit: limit,
+			steps: []step{
+				{wantAllowed: true, wantRemaining: 2},
+				{wantAllowed: true, wantRemaining: 1},
+				{wantAllowed: true, wantRemaining: 0},
+				{advance: 30 * time.Second, wantAllowed: true, wantRemaining: 2},
+			},
+		},
+		{
+			name:  "burst defaults to rate",
+			limit: Limit{Rate: 2, Period: time.Minute},
+			steps: []step{
+				{wantAllowed: true, wantRemaining: 1},
+				{wantAllowed: true, wantRemaining: 0},
+				{wantAllowed: false, wantRemaining: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, mr := newTestLimiter(t)
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, s := range tt.steps {
+				if s.advance > 0 {
+					now = now.Add(s.advance)
+					mr.SetTime(now)
+					mr.FastForward(s.advance)
+				}
+				result, err := limiter.Allow(context.Background(), "user_1:POST:/api/v1/chat", tt.limit)
+				if err != nil {
+					t.Fatalf("step %d: Allow() error = %v", i, err)
+				}
+				if result.Allowed != s.wantAllowed || result.Remaining != s.wantRemaining {
+					t.Errorf("step %d: allowed = %v, remaining = %d, want %v, %d",
+						i, result.Allowed, result.Remaining, s.wantAllowed, s.wantRemaining)
+				}
+				if !result.Allowed && result.RetryAfter <= 0 {
+					t.Errorf("step %d: denied without retry after", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRedisLimiterResult(t *testing.T) {
+	limiter, _ := newTestLimiter(t)
+	limit := Limit{Rate: 6, Period: time.Minute, Burst: 3}
+
+	var result *Result
+	for i := 0; i < 4; i++ {
+		var err error
+		if result, err = limiter.Allow(context.Background(), "ip:10.0.0.1:GET:/api/v1/sessions", limit); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+	}
+	if result.Allowed {
+		t.Fatal("fourth request allowed, want denied")
+	}
+	if result.Limit != 3 {
+		t.Errorf("limit = %d, want burst 3", result.Limit)
+	}
+	if result.RetryAfter != 10*time.Second {
+		t.Errorf("retry after = %s, want 10s", result.RetryAfter)
+	}
+	if result.ResetAfter != 30*time.Second {
+		t.Errorf("reset after = %s, want 30s", result.ResetAfter)
+	}
+}
+
+func TestRedisLimiterKeysAreIndependent(t *testing.T) {
+	limiter, _ := newTestLimiter(t)
+	limit := Limit{Rate: 1, Period: time.Minute}
+
+	for _, key := range []string{"user_1:POST:/api/v1/chat", "user_2:POST:/api/v1/chat", "user_1:GET:/api/v1/sessions"} {
+		result, err := limiter.Allow(context.Background(), key, limit)
+		if err != nil {
+			t.Fatalf("Allow(%s) error = %v", key, err)
+		}
+		if !result.Allowed {
+			t.Errorf("Allow(%s) denied, want allowed", key)
+		}
+	}
+}
+
+func TestRedisLimiterInvalidLimit(t *testing.T) {
+	limiter, _ := newTestLimiter(t)
+	for _, limit := range []Limit{{Rate: 0, Period: time.Minute}, {Rate: 1}} {
+		if _, err := limiter.Allow(context.Background(), "k", limit); err == nil {
+			t.Errorf("Allow() with %+v error = nil, want error", limit)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"shopping-guide-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type logRepository struct {
+	db *gorm.DB
+}
+
+// NewLogRepository 创建日志存储
+func NewLogRepository(db *gorm.DB) LogRepository {
+	return &logRepository{
+		db: db,
+	}
+}
+
+// SaveChatLog 保存对话日志
+func (r *logRepository) SaveChatLog(ctx context.Context, log *model.ChatLog) error {
+	if err := r.db.WithContext(ctx).Create(log).Error; err != nil {
+		return fmt.Errorf("failed to save chat log: %w", err)
+	}
+	return nil
+}
+
+// SaveDifyCallLog 保存 Dify 调用日志
+func (r *logRepository) SaveDifyCallLog(ctx context.Context, log *model.DifyCallLog) error {
+	if err := r.db.WithContext(ctx).Create(log).Error; err != nil {
+		return fmt.Errorf("failed to save dify call log: %w", err)
+	}
+	return nil
+}
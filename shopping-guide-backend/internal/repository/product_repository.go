@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"shopping-guide-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// productStatusOnSale 上架状态
+const productStatusOnSale = 1
+
+type productRepository struct {
+	db *gorm.DB
+}
+
+// NewProductRepository 创建商品存储
+func NewProductRepository(db *gorm.DB) ProductRepository {
+	return &productRepository{
+		db: db,
+	}
+}
+
+// GetByID 根据商品ID获取商品
+func (r *productRepository) GetByID(ctx context.Context, productID string) (*model.Product, error) {
+	var product model.Product
+	if err := r.db.WithContext(ctx).Where("product_id = ?", productID).First(&product).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetByIDs 批量获取商品
+func (r *productRepository) GetByIDs(ctx context.Context, productIDs []string) ([]model.Product, error) {
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+	var products []model.Product
+	if err := r.db.WithContext(ctx).Where("product_id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	return products, nil
+}
+
+// Search 按名称/描述模糊匹配上架商品
+func (r *productRepository) Search(ctx context.Context, req *model.ProductSearchRequest) ([]model.Product, error) {
+	query := r.db.WithContext(ctx).Where("status = ?", productStatusOnSale)
+	if req.Category != "" {
+		query = query.Where("category = ?", req.Category)
+	}
+	if req.Query != "" {
+		like := "%" + req.Query + "%"
+		query = query.Where("name LIKE ? OR description LIKE ?", like, like)
+	}
+	if req.TopK > 0 {
+		query = query.Limit(req.TopK)
+	}
+
+	var products []model.Product
+	if err := query.Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	return products, nil
+}
+
+// GetCategories 获取类目树（类目 -> 子类目 -> 商品名称）
+func (r *productRepository) GetCategories(ctx context.Context) (*model.ProductStorage, error) {
+	products, err := r.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	storage := &model.ProductStorage{
+		Categories: make(map[string]model.CategoryInfo),
+	}
+	for _, p := range products {
+		info, ok := storage.Categories[p.Category]
+		if !ok {
+			info = model.CategoryInfo{
+				CategoryName:  p.Category,
+				SubCategories: make(map[string][]string),
+			}
+			storage.Categories[p.Category] = info
+		}
+		info.SubCategories[p.SubCategory] = append(info.SubCategories[p.SubCategory], p.Name)
+	}
+	return storage, nil
+}
+
+// ListActive 获取全部上架商品
+func (r *productRepository) ListActive(ctx context.Context) ([]model.Product, error) {
+	var products []model.Product
+	if err := r.db.WithContext(ctx).Where("status = ?", productStatusOnSale).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+	return products, nil
+}
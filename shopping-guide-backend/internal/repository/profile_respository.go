@@ -2,15 +2,33 @@ package repository
 
 import (
 	"context"
+	"errors"
+
 	"shopping-guide-backend/internal/config"
 	"shopping-guide-backend/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProfileRepository interface {
 	GetProfile(ctx context.Context, userID string) (*model.UserProfile, error)
 	UpdateProfile(ctx context.Context, userID string, profile *model.UserProfile) error
+	// SaveWithHistory 在同一事务中写入画像与变更记录
+	SaveWithHistory(ctx context.Context, profile *model.UserProfile, histories []*model.ProfileHistory) error
+	// MergeProfile 在事务中加锁读取最新画像（不存在时为只有 user_id 的空画像）并交给 merge 修改，
+	// 只写入 merge 返回的列与 updated_at；merge 返回空列表时不写入
+	MergeProfile(ctx context.Context, userID string, merge ProfileMergeFunc) error
+	ListHistory(ctx context.Context, userID string, limit int) ([]model.ProfileHistory, error)
+}
+
+// ProfileMergeFunc 修改加锁读取的画像，返回改动的列与变更记录
+type ProfileMergeFunc func(profile *model.UserProfile) (columns []string, histories []*model.ProfileHistory)
+
+// profileColumns 画像写入时更新的列
+var profileColumns = []string{
+	"preferred_style", "age", "gender", "interests", "budget_min", "budget_max",
+	"sizes", "signals", "enrichment_disabled", "updated_at",
 }
 
 type profileRepository struct {
@@ -33,6 +51,78 @@ func (p *profileRepository) GetProfile(ctx context.Context, userID string) (*mod
 	return &profile, nil
 }
 
+// UpdateProfile 按 user_id 写入画像，不存在时新建
 func (p *profileRepository) UpdateProfile(ctx context.Context, userID string, profile *model.UserProfile) error {
-	return p.db.WithContext(ctx).Where("user_id = ?", userID).Save(profile).Error
+	profile.UserID = userID
+	return upsertProfile(p.db.WithContext(ctx), profile)
+}
+
+func (p *profileRepository) SaveWithHistory(ctx context.Context, profile *model.UserProfile, histories []*model.ProfileHistory) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := upsertProfile(tx, profile); err != nil {
+			return err
+		}
+		if len(histories) == 0 {
+			return nil
+		}
+		return tx.Create(&histories).Error
+	})
+}
+
+func (p *profileRepository) MergeProfile(ctx context.Context, userID string, merge ProfileMergeFunc) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 加锁读取，避免覆盖抽取期间用户手动修改的字段（包括关闭自动补全）
+		var profile model.UserProfile
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&profile).Error
+		exists := err == nil
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			profile = model.UserProfile{UserID: userID}
+		}
+
+		columns, histories := merge(&profile)
+		if len(columns) == 0 {
+			return nil
+		}
+		columns = append(columns, "updated_at")
+
+		if exists {
+			err = tx.Model(&model.UserProfile{}).Where("user_id = ?", userID).Select(columns).Updates(&profile).Error
+		} else {
+			// 并发新建时只覆盖本次改动的列
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns(columns),
+			}).Create(&profile).Error
+		}
+		if err != nil {
+			return err
+		}
+		if len(histories) == 0 {
+			return nil
+		}
+		return tx.Create(&histories).Error
+	})
+}
+
+func (p *profileRepository) ListHistory(ctx context.Context, userID string, limit int) ([]model.ProfileHistory, error) {
+	var histories []model.ProfileHistory
+	err := p.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+func upsertProfile(db *gorm.DB, profile *model.UserProfile) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns(profileColumns),
+	}).Create(profile).Error
 }
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-guide-backend/internal/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// token 用量计数的保留时间：日计数保留到次日汇总完成，月计数保留到次月初
+const (
+	dailyUsageTTL   = 48 * time.Hour
+	monthlyUsageTTL = 32 * 24 * time.Hour
+)
+
+// QuotaRepository token 用量计数存储（Redis），多副本共享
+type QuotaRepository interface {
+	// Incr 累加用量，返回累加后的当日与当月 tokens
+	Incr(ctx context.Context, scope, subjectID string, tokens int64, at time.Time) (daily, monthly int64, err error)
+	// Get 当日与当月 tokens
+	Get(ctx context.Context, scope, subjectID string, at time.Time) (daily, monthly int64, err error)
+	// ListDaily 指定日期有用量的主体及其当日用量
+	ListDaily(ctx context.Context, day time.Time) ([]model.TokenUsage, error)
+	// Publish 发布配额事件
+	Publish(ctx context.Context, channel string, event *model.QuotaEvent) error
+}
+
+type quotaRepository struct {
+	rdb *redis.Client
+}
+
+// NewQuotaRepository 创建 token 用量计数存储
+func NewQuotaRepository(rdb *redis.Client) QuotaRepository {
+	return &quotaRepository{
+		rdb: rdb,
+	}
+}
+
+// Incr 累加当日与当月用量，并把主体记入当日待汇总集合
+func (r *quotaRepository) Incr(ctx context.Context, scope, subjectID string, tokens int64, at time.Time) (int64, int64, error) {
+	dailyKey := dailyUsageKey(scope, subjectID, at)
+	monthlyKey := monthlyUsageKey(scope, subjectID, at)
+	dirtyKey := dirtyUsageKey(at)
+
+	var daily, monthly *redis.IntCmd
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		daily = pipe.HIncrBy(ctx, dailyKey, "tokens", tokens)
+		pipe.HIncrBy(ctx, dailyKey, "requests", 1)
+		pipe.Expire(ctx, dailyKey, dailyUsageTTL)
+		monthly = pipe.HIncrBy(ctx, monthlyKey, "tokens", tokens)
+		pipe.Expire(ctx, monthlyKey, monthlyUsageTTL)
+		pipe.SAdd(ctx, dirtyKey, scope+":"+subjectID)
+		pipe.Expire(ctx, dirtyKey, dailyUsageTTL)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to incr token usage: %w", err)
+	}
+	return daily.Val(), monthly.Val(), nil
+}
+
+// Get 当日与当月用量
+func (r *quotaRepository) Get(ctx context.Context, scope, subjectID string, at time.Time) (int64, int64, error) {
+	var daily, monthly *redis.StringCmd
+	_, err := r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		daily = pipe.HGet(ctx, dailyUsageKey(scope, subjectID, at), "tokens")
+		monthly = pipe.HGet(ctx, monthlyUsageKey(scope, subjectID, at), "tokens")
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return 0, 0, fmt.Errorf("failed to get token usage: %w", err)
+	}
+	return usageValue(daily), usageValue(monthly), nil
+}
+
+// ListDaily 指定日期的用量
+func (r *quotaRepository) ListDaily(ctx context.Context, day time.Time) ([]model.TokenUsage, error) {
+	members, err := r.rdb.SMembers(ctx, dirtyUsageKey(day)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list token usage subjects: %w", err)
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redis.SliceCmd, len(members))
+	_, err = r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			scope, subjectID, _ := strings.Cut(member, ":")
+			cmds[i] = pipe.HMGet(ctx, dailyUsageKey(scope, subjectID, day), "tokens", "requests")
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get token usage: %w", err)
+	}
+
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	usages := make([]model.TokenUsage, 0, len(members))
+	for i, member := range members {
+		values := cmds[i].Val()
+		if len(values) != 2 || values[0] == nil {
+			continue
+		}
+		scope, subjectID, _ := strings.Cut(member, ":")
+		usages = append(usages, model.TokenUsage{
+			Scope:     scope,
+			SubjectID: subjectID,
+			Day:       date,
+			Tokens:    parseUsage(values[0]),
+			Requests:  parseUsage(values[1]),
+		})
+	}
+	return usages, nil
+}
+
+// Publish 发布配额事件
+func (r *quotaRepository) Publish(ctx context.Context, channel string, event *model.QuotaEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quota event: %w", err)
+	}
+	if err := r.rdb.Publish(ctx, channel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish quota event: %w", err)
+	}
+	return nil
+}
+
+func dailyUsageKey(scope, subjectID string, at time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:d:%s", scope, subjectID, at.Format("20060102"))
+}
+
+func monthlyUsageKey(scope, subjectID string, at time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:m:%s", scope, subjectID, at.Format("200601"))
+}
+
+// dirtyUsageKey 当日有用量变化、待汇总到 MySQL 的主体集合
+func dirtyUsageKey(at time.Time) string {
+	return "quota:dirty:" + at.Format("20060102")
+}
+
+func usageValue(cmd *redis.StringCmd) int64 {
+	v, err := cmd.Int64()
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func parseUsage(v interface{}) int64 {
+	s, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"shopping-guide-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 转化统计支持的分组维度
+var conversionGroupColumns = map[string]string{
+	"tool":           "r.tool",
+	"product":        "r.product_id",
+	"prompt_version": "r.prompt_version",
+}
+
+type recommendationRepository struct {
+	db *gorm.DB
+}
+
+// NewRecommendationRepository 创建推荐记录存储
+func NewRecommendationRepository(db *gorm.DB) RecommendationRepository {
+	return &recommendationRepository{
+		db: db,
+	}
+}
+
+// CreateBatch 批量写入推荐记录，写入后回填 RecID
+func (r *recommendationRepository) CreateBatch(ctx context.Context, recs []*model.ProductRecommendation) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(&recs).Error; err != nil {
+		return fmt.Errorf("failed to create recommendations: %w", err)
+	}
+	return nil
+}
+
+// GetByID 获取推荐记录
+func (r *recommendationRepository) GetByID(ctx context.Context, recID int64) (*model.ProductRecommendation, error) {
+	var rec model.ProductRecommendation
+	if err := r.db.WithContext(ctx).Where("rec_id = ?", recID).First(&rec).Error; err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// RecordEvent 写入行为明细，并把推荐记录的 user_action 推进到漏斗中更深的行为
+// 同一推荐记录的同一行为只写一条明细，重复上报直接返回；推荐记录加行锁，并发上报不会重复写入
+func (r *recommendationRepository) RecordEvent(ctx context.Context, event *model.RecommendationEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rec model.ProductRecommendation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("rec_id = ?", event.RecID).First(&rec).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.RecommendationEvent{}).
+			Where("rec_id = ? AND action = ?", event.RecID, event.Action).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count recommendation events: %w", err)
+		}
+		if count > 0 {
+			return nil
+		}
+
+		if err := tx.Create(event).Error; err != nil {
+			return fmt.Errorf("failed to create recommendation event: %w", err)
+		}
+
+		if model.UserActionRank(event.Action) > model.UserActionRank(rec.UserAction) {
+			if err := tx.Model(&rec).Update("user_action", event.Action).Error; err != nil {
+				return fmt.Errorf("failed to update user action: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// AggregateConversion 按维度统计推荐转化
+// 每个行为按推荐记录去重计数，同一推荐多次点击只算一次
+func (r *recommendationRepository) AggregateConversion(ctx context.Context, groupBy string, since time.Time) ([]model.ConversionStat, error) {
+	column, ok := conversionGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group by: %s", groupBy)
+	}
+
+	var stats []model.ConversionStat
+	err := r.db.WithContext(ctx).
+		Table("product_recommendations AS r").
+		Select(fmt.Sprintf(`%s AS dimension,
+			COUNT(DISTINCT r.rec_id) AS recommended,
+			COUNT(DISTINCT CASE WHEN e.action = ? THEN r.rec_id END) AS views,
+			COUNT(DISTINCT CASE WHEN e.action = ? THEN r.rec_id END) AS clicks,
+			COUNT(DISTINCT CASE WHEN e.action = ? THEN r.rec_id END) AS add_carts,
+			COUNT(DISTINCT CASE WHEN e.action = ? THEN r.rec_id END) AS purchases`, column),
+			model.UserActionView, model.UserActionClick, model.UserActionAddCart, model.UserActionPurchase).
+		Joins("LEFT JOIN recommendation_events AS e ON e.rec_id = r.rec_id").
+		Where("r.created_at >= ?", since).
+		Group(column).
+		Order("recommended DESC").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate conversion: %w", err)
+	}
+
+	for i := range stats {
+		if stats[i].Recommended == 0 {
+			continue
+		}
+		total := float64(stats[i].Recommended)
+		stats[i].ClickRate = float64(stats[i].Clicks) / total
+		stats[i].AddCartRate = float64(stats[i].AddCarts) / total
+		stats[i].PurchaseRate = float64(stats[i].Purchases) / total
+	}
+	return stats, nil
+}
+
+// ListUserActions 获取用户有过行为的推荐记录
+func (r *recommendationRepository) ListUserActions(ctx context.Context, userID string, since time.Time) ([]model.ProductRecommendation, error) {
+	var recs []model.ProductRecommendation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND user_action <> '' AND created_at >= ?", userID, since).
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user actions: %w", err)
+	}
+	return recs, nil
+}
+
+// CountProductActions 统计商品被点击/加购/购买的推荐次数，作为热度
+func (r *recommendationRepository) CountProductActions(ctx context.Context, productIDs []string, since time.Time) (map[string]int64, error) {
+	counts := make(map[string]int64, len(productIDs))
+	if len(productIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ProductID string
+		Total     int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&model.ProductRecommendation{}).
+		Select("product_id, COUNT(*) AS total").
+		Where("product_id IN ? AND user_action IN ? AND created_at >= ?", productIDs,
+			[]string{model.UserActionClick, model.UserActionAddCart, model.UserActionPurchase}, since).
+		Group("product_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count product actions: %w", err)
+	}
+
+	for _, row := range rows {
+		counts[row.ProductID] = row.Total
+	}
+	return counts, nil
+}
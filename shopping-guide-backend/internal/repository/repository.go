@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"shopping-guide-backend/internal/model"
 )
@@ -11,13 +12,17 @@ import (
 // ProductRepository 商品存储接口
 type ProductRepository interface {
 	GetByID(ctx context.Context, productID string) (*model.Product, error)
+	GetByIDs(ctx context.Context, productIDs []string) ([]model.Product, error)
 	Search(ctx context.Context, req *model.ProductSearchRequest) ([]model.Product, error)
 	GetCategories(ctx context.Context) (*model.ProductStorage, error)
+	ListActive(ctx context.Context) ([]model.Product, error)
 }
 
 // UserRepository 用户存储接口
 type UserRepository interface {
 	GetByID(ctx context.Context, userID string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByPhone(ctx context.Context, phone string) (*model.User, error)
 	Create(ctx context.Context, user *model.User) error
 	Update(ctx context.Context, user *model.User) error
 }
@@ -28,6 +33,22 @@ type LogRepository interface {
 	SaveDifyCallLog(ctx context.Context, log *model.DifyCallLog) error
 }
 
+// RecommendationRepository 推荐记录存储接口
+type RecommendationRepository interface {
+	CreateBatch(ctx context.Context, recs []*model.ProductRecommendation) error
+	GetByID(ctx context.Context, recID int64) (*model.ProductRecommendation, error)
+	RecordEvent(ctx context.Context, event *model.RecommendationEvent) error
+	AggregateConversion(ctx context.Context, groupBy string, since time.Time) ([]model.ConversionStat, error)
+	ListUserActions(ctx context.Context, userID string, since time.Time) ([]model.ProductRecommendation, error)
+	CountProductActions(ctx context.Context, productIDs []string, since time.Time) (map[string]int64, error)
+}
+
+// TokenUsageRepository token 用量汇总存储接口
+type TokenUsageRepository interface {
+	Upsert(ctx context.Context, usages []model.TokenUsage) error
+	List(ctx context.Context, scope, subjectID string, since time.Time) ([]model.TokenUsage, error)
+}
+
 // CacheRepository 缓存接口
 type CacheRepository interface {
 	Set(ctx context.Context, key string, value interface{}) error
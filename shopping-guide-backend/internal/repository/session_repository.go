@@ -3,13 +3,19 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+
+	"shopping-guide-backend/internal/apperr"
 	"shopping-guide-backend/internal/config"
 	"shopping-guide-backend/internal/model"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// ErrSessionNotFound 会话不存在或已过期
+var ErrSessionNotFound error = apperr.New(apperr.KindNotFound, "session not found")
+
 // SessionRepository 会话存储接口
 type SessionRepository interface {
 	Save(ctx context.Context, session *model.Session) error
@@ -55,8 +61,8 @@ func (s sessionRepository) Get(ctx context.Context, sessionID string) (*model.Se
 
 	data, err := s.rdb.Get(ctx, key).Result()
 	if err != nil {
-		if err == redis.Nil {
-			return nil, fmt.Errorf("session not found")
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrSessionNotFound
 		}
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
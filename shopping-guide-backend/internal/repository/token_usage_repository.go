@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"shopping-guide-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type tokenUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenUsageRepository 创建 token 用量汇总存储
+func NewTokenUsageRepository(db *gorm.DB) TokenUsageRepository {
+	return &tokenUsageRepository{
+		db: db,
+	}
+}
+
+// Upsert 写入按天汇总的用量，已存在时以 Redis 中的累计值覆盖，重复汇总结果不变
+func (r *tokenUsageRepository) Upsert(ctx context.Context, usages []model.TokenUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range usages {
+		usages[i].UpdatedAt = now
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "subject_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tokens", "requests", "updated_at"}),
+	}).Create(&usages).Error
+	if err != nil {
+		return fmt.Errorf("failed to upsert token usages: %w", err)
+	}
+	return nil
+}
+
+// List 主体自 since 起按天汇总的用量，时间倒序
+func (r *tokenUsageRepository) List(ctx context.Context, scope, subjectID string, since time.Time) ([]model.TokenUsage, error) {
+	var usages []model.TokenUsage
+	err := r.db.WithContext(ctx).
+		Where("scope = ? AND subject_id = ? AND day >= ?", scope, subjectID, since.Format("2006-01-02")).
+		Order("day DESC").
+		Find(&usages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list token usages: %w", err)
+	}
+	return usages, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"shopping-guide-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateUser 邮箱或手机号已被注册
+var ErrDuplicateUser = errors.New("email or phone already registered")
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository 创建用户存储
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{
+		db: db,
+	}
+}
+
+func (r *userRepository) GetByID(ctx context.Context, userID string) (*model.User, error) {
+	return r.first(ctx, "user_id = ?", userID)
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.first(ctx, "email = ?", email)
+}
+
+func (r *userRepository) GetByPhone(ctx context.Context, phone string) (*model.User, error) {
+	return r.first(ctx, "phone = ?", phone)
+}
+
+// Create 创建用户，同时创建关联的画像
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	err := r.db.WithContext(ctx).Create(user).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateUser
+	}
+	return err
+}
+
+// Update 更新用户基本信息，不更新关联的画像
+func (r *userRepository) Update(ctx context.Context, user *model.User) error {
+	err := r.db.WithContext(ctx).Omit("Profile").Save(user).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateUser
+	}
+	return err
+}
+
+func (r *userRepository) first(ctx context.Context, query string, args ...interface{}) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Preload("Profile").Where(query, args...).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
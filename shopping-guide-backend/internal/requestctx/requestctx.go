@@ -0,0 +1,139 @@
+package requestctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 请求关联相关的请求/响应头
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent" // W3C Trace Context
+)
+
+// maxRequestIDLength 客户端传入请求ID的最大长度，超长或含非法字符时重新生成
+const maxRequestIDLength = 128
+
+// Metadata 请求关联信息，贯穿 handler、service 与 Dify 调用
+type Metadata struct {
+	RequestID  string
+	TraceID    string // 32 位十六进制，沿用上游 traceparent，没有时新生成
+	SpanID     string // 本服务处理该请求的 span，向下游传递时作为 parent-id
+	ParentID   string // 上游 span，没有上游 traceparent 时为空
+	TraceFlags string
+	ClientIP   string // 客户端IP，按 server.trusted_proxies 解析
+}
+
+type metadataKey struct{}
+
+// New 由请求头构建关联信息
+// 请求ID优先沿用 X-Request-ID；traceparent 合法时沿用其 trace-id，否则开启新的 trace
+func New(requestID, traceparent string) *Metadata {
+	m := &Metadata{
+		SpanID:     randomHex(8),
+		TraceFlags: "01",
+	}
+	if traceID, parentID, flags, ok := ParseTraceparent(traceparent); ok {
+		m.TraceID, m.ParentID, m.TraceFlags = traceID, parentID, flags
+	} else {
+		m.TraceID = randomHex(16)
+	}
+
+	if validRequestID(requestID) {
+		m.RequestID = requestID
+	} else {
+		m.RequestID = uuid.New().String()
+	}
+	return m
+}
+
+// Traceparent 向下游传递的 traceparent，parent-id 为本服务的 span
+func (m *Metadata) Traceparent() string {
+	return "00-" + m.TraceID + "-" + m.SpanID + "-" + m.TraceFlags
+}
+
+// WithMetadata 把关联信息放入 context
+func WithMetadata(ctx context.Context, m *Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, m)
+}
+
+// FromContext 取出关联信息
+func FromContext(ctx context.Context) (*Metadata, bool) {
+	m, ok := ctx.Value(metadataKey{}).(*Metadata)
+	return m, ok
+}
+
+// RequestID 取出请求ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	if m, ok := FromContext(ctx); ok {
+		return m.RequestID
+	}
+	return ""
+}
+
+// ClientIP 取出客户端IP，没有时返回空字符串
+func ClientIP(ctx context.Context) string {
+	if m, ok := FromContext(ctx); ok {
+		return m.ClientIP
+	}
+	return ""
+}
+
+// ParseTraceparent 解析 W3C traceparent：{version}-{trace-id}-{parent-id}-{trace-flags}
+// 未知版本按规范只取前四段；trace-id、parent-id 全零或版本为 ff 时视为非法
+func ParseTraceparent(v string) (traceID, parentID, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 {
+		return "", "", "", false
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", "", false
+	}
+	if !isHex(traceID, 32) || !isHex(parentID, 16) || !isHex(flags, 2) {
+		return "", "", "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return "", "", "", false
+	}
+	return traceID, parentID, flags, true
+}
+
+// validRequestID 请求ID只允许字母数字与 -_.:，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isHex 长度为 n 的小写十六进制串
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= '0' && r <= '9') && !(r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
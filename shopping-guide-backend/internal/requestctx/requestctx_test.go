@@ -0,0 +1,49 @@
+package requestctx
+
+import "testing"
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name   string
+		value  string
+		ok     bool
+		flags  string
+		parent string
+	}{
+		{name: "valid", value: "00-" + traceID + "-" + parentID + "-01", ok: true, flags: "01", parent: parentID},
+		{name: "surrounding spaces", value: "  00-" + traceID + "-" + parentID + "-00 ", ok: true, flags: "00", parent: parentID},
+		{name: "future version with extra fields", value: "cc-" + traceID + "-" + parentID + "-01-what-the-future", ok: true, flags: "01", parent: parentID},
+		{name: "empty", value: ""},
+		{name: "too few fields", value: "00-" + traceID + "-" + parentID},
+		{name: "version 00 with extra fields", value: "00-" + traceID + "-" + parentID + "-01-extra"},
+		{name: "version ff", value: "ff-" + traceID + "-" + parentID + "-01"},
+		{name: "uppercase hex", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + parentID + "-01"},
+		{name: "short trace id", value: "00-4bf92f3577b34da6-" + parentID + "-01"},
+		{name: "short parent id", value: "00-" + traceID + "-00f067aa-01"},
+		{name: "non-hex flags", value: "00-" + traceID + "-" + parentID + "-zz"},
+		{name: "all-zero trace id", value: "00-00000000000000000000000000000000-" + parentID + "-01"},
+		{name: "all-zero parent id", value: "00-" + traceID + "-0000000000000000-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTrace, gotParent, gotFlags, ok := ParseTraceparent(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("ParseTraceparent(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if !ok {
+				if gotTrace != "" || gotParent != "" || gotFlags != "" {
+					t.Errorf("ParseTraceparent(%q) = %q, %q, %q, want empty values", tt.value, gotTrace, gotParent, gotFlags)
+				}
+				return
+			}
+			if gotTrace != traceID || gotParent != tt.parent || gotFlags != tt.flags {
+				t.Errorf("ParseTraceparent(%q) = %q, %q, %q, want %q, %q, %q",
+					tt.value, gotTrace, gotParent, gotFlags, traceID, tt.parent, tt.flags)
+			}
+		})
+	}
+}
@@ -1,63 +1,140 @@
 package router
 
 import (
+	"log/slog"
+
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/config"
 	"shopping-guide-backend/internal/handler"
+	"shopping-guide-backend/internal/middleware"
+	"shopping-guide-backend/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Handlers 路由依赖的处理器，未注入的处理器不注册对应路由
+type Handlers struct {
+	Chat           handler.ChatHandler
+	Product        handler.ProductHandler
+	Recommendation handler.RecommendationHandler
+	Ranking        handler.RankingHandler
+	Profile        handler.ProfileHandler
+	Session        handler.SessionHandler
+	Style          handler.StyleHandler
+	User           handler.UserHandler
+	Usage          handler.UsageHandler
+	Admin          handler.AdminHandler
+}
+
 // SetupRouter 设置路由
-func SetupRouter(chatHandler handler.ChatHandler) *gin.Engine {
-	r := gin.Default()
+// limiter 为 nil 时不限流
+func SetupRouter(store *config.Store, tokens *auth.TokenManager, limiter ratelimit.Limiter, h *Handlers) *gin.Engine {
+	cfg := store.Get()
+	r := gin.New()
+	// 只采信可信代理转发的 X-Forwarded-For，否则 ClientIP 取连接地址，避免伪造请求头绕过按IP限流
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Error("invalid trusted proxies, trusting none", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
 
 	// 中间件
-	// r.Use(middleware.CORS())
-	// r.Use(middleware.Logger())
-	// r.Use(middleware.Recovery())
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Tracing())
+	r.Use(middleware.Metrics())
+	r.Use(middleware.Logger())
+	r.Use(middleware.Recovery())
+	r.Use(middleware.CORS(&cfg.Middleware.CORS))
+	r.Use(middleware.Auth(&cfg.Middleware.Auth, tokens))
 
-	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// 健康检查与监控指标，/health 与 /readyz 同为就绪检查
+	if h.Admin != nil {
+		r.GET("/livez", h.Admin.Livez)
+		r.GET("/readyz", h.Admin.Health)
+		r.GET("/health", h.Admin.Health)
+		r.GET("/metrics", h.Admin.Metrics)
+	}
 
 	// API路由组
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.Identity(&cfg.Middleware.Auth))
+	v1.Use(middleware.RateLimit(store, limiter))
 	{
+		// 账号接口
+		if h.User != nil {
+			v1.POST("/auth/register", h.User.Register)
+			v1.POST("/auth/login", h.User.Login)
+			v1.POST("/auth/refresh", h.User.Refresh)
+		}
+
 		// 对话接口
-		if chatHandler != nil {
-			v1.POST("/chat", chatHandler.Chat)
-			v1.POST("/chat/stream", chatHandler.ChatStream)
+		if h.Chat != nil {
+			v1.POST("/chat", h.Chat.Chat)
+			v1.POST("/chat/stream", h.Chat.ChatStream)
+		}
+
+		// 推荐行为上报接口
+		if h.Recommendation != nil {
+			v1.POST("/recommendations/:id/events", h.Recommendation.TrackEvent)
+		}
+
+		// 用户画像接口
+		if h.Profile != nil {
+			v1.GET("/users/:id/profile", h.Profile.GetProfile)
+			v1.PUT("/users/:id/profile", h.Profile.UpdateProfile)
+			v1.PATCH("/users/:id/profile", h.Profile.PatchProfile)
+			v1.GET("/users/:id/profile/history", h.Profile.ListHistory)
+		}
+
+		// token 用量接口
+		if h.Usage != nil {
+			v1.GET("/users/:id/usage", h.Usage.UserUsage)
 		}
 
 		// 会话接口
-		v1.POST("/sessions", func(c *gin.Context) {
-			// TODO: sessionHandler.CreateSession
-		})
-		v1.GET("/sessions/:session_id", func(c *gin.Context) {
-			// TODO: sessionHandler.GetSession
-		})
-		v1.GET("/sessions", func(c *gin.Context) {
-			// TODO: sessionHandler.ListSessions
-		})
-		v1.DELETE("/sessions/:session_id", func(c *gin.Context) {
-			// TODO: sessionHandler.DeleteSession
-		})
+		if h.Session != nil {
+			v1.POST("/sessions", h.Session.CreateSession)
+			v1.GET("/sessions/:session_id", h.Session.GetSession)
+			v1.GET("/sessions", h.Session.ListSessions)
+			v1.DELETE("/sessions/:session_id", h.Session.DeleteSession)
+		}
 	}
 
 	// 内部接口（供Dify调用）
 	internal := r.Group("/internal")
+	internal.Use(middleware.InternalOnly(&cfg.Middleware.Auth))
 	{
-		internal.POST("/products/search", func(c *gin.Context) {
-			// TODO: productHandler.SearchProducts
-		})
+		if h.Product != nil {
+			internal.POST("/products/search", h.Product.SearchProducts)
+		}
 	}
 
 	// 管理接口
 	admin := r.Group("/admin")
+	admin.Use(middleware.AdminOnly(&cfg.Middleware.Auth))
 	{
 		admin.GET("/dify/workflows/status", func(c *gin.Context) {
 			// TODO: adminHandler.DifyWorkflowStatus
 		})
+		if h.Recommendation != nil {
+			admin.GET("/recommendations/metrics", h.Recommendation.ConversionMetrics)
+		}
+		if h.Product != nil {
+			admin.POST("/products/reindex", h.Product.RebuildIndex)
+		}
+		if h.Ranking != nil {
+			admin.POST("/ranking/preview", h.Ranking.Preview)
+		}
+		if h.Usage != nil {
+			admin.GET("/usage/users/:id", h.Usage.AdminUserUsage)
+			admin.GET("/usage/merchants/:id", h.Usage.MerchantUsage)
+		}
+		if h.Admin != nil {
+			admin.GET("/config", h.Admin.Config)
+		}
+		if h.Style != nil {
+			admin.GET("/styles", h.Style.List)
+			admin.POST("/styles/preview", h.Style.Preview)
+		}
 	}
 
 	return r
@@ -0,0 +1,80 @@
+package search
+
+import "math"
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// BM25Index 内存 BM25 关键词索引
+type BM25Index struct {
+	docs      []bm25Doc
+	docFreq   map[string]int
+	avgDocLen float64
+}
+
+type bm25Doc struct {
+	id     string
+	length int
+	terms  map[string]int
+}
+
+// NewBM25Index 构建 BM25 索引
+func NewBM25Index(docs []Document) *BM25Index {
+	idx := &BM25Index{
+		docs:    make([]bm25Doc, 0, len(docs)),
+		docFreq: make(map[string]int),
+	}
+
+	var totalLen int
+	for _, d := range docs {
+		tokens := Tokenize(d.Text)
+		terms := make(map[string]int, len(tokens))
+		for _, t := range tokens {
+			terms[t]++
+		}
+		for t := range terms {
+			idx.docFreq[t]++
+		}
+		idx.docs = append(idx.docs, bm25Doc{id: d.ID, length: len(tokens), terms: terms})
+		totalLen += len(tokens)
+	}
+	if len(docs) > 0 {
+		idx.avgDocLen = float64(totalLen) / float64(len(docs))
+	}
+	return idx
+}
+
+// Search 返回 BM25 得分最高的 k 个结果，得分为 0 的文档不返回
+func (idx *BM25Index) Search(query string, k int) []Hit {
+	queryTerms := make(map[string]struct{})
+	for _, t := range Tokenize(query) {
+		queryTerms[t] = struct{}{}
+	}
+	if len(queryTerms) == 0 || len(idx.docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.docs))
+	var hits []Hit
+	for _, d := range idx.docs {
+		var score float64
+		for t := range queryTerms {
+			tf := float64(d.terms[t])
+			if tf == 0 {
+				continue
+			}
+			df := float64(idx.docFreq[t])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := tf + bm25K1*(1-bm25B+bm25B*float64(d.length)/idx.avgDocLen)
+			score += idf * tf * (bm25K1 + 1) / norm
+		}
+		if score > 0 {
+			hits = append(hits, Hit{ID: d.id, Score: score})
+		}
+	}
+
+	return topK(hits, k)
+}
@@ -0,0 +1,58 @@
+package search
+
+import "testing"
+
+func testDocs() []Document {
+	return []Document{
+		{ID: "bottle", Text: "运动水壶 500ml 骑行 户外"},
+		{ID: "light", Text: "自行车尾灯 USB充电 骑行 夜骑"},
+		{ID: "helmet", Text: "骑行头盔 透气 轻量"},
+		{ID: "mug", Text: "陶瓷马克杯 办公室"},
+	}
+}
+
+func TestBM25IndexSearch(t *testing.T) {
+	idx := NewBM25Index(testDocs())
+
+	tests := []struct {
+		name    string
+		query   string
+		k       int
+		wantIDs []string
+	}{
+		{name: "single match", query: "水壶", k: 10, wantIDs: []string{"bottle"}},
+		{name: "english term", query: "USB", k: 10, wantIDs: []string{"light"}},
+		{name: "no match", query: "键盘", k: 10, wantIDs: nil},
+		{name: "empty query", query: "", k: 10, wantIDs: nil},
+		{name: "rarer term ranks first", query: "夜骑 骑行", k: 10, wantIDs: []string{"light", "helmet", "bottle"}},
+		{name: "k truncates", query: "骑行", k: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := idx.Search(tt.query, tt.k)
+			if tt.k == 1 {
+				if len(hits) != 1 {
+					t.Fatalf("len(hits) = %d, want 1", len(hits))
+				}
+				return
+			}
+			if len(hits) != len(tt.wantIDs) {
+				t.Fatalf("hits = %v, want ids %v", hits, tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if hits[i].ID != id {
+					t.Errorf("hits[%d] = %s, want %s (hits %v)", i, hits[i].ID, id, hits)
+				}
+				if hits[i].Score <= 0 {
+					t.Errorf("hits[%d] score = %f, want > 0", i, hits[i].Score)
+				}
+			}
+		})
+	}
+}
+
+func TestBM25IndexEmpty(t *testing.T) {
+	if hits := NewBM25Index(nil).Search("水壶", 10); hits != nil {
+		t.Errorf("Search on empty index = %v, want nil", hits)
+	}
+}
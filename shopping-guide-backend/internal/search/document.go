@@ -0,0 +1,49 @@
+package search
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"shopping-guide-backend/internal/model"
+)
+
+// Document 参与检索的文档
+type Document struct {
+	ID   string
+	Text string
+}
+
+// Hit 检索命中结果
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// ProductDocument 将商品转换为检索文档
+// 名称、类目、描述与属性拼接为一段文本，属性按键排序保证内容稳定
+func ProductDocument(p *model.Product) Document {
+	parts := []string{p.Name, p.Category, p.SubCategory, p.Description}
+
+	keys := make([]string, 0, len(p.Attributes))
+	for k := range p.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%v", k, p.Attributes[k]))
+	}
+
+	return Document{
+		ID:   p.ProductID,
+		Text: strings.Join(parts, " "),
+	}
+}
+
+// ContentHash 计算文档内容摘要，用于判断向量是否需要重建
+func ContentHash(text string) string {
+	sum := sha1.Sum([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,201 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"shopping-guide-backend/internal/config"
+)
+
+// Embedding 提供方
+const (
+	ProviderOpenAI = "openai"
+	ProviderHash   = "hash"
+)
+
+// Embedder 文本向量化接口
+type Embedder interface {
+	// Embed 批量向量化，返回顺序与输入一致
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model 向量模型标识，用于校验持久化索引是否可复用
+	Model() string
+}
+
+// NewEmbedder 根据配置创建向量化实现
+func NewEmbedder(cfg *config.EmbeddingConfig) (Embedder, error) {
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		return NewOpenAIEmbedder(cfg), nil
+	case ProviderHash, "":
+		return NewHashEmbedder(cfg.Dimension), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.Provider)
+	}
+}
+
+// hashEmbedder 本地哈希向量化
+// 将检索词哈希到固定维度并做 L2 归一化，结果确定且无需外部服务，适用于测试和离线环境
+type hashEmbedder struct {
+	dim int
+}
+
+// NewHashEmbedder 创建本地哈希向量化
+func NewHashEmbedder(dim int) Embedder {
+	if dim <= 0 {
+		dim = 256
+	}
+	return &hashEmbedder{dim: dim}
+}
+
+func (e *hashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, e.dim)
+		for _, token := range Tokenize(text) {
+			h := fnv.New64a()
+			h.Write([]byte(token))
+			sum := h.Sum64()
+			// 用高位决定符号，降低哈希冲突带来的偏差
+			sign := float32(1)
+			if sum>>63 == 1 {
+				sign = -1
+			}
+			vec[sum%uint64(e.dim)] += sign
+		}
+		vectors[i] = normalize(vec)
+	}
+	return vectors, nil
+}
+
+func (e *hashEmbedder) Model() string {
+	return fmt.Sprintf("hash-%d", e.dim)
+}
+
+// openAIEmbedder OpenAI 兼容的向量化接口（POST {base_url}/embeddings）
+type openAIEmbedder struct {
+	cfg        *config.EmbeddingConfig
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbedder 创建 OpenAI 兼容的向量化实现
+func NewOpenAIEmbedder(cfg *config.EmbeddingConfig) Embedder {
+	return &openAIEmbedder{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+	}
+}
+
+type embeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	batchSize := e.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *openAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(embeddingRequest{
+		Model:      e.cfg.Model,
+		Input:      texts,
+		Dimensions: e.cfg.Dimension,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	url := strings.TrimRight(e.cfg.BaseURL, "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.APIKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding api error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result embeddingResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal embedding response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding count mismatch: want=%d, got=%d", len(texts), len(result.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index out of range: %d", item.Index)
+		}
+		vectors[item.Index] = normalize(item.Embedding)
+	}
+	return vectors, nil
+}
+
+func (e *openAIEmbedder) Model() string {
+	return fmt.Sprintf("%s-%d", e.cfg.Model, e.cfg.Dimension)
+}
+
+// normalize L2 归一化，归一化后余弦相似度即点积
+func normalize(vec []float32) []float32 {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vec
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
@@ -0,0 +1,129 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shopping-guide-backend/internal/config"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestHashEmbedder(t *testing.T) {
+	e := NewHashEmbedder(64)
+	if e.Model() != "hash-64" {
+		t.Errorf("Model() = %s, want hash-64", e.Model())
+	}
+
+	texts := []string{"骑行水壶", "骑行水壶", "陶瓷马克杯", ""}
+	vectors, err := e.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("len(vectors) = %d, want %d", len(vectors), len(texts))
+	}
+	for i, v := range vectors[:3] {
+		if len(v) != 64 {
+			t.Errorf("len(vectors[%d]) = %d, want 64", i, len(v))
+		}
+		if norm := cosine(v, v); math.Abs(norm-1) > 1e-5 {
+			t.Errorf("vectors[%d] norm = %f, want 1", i, norm)
+		}
+	}
+	if sim := cosine(vectors[0], vectors[1]); math.Abs(sim-1) > 1e-5 {
+		t.Errorf("same text similarity = %f, want 1", sim)
+	}
+	if cosine(vectors[0], vectors[2]) >= cosine(vectors[0], vectors[1]) {
+		t.Error("different text should be less similar than identical text")
+	}
+	if cosine(vectors[3], vectors[3]) != 0 {
+		t.Error("empty text should embed to zero vector")
+	}
+
+	if NewHashEmbedder(0).Model() != "hash-256" {
+		t.Error("default dimension should be 256")
+	}
+}
+
+func TestNewEmbedder(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{provider: "", want: "hash-8"},
+		{provider: ProviderHash, want: "hash-8"},
+		{provider: ProviderOpenAI, want: "m-8"},
+		{provider: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		e, err := NewEmbedder(&config.EmbeddingConfig{Provider: tt.provider, Model: "m", Dimension: 8})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewEmbedder(%q) error = nil, want error", tt.provider)
+			}
+			continue
+		}
+		if err != nil || e.Model() != tt.want {
+			t.Errorf("NewEmbedder(%q) = %v, %v, want model %s", tt.provider, e, err, tt.want)
+		}
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var batches [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req embeddingRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		batches = append(batches, req.Input)
+		if req.Input[0] == "fail" {
+			http.Error(w, "upstream error", http.StatusInternalServerError)
+			return
+		}
+
+		// 倒序返回，验证按 index 还原顺序
+		var resp embeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: i, Embedding: []float32{1, float32(len(req.Input[i]))}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	e := NewOpenAIEmbedder(&config.EmbeddingConfig{BaseURL: srv.URL + "/", APIKey: "key", Model: "m", Dimension: 2, BatchSize: 2})
+	vectors, err := e.Embed(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("batches = %v, want sizes [2 1]", batches)
+	}
+	// 向量为 [1, len(text)] 归一化后的结果，v[1]/v[0] 还原出文本长度
+	for i, v := range vectors {
+		if len(v) != 2 || math.Abs(float64(v[1]/v[0])-float64(i+1)) > 1e-5 || math.Abs(cosine(v, v)-1) > 1e-5 {
+			t.Errorf("vectors[%d] = %v, want normalized [1 %d]", i, v, i+1)
+		}
+	}
+
+	if _, err := e.Embed(context.Background(), []string{"fail"}); err == nil {
+		t.Error("Embed() with upstream error = nil, want error")
+	}
+}
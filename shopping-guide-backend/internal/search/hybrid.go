@@ -0,0 +1,54 @@
+package search
+
+// Blend 融合 BM25 与向量检索结果
+// 两路得分量纲不同，先各自做 min-max 归一化，再按 alpha 加权：
+// score = alpha*vector + (1-alpha)*bm25，只在一路出现的文档另一路记 0 分
+func Blend(keywordHits, vectorHits []Hit, alpha float64, k int) []Hit {
+	if alpha < 0 {
+		alpha = 0
+	}
+	if alpha > 1 {
+		alpha = 1
+	}
+
+	scores := make(map[string]float64)
+	for id, s := range NormalizeScores(keywordHits) {
+		scores[id] += (1 - alpha) * s
+	}
+	for id, s := range NormalizeScores(vectorHits) {
+		scores[id] += alpha * s
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, s := range scores {
+		hits = append(hits, Hit{ID: id, Score: s})
+	}
+	return topK(hits, k)
+}
+
+// NormalizeScores min-max 归一化到 [0,1]，所有得分相同时统一记 1 分
+func NormalizeScores(hits []Hit) map[string]float64 {
+	result := make(map[string]float64, len(hits))
+	if len(hits) == 0 {
+		return result
+	}
+
+	minScore, maxScore := hits[0].Score, hits[0].Score
+	for _, h := range hits {
+		if h.Score < minScore {
+			minScore = h.Score
+		}
+		if h.Score > maxScore {
+			maxScore = h.Score
+		}
+	}
+
+	for _, h := range hits {
+		if maxScore == minScore {
+			result[h.ID] = 1
+			continue
+		}
+		result[h.ID] = (h.Score - minScore) / (maxScore - minScore)
+	}
+	return result
+}
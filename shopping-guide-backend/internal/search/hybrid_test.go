@@ -0,0 +1,86 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeScores(t *testing.T) {
+	tests := []struct {
+		name string
+		hits []Hit
+		want map[string]float64
+	}{
+		{name: "empty", hits: nil, want: map[string]float64{}},
+		{name: "min max", hits: []Hit{{"a", 10}, {"b", 5}, {"c", 0}}, want: map[string]float64{"a": 1, "b": 0.5, "c": 0}},
+		{name: "negative scores", hits: []Hit{{"a", 0.2}, {"b", -0.2}}, want: map[string]float64{"a": 1, "b": 0}},
+		{name: "equal scores", hits: []Hit{{"a", 3}, {"b", 3}}, want: map[string]float64{"a": 1, "b": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeScores(tt.hits)
+			if len(got) != len(tt.want) {
+				t.Fatalf("NormalizeScores() = %v, want %v", got, tt.want)
+			}
+			for id, want := range tt.want {
+				if math.Abs(got[id]-want) > 1e-9 {
+					t.Errorf("score[%s] = %f, want %f", id, got[id], want)
+				}
+			}
+		})
+	}
+}
+
+func TestBlend(t *testing.T) {
+	keyword := []Hit{{"a", 8}, {"b", 4}, {"c", 0}}
+	vector := []Hit{{"c", 0.9}, {"d", 0.5}, {"a", 0.1}}
+
+	tests := []struct {
+		name    string
+		alpha   float64
+		k       int
+		wantIDs []string
+		want    map[string]float64
+	}{
+		{
+			name:    "keyword only",
+			alpha:   0,
+			k:       10,
+			wantIDs: []string{"a", "b", "c", "d"},
+			want:    map[string]float64{"a": 1, "b": 0.5, "c": 0, "d": 0},
+		},
+		{
+			name:    "vector only",
+			alpha:   1,
+			k:       10,
+			wantIDs: []string{"c", "d", "a", "b"},
+			want:    map[string]float64{"c": 1, "d": 0.5, "a": 0, "b": 0},
+		},
+		{
+			name:    "half and half",
+			alpha:   0.5,
+			k:       10,
+			wantIDs: []string{"a", "c", "b", "d"},
+			want:    map[string]float64{"a": 0.5, "c": 0.5, "b": 0.25, "d": 0.25},
+		},
+		{name: "alpha clamped above 1", alpha: 3, k: 10, wantIDs: []string{"c", "d", "a", "b"}},
+		{name: "alpha clamped below 0", alpha: -1, k: 10, wantIDs: []string{"a", "b", "c", "d"}},
+		{name: "k truncates", alpha: 0.5, k: 2, wantIDs: []string{"a", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := Blend(keyword, vector, tt.alpha, tt.k)
+			if len(hits) != len(tt.wantIDs) {
+				t.Fatalf("Blend() = %v, want ids %v", hits, tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if hits[i].ID != id {
+					t.Errorf("hits[%d] = %s, want %s (hits %v)", i, hits[i].ID, id, hits)
+				}
+				if want, ok := tt.want[id]; ok && math.Abs(hits[i].Score-want) > 1e-9 {
+					t.Errorf("score[%s] = %f, want %f", id, hits[i].Score, want)
+				}
+			}
+		})
+	}
+}
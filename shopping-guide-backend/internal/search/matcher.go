@@ -0,0 +1,187 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"shopping-guide-backend/internal/model"
+)
+
+// 匹配置信度
+const (
+	matchScoreExact = 1.0 // 商品ID或完整名称
+	matchScoreCode  = 0.9 // 型号（如 X1、C1），且型号在商品库中唯一
+	matchScoreFuzzy = 0.8 // 名称二元组覆盖率达标时的最高分
+
+	fuzzyMinCoverage = 0.6
+	fuzzyMinBigrams  = 2
+)
+
+// Match 文本中提及的商品
+type Match struct {
+	ID       string
+	Mention  string // 命中片段
+	Position int    // 命中片段在文本中的字节偏移
+	Score    float64
+}
+
+// NameMatcher 商品提及识别
+// 依次尝试商品ID、完整名称、型号、名称模糊匹配，每个商品取置信度最高的一次命中
+type NameMatcher struct {
+	entries   []nameEntry
+	codeCount map[string]int
+}
+
+type nameEntry struct {
+	productID string
+	id        string // 以下字段均为小写，用于匹配
+	name      string
+	codes     []string
+	bigrams   []string
+}
+
+// NewNameMatcher 构建商品提及识别器
+func NewNameMatcher(products []model.Product) *NameMatcher {
+	m := &NameMatcher{
+		entries:   make([]nameEntry, 0, len(products)),
+		codeCount: make(map[string]int),
+	}
+	for _, p := range products {
+		e := nameEntry{
+			productID: p.ProductID,
+			id:        strings.ToLower(p.ProductID),
+			name:      strings.ToLower(p.Name),
+			codes:     modelCodes(p.Name),
+			bigrams:   hanBigrams(p.Name),
+		}
+		for _, code := range e.codes {
+			m.codeCount[code]++
+		}
+		m.entries = append(m.entries, e)
+	}
+	return m
+}
+
+// Match 识别文本中提及的商品，按出现位置排序
+func (m *NameMatcher) Match(text string) []Match {
+	lower := strings.ToLower(text)
+
+	var matches []Match
+	for i := range m.entries {
+		if match, ok := m.matchEntry(&m.entries[i], lower); ok {
+			// 大小写转换不改变 ASCII 与中文的字节长度，命中片段可直接从原文截取
+			if len(lower) == len(text) && match.Position+len(match.Mention) <= len(text) {
+				match.Mention = text[match.Position : match.Position+len(match.Mention)]
+			}
+			matches = append(matches, match)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Position != matches[j].Position {
+			return matches[i].Position < matches[j].Position
+		}
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+func (m *NameMatcher) matchEntry(e *nameEntry, text string) (Match, bool) {
+	if pos := strings.Index(text, e.id); pos >= 0 {
+		return Match{ID: e.productID, Mention: text[pos : pos+len(e.id)], Position: pos, Score: matchScoreExact}, true
+	}
+	if pos := strings.Index(text, e.name); pos >= 0 && e.name != "" {
+		return Match{ID: e.productID, Mention: text[pos : pos+len(e.name)], Position: pos, Score: matchScoreExact}, true
+	}
+	for _, code := range e.codes {
+		if m.codeCount[code] != 1 {
+			continue
+		}
+		if pos := indexToken(text, code); pos >= 0 {
+			return Match{ID: e.productID, Mention: text[pos : pos+len(code)], Position: pos, Score: matchScoreCode}, true
+		}
+	}
+
+	if len(e.bigrams) < fuzzyMinBigrams {
+		return Match{}, false
+	}
+	matched, first := 0, -1
+	for _, bg := range e.bigrams {
+		if pos := strings.Index(text, bg); pos >= 0 {
+			matched++
+			if first < 0 || pos < first {
+				first = pos
+			}
+		}
+	}
+	coverage := float64(matched) / float64(len(e.bigrams))
+	if matched < fuzzyMinBigrams || coverage < fuzzyMinCoverage {
+		return Match{}, false
+	}
+	return Match{ID: e.productID, Mention: text[first : first+len(e.bigrams[0])], Position: first, Score: matchScoreFuzzy * coverage}, true
+}
+
+// modelCodes 提取名称中的型号：同时包含字母和数字的连续英数字串
+func modelCodes(name string) []string {
+	var codes []string
+	for _, token := range Tokenize(name) {
+		var hasLetter, hasDigit bool
+		for _, r := range token {
+			if r > unicode.MaxASCII {
+				hasLetter, hasDigit = false, false
+				break
+			}
+			if unicode.IsLetter(r) {
+				hasLetter = true
+			}
+			if unicode.IsDigit(r) {
+				hasDigit = true
+			}
+		}
+		if hasLetter && hasDigit {
+			codes = append(codes, token)
+		}
+	}
+	return codes
+}
+
+// hanBigrams 名称中的中文二元组
+func hanBigrams(name string) []string {
+	var bigrams []string
+	for _, token := range Tokenize(name) {
+		if len([]rune(token)) == 2 && unicode.Is(unicode.Han, []rune(token)[0]) {
+			bigrams = append(bigrams, token)
+		}
+	}
+	return bigrams
+}
+
+// indexToken 查找独立出现的英数字串，前后不能紧邻其他英数字符（避免 X1 命中 X10）
+func indexToken(text, token string) int {
+	offset := 0
+	for {
+		pos := strings.Index(text[offset:], token)
+		if pos < 0 {
+			return -1
+		}
+		start := offset + pos
+		end := start + len(token)
+		if !isASCIIAlnumBefore(text, start) && !isASCIIAlnumAt(text, end) {
+			return start
+		}
+		offset = start + 1
+	}
+}
+
+func isASCIIAlnumBefore(text string, pos int) bool {
+	return pos > 0 && isASCIIAlnum(text[pos-1])
+}
+
+func isASCIIAlnumAt(text string, pos int) bool {
+	return pos < len(text) && isASCIIAlnum(text[pos])
+}
+
+func isASCIIAlnum(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
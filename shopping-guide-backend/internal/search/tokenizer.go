@@ -0,0 +1,43 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize 将文本切分为检索词
+// 英文/数字按连续字符成词并转小写，中文按单字和相邻二元组切分，
+// 二元组能覆盖"水壶""车灯"这类词语，而无需引入分词词典
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var prevHan rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			tokens = append(tokens, string(r))
+			if prevHan != 0 {
+				tokens = append(tokens, string([]rune{prevHan, r}))
+			}
+			prevHan = r
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			prevHan = 0
+			word = append(word, r)
+		default:
+			prevHan = 0
+			flushWord()
+		}
+	}
+	flushWord()
+
+	return tokens
+}
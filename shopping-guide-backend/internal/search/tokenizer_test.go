@@ -0,0 +1,30 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "english lowercased", text: "Water Bottle", want: []string{"water", "bottle"}},
+		{name: "letters and digits stay together", text: "X1 500ml", want: []string{"x1", "500ml"}},
+		{name: "han unigrams and bigrams", text: "水壶", want: []string{"水", "壶", "水壶"}},
+		{name: "han run", text: "骑行灯", want: []string{"骑", "行", "骑行", "灯", "行灯"}},
+		{name: "mixed breaks han bigram", text: "车灯X1尾灯", want: []string{"车", "灯", "车灯", "x1", "尾", "灯", "尾灯"}},
+		{name: "punctuation separates", text: "水,壶", want: []string{"水", "壶"}},
+		{name: "only punctuation", text: "，。!?", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// vectorEntry 索引条目
+type vectorEntry struct {
+	ID     string
+	Hash   string // 文档内容摘要，内容变化时需要重新向量化
+	Vector []float32
+}
+
+// vectorIndexFile 索引持久化格式
+type vectorIndexFile struct {
+	Model   string
+	Entries []vectorEntry
+}
+
+// VectorIndex 内存向量索引（暴力检索 + 余弦相似度）
+// 商品库规模在万级以内，线性扫描足够快且结果精确
+type VectorIndex struct {
+	mu      sync.RWMutex
+	model   string
+	entries map[string]vectorEntry
+}
+
+// NewVectorIndex 创建空索引，model 为生成向量的模型标识
+func NewVectorIndex(model string) *VectorIndex {
+	return &VectorIndex{
+		model:   model,
+		entries: make(map[string]vectorEntry),
+	}
+}
+
+// LoadVectorIndex 从磁盘加载索引
+// 文件不存在或模型不一致时返回空索引，由调用方重新向量化
+func LoadVectorIndex(path string, model string) (*VectorIndex, error) {
+	idx := NewVectorIndex(model)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, fmt.Errorf("failed to open vector index: %w", err)
+	}
+	defer f.Close()
+
+	var data vectorIndexFile
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode vector index: %w", err)
+	}
+	if data.Model != model {
+		return idx, nil
+	}
+
+	for _, e := range data.Entries {
+		idx.entries[e.ID] = e
+	}
+	return idx, nil
+}
+
+// Save 持久化索引，先写临时文件再重命名，避免写入中断导致文件损坏
+func (idx *VectorIndex) Save(path string) error {
+	idx.mu.RLock()
+	data := vectorIndexFile{
+		Model:   idx.model,
+		Entries: make([]vectorEntry, 0, len(idx.entries)),
+	}
+	for _, e := range idx.entries {
+		data.Entries = append(data.Entries, e)
+	}
+	idx.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index dir: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create vector index: %w", err)
+	}
+	if err := gob.NewEncoder(f).Encode(&data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode vector index: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close vector index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to rename vector index: %w", err)
+	}
+	return nil
+}
+
+// Hash 返回已索引文档的内容摘要
+func (idx *VectorIndex) Hash(id string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	e, ok := idx.entries[id]
+	return e.Hash, ok
+}
+
+// Upsert 写入或覆盖向量，向量需已归一化
+func (idx *VectorIndex) Upsert(id string, hash string, vector []float32) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entries[id] = vectorEntry{ID: id, Hash: hash, Vector: vector}
+}
+
+// Retain 仅保留指定ID，用于剔除已下架商品
+func (idx *VectorIndex) Retain(ids map[string]struct{}) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for id := range idx.entries {
+		if _, ok := ids[id]; !ok {
+			delete(idx.entries, id)
+		}
+	}
+}
+
+// Len 索引条目数
+func (idx *VectorIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
+
+// Search 返回与查询向量余弦相似度最高的 k 个结果
+func (idx *VectorIndex) Search(query []float32, k int) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	hits := make([]Hit, 0, len(idx.entries))
+	for _, e := range idx.entries {
+		if len(e.Vector) != len(query) {
+			continue
+		}
+		var dot float64
+		for i, v := range e.Vector {
+			dot += float64(v) * float64(query[i])
+		}
+		hits = append(hits, Hit{ID: e.ID, Score: dot})
+	}
+
+	return topK(hits, k)
+}
+
+// topK 按得分降序截取前 k 个，得分相同时按ID排序保证结果稳定
+func topK(hits []Hit, k int) []Hit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVectorIndexSearch(t *testing.T) {
+	idx := NewVectorIndex("test")
+	idx.Upsert("x", "h1", []float32{1, 0})
+	idx.Upsert("y", "h2", []float32{0.6, 0.8})
+	idx.Upsert("z", "h3", []float32{0, 1})
+	idx.Upsert("bad", "h4", []float32{1, 0, 0}) // 维度不一致的向量不参与检索
+
+	tests := []struct {
+		name    string
+		query   []float32
+		k       int
+		wantIDs []string
+	}{
+		{name: "nearest first", query: []float32{1, 0}, k: 10, wantIDs: []string{"x", "y", "z"}},
+		{name: "other axis", query: []float32{0, 1}, k: 10, wantIDs: []string{"z", "y", "x"}},
+		{name: "k truncates", query: []float32{0, 1}, k: 1, wantIDs: []string{"z"}},
+		{name: "dimension mismatch", query: []float32{1, 0, 0, 0}, k: 10, wantIDs: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := idx.Search(tt.query, tt.k)
+			if len(hits) != len(tt.wantIDs) {
+				t.Fatalf("Search() = %v, want ids %v", hits, tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if hits[i].ID != id {
+					t.Errorf("hits[%d] = %s, want %s", i, hits[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestVectorIndexUpsertRetain(t *testing.T) {
+	idx := NewVectorIndex("test")
+	idx.Upsert("a", "h1", []float32{1})
+	idx.Upsert("b", "h2", []float32{1})
+	idx.Upsert("a", "h3", []float32{1})
+
+	if hash, ok := idx.Hash("a"); !ok || hash != "h3" {
+		t.Errorf("Hash(a) = %q, %v, want h3, true", hash, ok)
+	}
+
+	idx.Retain(map[string]struct{}{"a": {}})
+	if idx.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", idx.Len())
+	}
+	if _, ok := idx.Hash("b"); ok {
+		t.Error("Hash(b) found after Retain, want removed")
+	}
+}
+
+func TestVectorIndexPersistence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "index.gob")
+
+	idx := NewVectorIndex("model-a")
+	idx.Upsert("a", "h1", []float32{1, 0})
+	idx.Upsert("b", "h2", []float32{0, 1})
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		model   string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "round trip", path: path, model: "model-a", wantLen: 2},
+		{name: "model changed", path: path, model: "model-b", wantLen: 0},
+		{name: "missing file", path: filepath.Join(dir, "missing.gob"), model: "model-a", wantLen: 0},
+		{name: "corrupt file", path: writeFile(t, dir, "corrupt.gob", "not gob"), model: "model-a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := LoadVectorIndex(tt.path, tt.model)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("LoadVectorIndex() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadVectorIndex() error = %v", err)
+			}
+			if loaded.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", loaded.Len(), tt.wantLen)
+			}
+		})
+	}
+
+	loaded, err := LoadVectorIndex(path, "model-a")
+	if err != nil {
+		t.Fatalf("LoadVectorIndex() error = %v", err)
+	}
+	if hits := loaded.Search([]float32{0, 1}, 1); len(hits) != 1 || hits[0].ID != "b" {
+		t.Errorf("Search() after load = %v, want b", hits)
+	}
+	if hash, _ := loaded.Hash("a"); hash != "h1" {
+		t.Errorf("Hash(a) after load = %q, want h1", hash)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
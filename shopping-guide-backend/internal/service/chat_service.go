@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/tracing"
 
 	"github.com/google/uuid"
 )
@@ -27,17 +28,23 @@ func NewChatService(orchestrator OrchestratorService) ChatService {
 }
 
 // Chat 对话（阻塞式）
-func (s *chatService) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
+func (s *chatService) Chat(ctx context.Context, req *model.ChatRequest) (resp *model.ChatResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ChatService.Chat")
+	defer func() { tracing.End(span, err) }()
 
-	sessionID := uuid.New().String()
-	req.SessionID = sessionID
+	if req.SessionID == "" {
+		req.SessionID = uuid.New().String()
+	}
 	return s.orchestrator.ProcessChat(ctx, req)
 }
 
 // ChatStream 对话（流式）
-func (s *chatService) ChatStream(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error) {
-	// TODO: 实现流式对话
-	ch := make(chan model.StreamChunk)
-	close(ch)
-	return ch, nil
+func (s *chatService) ChatStream(ctx context.Context, req *model.ChatRequest) (stream <-chan model.StreamChunk, err error) {
+	ctx, span := tracing.Start(ctx, "ChatService.ChatStream")
+	defer func() { tracing.End(span, err) }()
+
+	if req.SessionID == "" {
+		req.SessionID = uuid.New().String()
+	}
+	return s.orchestrator.ProcessChatStream(ctx, req)
 }
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"shopping-guide-backend/internal/model"
+)
+
+// comparisonCues 对比类问题的提示词
+var comparisonCues = []string{"哪个", "哪款", "对比", "比较", "区别", "差别", "不同", "还是", "选哪", "vs", "pk"}
+
+// minComparisonMatchScore 参与对比的商品最低匹配置信度，过滤掉模糊匹配中把握不大的商品
+const minComparisonMatchScore = 0.6
+
+// ComparisonService 商品对比服务
+type ComparisonService interface {
+	// Detect 识别对比类问题，返回对比表；不是对比问题时返回 nil
+	Detect(ctx context.Context, query string) (*model.ComparisonTable, error)
+	// BuildTable 由商品构建归一化对比表
+	BuildTable(products []model.Product) *model.ComparisonTable
+}
+
+// comparisonService 商品对比服务实现
+type comparisonService struct {
+	productService ProductService
+}
+
+// NewComparisonService 创建商品对比服务
+func NewComparisonService(productService ProductService) ComparisonService {
+	return &comparisonService{
+		productService: productService,
+	}
+}
+
+// Detect 识别对比类问题
+// 需要同时满足：含对比提示词、提及两个及以上商品库商品
+func (s *comparisonService) Detect(ctx context.Context, query string) (*model.ComparisonTable, error) {
+	if !hasComparisonCue(query) {
+		return nil, nil
+	}
+
+	matches, err := s.productService.MatchProducts(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]model.Product, 0, len(matches))
+	seen := make(map[string]struct{}, len(matches))
+	for _, m := range matches {
+		if m.Score < minComparisonMatchScore {
+			continue
+		}
+		if _, ok := seen[m.Product.ProductID]; ok {
+			continue
+		}
+		seen[m.Product.ProductID] = struct{}{}
+		products = append(products, m.Product)
+	}
+	if len(products) < 2 {
+		return nil, nil
+	}
+
+	return s.BuildTable(products), nil
+}
+
+// BuildTable 构建对比表
+// 固定行：价格、库存、类目、子类目；其后为各商品属性的并集，属性名归一化后按字典序排列
+func (s *comparisonService) BuildTable(products []model.Product) *model.ComparisonTable {
+	table := &model.ComparisonTable{
+		Products: make([]model.ComparisonProduct, len(products)),
+	}
+
+	price := make([]string, len(products))
+	stock := make([]string, len(products))
+	category := make([]string, len(products))
+	subCategory := make([]string, len(products))
+	attributes := make(map[string][]string)
+
+	for i, p := range products {
+		table.Products[i] = model.ComparisonProduct{
+			ProductID: p.ProductID,
+			Name:      p.Name,
+			Price:     p.Price,
+		}
+		if len(p.Images) > 0 {
+			table.Products[i].Image = p.Images[0]
+		}
+
+		price[i] = "¥" + strconv.FormatFloat(p.Price, 'f', 2, 64)
+		stock[i] = formatStock(p.Stock)
+		category[i] = p.Category
+		subCategory[i] = p.SubCategory
+
+		for key, value := range p.Attributes {
+			name := normalizeAttributeName(key)
+			if name == "" {
+				continue
+			}
+			if _, ok := attributes[name]; !ok {
+				attributes[name] = make([]string, len(products))
+			}
+			attributes[name][i] = formatAttributeValue(value)
+		}
+	}
+
+	table.Rows = append(table.Rows,
+		newComparisonRow("价格", price),
+		newComparisonRow("库存", stock),
+		newComparisonRow("类目", category),
+		newComparisonRow("子类目", subCategory),
+	)
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		table.Rows = append(table.Rows, newComparisonRow(name, attributes[name]))
+	}
+
+	return table
+}
+
+func hasComparisonCue(query string) bool {
+	lower := strings.ToLower(query)
+	for _, cue := range comparisonCues {
+		if strings.Contains(lower, cue) {
+			return true
+		}
+	}
+	return false
+}
+
+func newComparisonRow(attribute string, values []string) model.ComparisonRow {
+	differs := false
+	for _, v := range values[1:] {
+		if v != values[0] {
+			differs = true
+			break
+		}
+	}
+	return model.ComparisonRow{
+		Attribute: attribute,
+		Values:    values,
+		Differs:   differs,
+	}
+}
+
+// normalizeAttributeName 属性名归一化：去空白、小写，"-"/空格统一为"_"，使 Frame-Size 与 frame_size 合并为一行
+func normalizeAttributeName(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return strings.NewReplacer("-", "_", " ", "_").Replace(key)
+}
+
+// formatAttributeValue 属性值格式化为展示文本
+func formatAttributeValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case bool:
+		if v {
+			return "是"
+		}
+		return "否"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, formatAttributeValue(item))
+		}
+		return strings.Join(parts, "、")
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
+
+func formatStock(stock int) string {
+	if stock <= 0 {
+		return "缺货"
+	}
+	return fmt.Sprintf("有货(%d)", stock)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/model"
+)
+
+func bikeCatalog() []model.Product {
+	return []model.Product{
+		{ProductID: "bike-001", Name: "山地自行车X1", Category: "骑行", SubCategory: "山地车", Price: 1299, Stock: 50,
+			Attributes: map[string]interface{}{"Frame-Size": "M", "weight": 12.5, "disc_brake": true, "颜色": []interface{}{"黑", "白"}}},
+		{ProductID: "bike-002", Name: "通勤自行车C1", Category: "骑行", SubCategory: "通勤车", Price: 899, Stock: 0,
+			Attributes: map[string]interface{}{"frame_size": "L", "weight": 12.5, "disc_brake": false}},
+		{ProductID: "bottle-001", Name: "运动水壶500ml", Category: "骑行", SubCategory: "水壶", Price: 59, Stock: 100},
+	}
+}
+
+func newTestComparisonService(t *testing.T) ComparisonService {
+	t.Helper()
+	products, _ := newTestProductService(t, &fakeProductRepo{products: bikeCatalog()}, 0)
+	return NewComparisonService(products)
+}
+
+func TestComparisonServiceDetect(t *testing.T) {
+	s := newTestComparisonService(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "names with cue", query: "山地自行车X1和通勤自行车C1哪个好", want: []string{"bike-001", "bike-002"}},
+		{name: "model codes with cue", query: "X1 vs C1", want: []string{"bike-001", "bike-002"}},
+		{name: "product ids with cue", query: "BIKE-002 和 bike-001 有什么区别", want: []string{"bike-002", "bike-001"}},
+		{name: "duplicate mention counted once", query: "X1 和山地自行车X1 对比", want: nil},
+		{name: "no cue", query: "山地自行车X1和通勤自行车C1", want: nil},
+		{name: "single product", query: "山地自行车X1怎么样，和别的比较一下", want: nil},
+		{name: "unknown products", query: "帐篷和睡袋哪个重要", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := s.Detect(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if tt.want == nil {
+				if table != nil {
+					t.Errorf("Detect() = %+v, want nil", table.Products)
+				}
+				return
+			}
+			if table == nil {
+				t.Fatalf("Detect() = nil, want %v", tt.want)
+			}
+			got := make([]string, len(table.Products))
+			for i, p := range table.Products {
+				got[i] = p.ProductID
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("products = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparisonServiceBuildTable(t *testing.T) {
+	s := newTestComparisonService(t)
+	products := bikeCatalog()[:2]
+	products[0].Images = []string{"https://img.example.com/x1.jpg"}
+
+	table := s.BuildTable(products)
+
+	if table.Products[0].Image != "https://img.example.com/x1.jpg" || table.Products[1].Image != "" {
+		t.Errorf("images = %q, %q", table.Products[0].Image, table.Products[1].Image)
+	}
+	want := []model.ComparisonRow{
+		{Attribute: "价格", Values: []string{"¥1299.00", "¥899.00"}, Differs: true},
+		{Attribute: "库存", Values: []string{"有货(50)", "缺货"}, Differs: true},
+		{Attribute: "类目", Values: []string{"骑行", "骑行"}, Differs: false},
+		{Attribute: "子类目", Values: []string{"山地车", "通勤车"}, Differs: true},
+		{Attribute: "disc_brake", Values: []string{"是", "否"}, Differs: true},
+		{Attribute: "frame_size", Values: []string{"M", "L"}, Differs: true},
+		{Attribute: "weight", Values: []string{"12.5", "12.5"}, Differs: false},
+		{Attribute: "颜色", Values: []string{"黑、白", ""}, Differs: true},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Errorf("rows =\n%+v\nwant\n%+v", table.Rows, want)
+	}
+}
+
+func TestFormatAttributeValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"  碳纤维 ", "碳纤维"},
+		{true, "是"},
+		{false, "否"},
+		{27.5, "27.5"},
+		{float64(21), "21"},
+		{[]interface{}{"S", "M", 3.0}, "S、M、3"},
+		{map[string]interface{}{"front": "油碟"}, `{"front":"油碟"}`},
+		{time.Second, "1000000000"},
+	}
+	for _, tt := range tests {
+		if got := formatAttributeValue(tt.value); got != tt.want {
+			t.Errorf("formatAttributeValue(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAttributeName(t *testing.T) {
+	tests := map[string]string{
+		"Frame-Size":   "frame_size",
+		" frame size ": "frame_size",
+		"frame_size":   "frame_size",
+		"颜色":           "颜色",
+		"  ":           "",
+	}
+	for in, want := range tests {
+		if got := normalizeAttributeName(in); got != want {
+			t.Errorf("normalizeAttributeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
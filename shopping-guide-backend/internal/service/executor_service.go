@@ -2,66 +2,206 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/client"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
 	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/style"
+	"shopping-guide-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Executor工作流配置名（对应 dify.workflows.executors 下的键）
+const (
+	workflowProductRecommendation = "product_recommendation"
+	workflowShoppingGuide         = "shopping_guide"
+	workflowQAAssistant           = "qa_assistant"
+	workflowComparison            = "comparison"
 )
 
+// ErrUnknownTool Planner 选择了未知的 Tool
+var ErrUnknownTool = errors.New("unknown tool")
+
 // ExecutorService Executor执行器服务（从Agent）
 // 根据Planner的Tool选择，调用对应的Executor
 type ExecutorService interface {
 	// Execute 执行具体的Agent逻辑
 	Execute(ctx context.Context, req *ExecutorRequest) (*model.ExecutorResult, error)
+	// ExecuteStream 以流式模式执行，工作流输出的文本片段依次交给 onText，结束后返回完整结果
+	ExecuteStream(ctx context.Context, req *ExecutorRequest, onText func(text string)) (*model.ExecutorResult, error)
 }
 
 // ExecutorRequest Executor请求
 type ExecutorRequest struct {
-	Query               string            // 用户输入
-	Tool                string            // Tool类型（PRODUCT_RECOMMENDATION_MODULE等）
-	History             []model.Message   // 对话历史
-	BusinessInstruction string            // 商家场景描述
-	UserProfile         model.UserProfile // 用户画像
-	UserID              string            // 用户ID
+	Query               string                 // 用户输入
+	Tool                string                 // Tool类型（PRODUCT_RECOMMENDATION_MODULE等）
+	History             []model.Message        // 对话历史
+	BusinessInstruction string                 // 商家场景描述
+	UserProfile         model.UserProfile      // 用户画像
+	UserID              string                 // 用户ID
+	Debug               bool                   // 是否在结果中附带排序得分明细
+	Comparison          *model.ComparisonTable // 商品对比表（检测到对比意图时，PRODUCT_COMPARISON_MODULE 与 QA_ASSISTANT_MODULE 使用）
+	Style               *style.Style           // 导购风格
+	Brief               bool                   // 低成本模式，要求工作流简短作答
+}
+
+// workflowCall 一次 Executor 工作流调用，阻塞与流式调用共用
+type workflowCall struct {
+	name     string // 工作流配置名
+	workflow config.DifyWorkflowConfig
+	inputs   map[string]interface{}
+	// result 由工作流响应与回复文本构建执行结果
+	result func(resp *model.DifyWorkflowResponse, text string) *model.ExecutorResult
 }
 
 // executorService Executor服务实现
 type executorService struct {
-	// TODO: 注入DifyClient
-	// TODO: 注入ProductService
-	// TODO: 注入LogRepository
+	difyClient     client.DifyClient
+	productService ProductService
+	rankingService RankingService
+	store          *config.Store
 }
 
 // NewExecutorService 创建Executor服务
-func NewExecutorService() ExecutorService {
-	return &executorService{}
+func NewExecutorService(
+	difyClient client.DifyClient,
+	productService ProductService,
+	rankingService RankingService,
+	store *config.Store,
+) ExecutorService {
+	return &executorService{
+		difyClient:     difyClient,
+		productService: productService,
+		rankingService: rankingService,
+		store:          store,
+	}
 }
 
 // Execute 执行
-func (s *executorService) Execute(ctx context.Context, req *ExecutorRequest) (*model.ExecutorResult, error) {
-	// TODO: 根据Tool类型选择对应的Executor工作流
-	// TODO: 调用Dify Executor工作流
-	// TODO: 解析返回结果
-	// TODO: 记录日志
+func (s *executorService) Execute(ctx context.Context, req *ExecutorRequest) (result *model.ExecutorResult, err error) {
+	ctx, span := tracing.Start(ctx, "ExecutorService.Execute "+req.Tool, trace.WithAttributes(tracing.AttrTool.String(req.Tool)))
+	defer func() { endExecutorSpan(span, result, err) }()
+
+	call, err := s.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	callCtx, cancel := withWorkflowTimeout(ctx, call.name, call.workflow)
+	defer cancel()
+
+	difyresp, err := s.difyClient.CallWorkflow(callCtx, call.workflow.AppID, call.inputs, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s workflow: %w", call.name, err)
+	}
+
+	text, err := workflowOutputText(difyresp.Data.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	return call.result(difyresp, text), nil
+}
+
+// ExecuteStream 流式执行
+// 工作流结束时以 outputs 中的文本为准，outputs 没有文本时使用流式输出拼接的文本
+func (s *executorService) ExecuteStream(ctx context.Context, req *ExecutorRequest, onText func(text string)) (result *model.ExecutorResult, err error) {
+	ctx, span := tracing.Start(ctx, "ExecutorService.ExecuteStream "+req.Tool, trace.WithAttributes(tracing.AttrTool.String(req.Tool)))
+	defer func() { endExecutorSpan(span, result, err) }()
+
+	call, err := s.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	callCtx, cancel := withWorkflowTimeout(ctx, call.name, call.workflow)
+	defer cancel()
+
+	stream, err := s.difyClient.CallWorkflowStream(callCtx, call.workflow.AppID, call.inputs, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s workflow: %w", call.name, err)
+	}
+
+	var streamed strings.Builder
+	for chunk := range stream {
+		switch chunk.Event {
+		case model.StreamEventChunk:
+			text, _ := chunk.Data.(string)
+			streamed.WriteString(text)
+			onText(text)
+		case model.StreamEventDone:
+			difyresp, ok := chunk.Data.(*model.DifyWorkflowResponse)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %s workflow result: %T", call.name, chunk.Data)
+			}
+			text, err := workflowOutputText(difyresp.Data.Outputs)
+			if err != nil {
+				if streamed.Len() == 0 {
+					return nil, err
+				}
+				text = streamed.String()
+			}
+			return call.result(difyresp, text), nil
+		case model.StreamEventError:
+			msg, _ := chunk.Data.(string)
+			return nil, fmt.Errorf("%s workflow stream failed: %w", call.name, streamError(callCtx, errors.New(msg)))
+		}
+	}
+	// 通道在 done 之前关闭：调用超时或调用方取消
+	return nil, fmt.Errorf("%s workflow stream closed: %w", call.name, streamError(callCtx, errors.New("stream closed before workflow finished")))
+}
+
+// endExecutorSpan 结束 Executor span，成功时记录工作流运行ID与 tokens
+func endExecutorSpan(span trace.Span, result *model.ExecutorResult, err error) {
+	if result != nil {
+		runID, _ := result.Metadata["workflow_run_id"].(string)
+		tokens, _ := result.Metadata["tokens_used"].(int)
+		span.SetAttributes(tracing.AttrWorkflowRunID.String(runID), tracing.AttrTokensUsed.Int(tokens))
+	}
+	tracing.End(span, err)
+}
+
+// streamError 流式调用中途失败的错误：超时为上游超时，调用方取消时返回取消原因，其余为上游不可用
+func streamError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return apperr.Wrap(apperr.KindUpstreamTimeout, "assistant service timed out", err)
+	case ctx.Err() != nil:
+		return ctx.Err()
+	default:
+		return apperr.Wrap(apperr.KindUpstreamUnavailable, "assistant service is unavailable", err)
+	}
+}
+
+// prepare 按 Tool 构建工作流调用
+func (s *executorService) prepare(ctx context.Context, req *ExecutorRequest) (*workflowCall, error) {
 	switch req.Tool {
 	case model.ToolShoppingGuide:
-		return s.executeShoppingGuide(ctx, req)
+		return s.prepareShoppingGuide(ctx, req)
 	case model.ToolProductRecommendation:
-		return s.executeProductRecommendation(ctx, req)
+		return s.prepareProductRecommendation(ctx, req)
 	case model.ToolQAAssistant:
-		return s.executeQAAssistant(ctx, req)
+		return s.prepareQAAssistant(req)
+	case model.ToolProductComparison:
+		return s.prepareComparison(req)
 	}
 
-	return nil, nil
+	return nil, fmt.Errorf("%w: %q", ErrUnknownTool, req.Tool)
 }
 
-func (s *executorService) executeShoppingGuide(ctx context.Context, req *ExecutorRequest) (*model.ExecutorResult, error) {
+func (s *executorService) prepareShoppingGuide(ctx context.Context, req *ExecutorRequest) (*workflowCall, error) {
 
 	// 将 UserProfile 序列化为 JSON 字符串
-	userPortraitJSON := fmt.Sprintf(`{"age": %d, "gender": "%s", "interests": %s}`,
-		req.UserProfile.Age,
-		req.UserProfile.Gender,
-		req.UserProfile.Interests, // 已经是 JSON 字符串格式
-	)
+	userPortraitJSON, err := req.UserProfile.Portrait()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build user portrait: %w", err)
+	}
 
 	// 构造请求参数
 	inputs := map[string]interface{}{
@@ -73,37 +213,292 @@ func (s *executorService) executeShoppingGuide(ctx context.Context, req *Executo
 	if len(req.History) > 0 {
 		inputs["history"] = req.History
 	}
+	addRequestInputs(inputs, req)
+
+	logger.FromContext(ctx).Debug("calling shopping guide workflow", "query", req.Query, "user_portrait", userPortraitJSON)
+
+	workflow := s.cfg().Dify.Workflows.Executors[workflowShoppingGuide]
+	return &workflowCall{
+		name:     workflowShoppingGuide,
+		workflow: workflow,
+		inputs:   inputs,
+		result: func(difyresp *model.DifyWorkflowResponse, text string) *model.ExecutorResult {
+			return &model.ExecutorResult{
+				Response:            text,
+				RecommendedProducts: []model.RecommendedProduct{},
+				Metadata: map[string]interface{}{
+					"workflow_run_id": difyresp.WorkflowRunID,
+					"tokens_used":     difyresp.Data.TotalTokens,
+					"prompt_version":  workflow.PromptVersion,
+				},
+			}
+		},
+	}, nil
+}
+
+// prepareProductRecommendation 商品推荐
+// 混合检索召回候选商品，经画像与行为重排后交给推荐工作流挑选并生成推荐话术
+func (s *executorService) prepareProductRecommendation(ctx context.Context, req *ExecutorRequest) (*workflowCall, error) {
+	cfg := s.cfg()
+	rankingCfg := cfg.Business.Ranking
+	topK := cfg.Business.Product.TopK
+	recallK := topK
+	if rankingCfg.Enabled && rankingCfg.RecallMultiplier > 1 {
+		recallK = topK * rankingCfg.RecallMultiplier
+	}
+
+	searchResp, err := s.productService.SearchProducts(ctx, &model.ProductSearchRequest{
+		Query: req.Query,
+		TopK:  recallK,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	products := searchResp.Products
+	var breakdowns []model.ScoreBreakdown
+	if rankingCfg.Enabled {
+		ranked, err := s.rankingService.Rank(ctx, &RankRequest{
+			UserID:          req.UserID,
+			Profile:         req.UserProfile,
+			Candidates:      searchResp.Products,
+			RelevanceScores: searchResp.Scores,
+			TopK:            topK,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rank products: %w", err)
+		}
+		products = ranked.Products
+		breakdowns = ranked.Breakdowns
+	} else if len(products) > topK {
+		products = products[:topK]
+	}
+
+	candidates := make([]map[string]interface{}, 0, len(products))
+	for _, p := range products {
+		candidates = append(candidates, map[string]interface{}{
+			"product_id":   p.ProductID,
+			"name":         p.Name,
+			"category":     p.Category,
+			"sub_category": p.SubCategory,
+			"price":        p.Price,
+			"description":  p.Description,
+			"attributes":   p.Attributes,
+		})
+	}
+	candidatesJSON, err := json.Marshal(candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal candidates: %w", err)
+	}
+
+	inputs := map[string]interface{}{
+		"query":                req.Query,
+		"products":             string(candidatesJSON),
+		"business_instruction": req.BusinessInstruction,
+	}
+	if len(req.History) > 0 {
+		inputs["history"] = req.History
+	}
+	addRequestInputs(inputs, req)
+
+	workflow := cfg.Dify.Workflows.Executors[workflowProductRecommendation]
+	return &workflowCall{
+		name:     workflowProductRecommendation,
+		workflow: workflow,
+		inputs:   inputs,
+		result: func(difyresp *model.DifyWorkflowResponse, text string) *model.ExecutorResult {
+			result := &model.ExecutorResult{
+				Response:            text,
+				RecommendedProducts: pickRecommendedProducts(difyresp.Data.Outputs, products),
+				Metadata: map[string]interface{}{
+					"search_mode":     searchResp.Mode,
+					"candidate_count": len(searchResp.Products),
+					"workflow_run_id": difyresp.WorkflowRunID,
+					"tokens_used":     difyresp.Data.TotalTokens,
+					"prompt_version":  workflow.PromptVersion,
+				},
+			}
+			if req.Debug && rankingCfg.Debug && breakdowns != nil {
+				result.Metadata["ranking"] = breakdowns
+			}
+			return result
+		},
+	}, nil
+}
+
+// prepareQAAssistant 答疑助手，携带对比表时基于对比表作答
+func (s *executorService) prepareQAAssistant(req *ExecutorRequest) (*workflowCall, error) {
+	inputs := map[string]interface{}{
+		"query":                req.Query,
+		"business_instruction": req.BusinessInstruction,
+	}
+	if len(req.History) > 0 {
+		inputs["history"] = req.History
+	}
+	addRequestInputs(inputs, req)
+	if req.Comparison != nil {
+		tableJSON, err := json.Marshal(req.Comparison)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal comparison table: %w", err)
+		}
+		inputs["comparison_table"] = string(tableJSON)
+	}
+
+	workflow := s.cfg().Dify.Workflows.Executors[workflowQAAssistant]
+	return &workflowCall{
+		name:     workflowQAAssistant,
+		workflow: workflow,
+		inputs:   inputs,
+		result: func(difyresp *model.DifyWorkflowResponse, text string) *model.ExecutorResult {
+			result := &model.ExecutorResult{
+				Response:            text,
+				RecommendedProducts: []model.RecommendedProduct{},
+				Metadata: map[string]interface{}{
+					"workflow_run_id": difyresp.WorkflowRunID,
+					"tokens_used":     difyresp.Data.TotalTokens,
+					"prompt_version":  workflow.PromptVersion,
+				},
+			}
+			if req.Comparison != nil {
+				result.Metadata["comparison_table"] = req.Comparison
+			}
+			return result
+		},
+	}, nil
+}
 
-	difyreq := model.DifyWorkflowRequest{
-		Inputs:       inputs,
-		ResponseMode: "blocking",
-		User:         req.UserID,
+// prepareComparison 商品对比
+// 配置了对比工作流时使用对比工作流，否则把对比表交给答疑助手
+func (s *executorService) prepareComparison(req *ExecutorRequest) (*workflowCall, error) {
+	if req.Comparison == nil {
+		return nil, fmt.Errorf("comparison table is required")
 	}
 
-	fmt.Printf("\n🔍 Calling GuideChat with query: %s\n", req.Query)
-	fmt.Printf("📊 User Portrait: %s\n", userPortraitJSON)
+	workflow, ok := s.cfg().Dify.Workflows.Executors[workflowComparison]
+	if !ok || workflow.AppID == "" {
+		return s.prepareQAAssistant(req)
+	}
 
-	difyresp, err := model.GuideChat(ctx, difyreq)
+	tableJSON, err := json.Marshal(req.Comparison)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call guide chat: %w", err)
+		return nil, fmt.Errorf("failed to marshal comparison table: %w", err)
+	}
+	inputs := map[string]interface{}{
+		"query":                req.Query,
+		"comparison_table":     string(tableJSON),
+		"business_instruction": req.BusinessInstruction,
+	}
+	if len(req.History) > 0 {
+		inputs["history"] = req.History
+	}
+	addRequestInputs(inputs, req)
+
+	return &workflowCall{
+		name:     workflowComparison,
+		workflow: workflow,
+		inputs:   inputs,
+		result: func(difyresp *model.DifyWorkflowResponse, text string) *model.ExecutorResult {
+			return &model.ExecutorResult{
+				Response:            text,
+				RecommendedProducts: []model.RecommendedProduct{},
+				Metadata: map[string]interface{}{
+					"workflow_run_id":  difyresp.WorkflowRunID,
+					"tokens_used":      difyresp.Data.TotalTokens,
+					"prompt_version":   workflow.PromptVersion,
+					"comparison_table": req.Comparison,
+				},
+			}
+		},
+	}, nil
+}
+
+// addRequestInputs 传入各 Executor 共用的输入
+// 导购风格：style、style_prompt、style_examples；低成本模式：answer_length=short
+func addRequestInputs(inputs map[string]interface{}, req *ExecutorRequest) {
+	if req.Brief {
+		inputs["answer_length"] = "short"
+	}
+	if req.Style == nil {
+		return
+	}
+	for k, v := range req.Style.Inputs() {
+		inputs[k] = v
+	}
+}
+
+// withWorkflowTimeout 按工作流配置设置调用超时，并标记工作流名称写入 Dify 调用日志
+func withWorkflowTimeout(ctx context.Context, name string, workflow config.DifyWorkflowConfig) (context.Context, context.CancelFunc) {
+	ctx = client.WithWorkflowName(ctx, name)
+	if workflow.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, workflow.Timeout)
+}
+
+// workflowOutputText 提取工作流文本输出，优先 result 字段，其次 text 字段
+func workflowOutputText(outputs map[string]interface{}) (string, error) {
+	if text, ok := outputs["result"].(string); ok {
+		return text, nil
+	}
+	if text, ok := outputs["text"].(string); ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("neither 'result' nor 'text' field found in outputs, outputs=%+v", outputs)
+}
+
+// pickRecommendedProducts 解析工作流挑选的商品
+// 工作流通过 recommended_products 输出 [{"product_id","reason"}]（数组或JSON字符串），
+// 只保留候选集内的商品，价格、图片等以商品库为准；未输出时按检索顺序返回全部候选
+func pickRecommendedProducts(outputs map[string]interface{}, candidates []model.Product) []model.RecommendedProduct {
+	byID := make(map[string]*model.Product, len(candidates))
+	for i := range candidates {
+		byID[candidates[i].ProductID] = &candidates[i]
 	}
 
-	fmt.Printf("\n📝 Guide raw response: %s\n", difyresp)
+	var picks []struct {
+		ProductID string `json:"product_id"`
+		Reason    string `json:"reason"`
+	}
+	switch raw := outputs["recommended_products"].(type) {
+	case string:
+		_ = json.Unmarshal([]byte(raw), &picks)
+	case []interface{}:
+		if data, err := json.Marshal(raw); err == nil {
+			_ = json.Unmarshal(data, &picks)
+		}
+	}
 
-	// TODO: 解析 difyresp 并构造 ExecutorResult
-	result := &model.ExecutorResult{
-		Response:            difyresp,
-		RecommendedProducts: []model.RecommendedProduct{},
-		Metadata:            map[string]interface{}{},
+	products := make([]model.RecommendedProduct, 0, len(candidates))
+	if len(picks) == 0 {
+		for i := range candidates {
+			products = append(products, toRecommendedProduct(&candidates[i], ""))
+		}
+		return products
 	}
 
-	return result, nil
+	for _, pick := range picks {
+		if p, ok := byID[pick.ProductID]; ok {
+			products = append(products, toRecommendedProduct(p, pick.Reason))
+		}
+	}
+	return products
 }
 
-func (s *executorService) executeProductRecommendation(ctx context.Context, req *ExecutorRequest) (*model.ExecutorResult, error) {
-	return nil, nil
+// toRecommendedProduct 商品转换为推荐商品
+func toRecommendedProduct(p *model.Product, reason string) model.RecommendedProduct {
+	rp := model.RecommendedProduct{
+		ProductID: p.ProductID,
+		Name:      p.Name,
+		Price:     p.Price,
+		Reason:    reason,
+	}
+	if len(p.Images) > 0 {
+		rp.Image = p.Images[0]
+	}
+	return rp
 }
 
-func (s *executorService) executeQAAssistant(ctx context.Context, req *ExecutorRequest) (*model.ExecutorResult, error) {
-	return nil, nil
+// cfg 当前生效的配置
+func (s *executorService) cfg() *config.Config {
+	return s.store.Get()
 }
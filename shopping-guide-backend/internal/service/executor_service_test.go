@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+)
+
+// fakeDifyClient 阻塞调用返回 resp，流式调用依次推送 events；记录调用的应用
+type fakeDifyClient struct {
+	resp   *model.DifyWorkflowResponse
+	events []model.StreamChunk
+	appIDs []string
+}
+
+func (c *fakeDifyClient) CallWorkflow(ctx context.Context, appID string, inputs map[string]interface{}, user string) (*model.DifyWorkflowResponse, error) {
+	c.appIDs = append(c.appIDs, appID)
+	return c.resp, nil
+}
+
+func (c *fakeDifyClient) CallWorkflowStream(ctx context.Context, appID string, inputs map[string]interface{}, user string) (<-chan model.StreamChunk, error) {
+	c.appIDs = append(c.appIDs, appID)
+	ch := make(chan model.StreamChunk)
+	go func() {
+		defer close(ch)
+		for _, event := range c.events {
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (c *fakeDifyClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+func newTestExecutorService(dify *fakeDifyClient) ExecutorService {
+	cfg := &config.Config{}
+	cfg.Dify.Workflows.Executors = map[string]config.DifyWorkflowConfig{
+		workflowQAAssistant: {AppID: "app-qa", PromptVersion: "qa-v1"},
+	}
+	return NewExecutorService(dify, nil, nil, config.NewStore(cfg))
+}
+
+func workflowDone(outputs map[string]interface{}, tokens int) model.StreamChunk {
+	return model.StreamChunk{Event: model.StreamEventDone, Data: &model.DifyWorkflowResponse{
+		WorkflowRunID: "run-1",
+		Data:          model.DifyWorkflowRunData{Status: "succeeded", Outputs: outputs, TotalTokens: tokens},
+	}}
+}
+
+func TestExecutorExecuteStream(t *testing.T) {
+	chunks := []model.StreamChunk{
+		{Event: model.StreamEventChunk, Data: "通勤选"},
+		{Event: model.StreamEventChunk, Data: "C1。"},
+	}
+	tests := []struct {
+		name     string
+		events   []model.StreamChunk
+		want     string
+		wantKind apperr.Kind
+		wantErr  bool
+	}{
+		{name: "outputs text", events: append(chunks, workflowDone(map[string]interface{}{"text": "通勤选C1！"}, 12)), want: "通勤选C1！"},
+		{name: "streamed text without outputs", events: append(chunks, workflowDone(map[string]interface{}{}, 12)), want: "通勤选C1。"},
+		{name: "workflow error", events: append(chunks, model.StreamChunk{Event: model.StreamEventError, Data: "workflow failed"}),
+			wantErr: true, wantKind: apperr.KindUpstreamUnavailable},
+		{name: "closed before done", events: chunks, wantErr: true, wantKind: apperr.KindUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dify := &fakeDifyClient{events: tt.events}
+			s := newTestExecutorService(dify)
+
+			var streamed []string
+			result, err := s.ExecuteStream(context.Background(), &ExecutorRequest{Query: "通勤买哪辆", Tool: model.ToolQAAssistant},
+				func(text string) { streamed = append(streamed, text) })
+			if !reflect.DeepEqual(streamed, []string{"通勤选", "C1。"}) {
+				t.Errorf("streamed = %v", streamed)
+			}
+			if tt.wantErr {
+				if err == nil || apperr.KindOf(err) != tt.wantKind {
+					t.Errorf("ExecuteStream() error = %v, want kind %v", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecuteStream() error = %v", err)
+			}
+			if result.Response != tt.want || result.Metadata["tokens_used"] != 12 || result.Metadata["prompt_version"] != "qa-v1" {
+				t.Errorf("result = %+v", result)
+			}
+			if !reflect.DeepEqual(dify.appIDs, []string{"app-qa"}) {
+				t.Errorf("called apps = %v", dify.appIDs)
+			}
+		})
+	}
+}
+
+func TestExecutorComparisonFallsBackToQA(t *testing.T) {
+	dify := &fakeDifyClient{resp: &model.DifyWorkflowResponse{
+		Data: model.DifyWorkflowRunData{Status: "succeeded", Outputs: map[string]interface{}{"result": "X1 更适合山路。"}},
+	}}
+	s := newTestExecutorService(dify)
+	table := &model.ComparisonTable{}
+
+	result, err := s.Execute(context.Background(), &ExecutorRequest{Tool: model.ToolProductComparison, Comparison: table})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Response != "X1 更适合山路。" || result.Metadata["comparison_table"] != table || dify.appIDs[0] != "app-qa" {
+		t.Errorf("result = %+v, apps = %v", result, dify.appIDs)
+	}
+
+	if _, err := s.Execute(context.Background(), &ExecutorRequest{Tool: "UNKNOWN"}); err == nil {
+		t.Error("Execute() with unknown tool succeeded")
+	}
+}
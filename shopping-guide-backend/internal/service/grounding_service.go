@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/metrics"
+	"shopping-guide-backend/internal/model"
+)
+
+var (
+	// priceRe 报价：¥1299 / ￥59.9 / 1299元 / 59块
+	priceRe = regexp.MustCompile(`[¥￥]\s*(\d+(?:\.\d+)?)|(\d+(?:\.\d+)?)\s*(?:元|块)`)
+	// modelCodeRe 疑似商品型号：字母开头且包含数字的英数字串，如 X1、Pro2
+	modelCodeRe = regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9]*\d[A-Za-z0-9]*\b`)
+	// quotedNameRe 书名号/直角引号中的名称
+	quotedNameRe = regexp.MustCompile(`[「《]([^」》]{2,30})[」》]`)
+	// sentenceEnd 句子结束符
+	sentenceEnd = "。！？!?；;\n"
+)
+
+// GroundingService 回复中商品与价格的校验服务
+// 对 Executor 输出做后处理：识别商品提及与报价，与商品库核对
+type GroundingService interface {
+	Ground(ctx context.Context, result *model.ExecutorResult) (*model.GroundingReport, error)
+}
+
+// groundingService 校验服务实现
+type groundingService struct {
+	productService ProductService
+	store          *config.Store
+}
+
+// NewGroundingService 创建校验服务
+func NewGroundingService(productService ProductService, store *config.Store) GroundingService {
+	return &groundingService{
+		productService: productService,
+		store:          store,
+	}
+}
+
+// span 文本片段
+type span struct {
+	start, end int
+}
+
+// Ground 校验并改写 Executor 结果
+// 1. 命中商品库的商品补充为 RecommendedProduct；
+// 2. 命中商品的报价与商品库不一致时按配置替换为真实价格；
+// 3. 商品库中不存在的商品按策略标记或删除所在句子
+func (s *groundingService) Ground(ctx context.Context, result *model.ExecutorResult) (*model.GroundingReport, error) {
+	if !s.cfg().Enabled {
+		return nil, nil
+	}
+
+	report := &model.GroundingReport{
+		Matched:     []string{},
+		Unmatched:   []string{},
+		PriceIssues: []model.PriceIssue{},
+	}
+	text := result.Response
+	if text == "" {
+		return report, nil
+	}
+
+	allMatches, err := s.productService.MatchProducts(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]model.ProductMatch, 0, len(allMatches))
+	for _, m := range allMatches {
+		if m.Score >= s.cfg().MinMatchScore {
+			matches = append(matches, m)
+		}
+	}
+
+	// 1. 命中商品补充为结构化推荐
+	existing := make(map[string]struct{}, len(result.RecommendedProducts))
+	for _, p := range result.RecommendedProducts {
+		existing[p.ProductID] = struct{}{}
+	}
+	for _, m := range matches {
+		report.Matched = appendUnique(report.Matched, m.Product.ProductID)
+		if _, ok := existing[m.Product.ProductID]; !ok {
+			existing[m.Product.ProductID] = struct{}{}
+			result.RecommendedProducts = append(result.RecommendedProducts, toRecommendedProduct(&m.Product, ""))
+		}
+	}
+
+	// 2. 报价核对
+	type replacement struct {
+		span
+		text string
+	}
+	var replacements []replacement
+	for _, loc := range priceRe.FindAllStringSubmatchIndex(text, -1) {
+		numStart, numEnd := loc[2], loc[3]
+		if numStart < 0 {
+			numStart, numEnd = loc[4], loc[5]
+		}
+		mentioned, err := strconv.ParseFloat(text[numStart:numEnd], 64)
+		if err != nil {
+			continue
+		}
+
+		m := nearestMatchInSentence(text, matches, loc[0])
+		if m == nil || m.Product.Price <= 0 {
+			// 没有关联商品的金额（如用户预算）不做校验
+			continue
+		}
+		if math.Abs(mentioned-m.Product.Price)/m.Product.Price <= s.cfg().PriceTolerance {
+			continue
+		}
+
+		issue := model.PriceIssue{
+			ProductID: m.Product.ProductID,
+			Mentioned: mentioned,
+			Actual:    m.Product.Price,
+			Corrected: s.cfg().CorrectPrices,
+		}
+		report.PriceIssues = append(report.PriceIssues, issue)
+		if s.cfg().CorrectPrices {
+			replacements = append(replacements, replacement{
+				span: span{start: loc[0], end: loc[1]},
+				text: formatPriceLike(text[loc[0]:loc[1]], m.Product.Price),
+			})
+		}
+	}
+
+	// 3. 商品库中不存在的商品
+	var unmatchedSpans []span
+	for _, c := range s.unknownProductCandidates(text) {
+		known, err := s.productService.MatchProducts(ctx, text[c.start:c.end])
+		if err != nil {
+			return nil, err
+		}
+		if hasConfidentMatch(known, s.cfg().MinMatchScore) || overlapsMatch(c, matches) {
+			continue
+		}
+		report.Unmatched = appendUnique(report.Unmatched, text[c.start:c.end])
+		unmatchedSpans = append(unmatchedSpans, c)
+	}
+
+	// 从后往前替换，保证前面片段的偏移不受影响
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start > replacements[j].start })
+	if s.cfg().Policy == model.GroundingPolicyStrip && len(unmatchedSpans) > 0 {
+		text = stripSentences(text, unmatchedSpans, func(sentence span) string {
+			out := text[sentence.start:sentence.end]
+			for _, r := range replacements {
+				if r.start >= sentence.start && r.end <= sentence.end {
+					out = out[:r.start-sentence.start] + r.text + out[r.end-sentence.start:]
+				}
+			}
+			return out
+		})
+		report.Stripped = true
+		if strings.TrimSpace(text) == "" {
+			text = s.cfg().FallbackMessage
+		}
+	} else {
+		for _, r := range replacements {
+			text = text[:r.start] + r.text + text[r.end:]
+		}
+	}
+	result.Response = text
+
+	recordGroundingStats(len(matches), len(report.Unmatched), len(report.PriceIssues))
+	return report, nil
+}
+
+// unknownProductCandidates 疑似商品提及：型号与书名号/直角引号中的名称
+func (s *groundingService) unknownProductCandidates(text string) []span {
+	ignore := make(map[string]struct{}, len(s.cfg().IgnoreTerms))
+	for _, term := range s.cfg().IgnoreTerms {
+		ignore[strings.ToLower(term)] = struct{}{}
+	}
+
+	var spans []span
+	for _, loc := range modelCodeRe.FindAllStringIndex(text, -1) {
+		if _, ok := ignore[strings.ToLower(text[loc[0]:loc[1]])]; ok {
+			continue
+		}
+		spans = append(spans, span{start: loc[0], end: loc[1]})
+	}
+	for _, loc := range quotedNameRe.FindAllStringSubmatchIndex(text, -1) {
+		spans = append(spans, span{start: loc[2], end: loc[3]})
+	}
+	return spans
+}
+
+// nearestMatchInSentence 找到与报价同句、位置最近的商品提及，优先取报价之前的
+func nearestMatchInSentence(text string, matches []model.ProductMatch, pos int) *model.ProductMatch {
+	sentence := sentenceAt(text, pos)
+	var before, after *model.ProductMatch
+	for i := range matches {
+		m := &matches[i]
+		if m.Position < sentence.start || m.Position >= sentence.end {
+			continue
+		}
+		if m.Position <= pos {
+			if before == nil || m.Position > before.Position {
+				before = m
+			}
+		} else if after == nil || m.Position < after.Position {
+			after = m
+		}
+	}
+	if before != nil {
+		return before
+	}
+	return after
+}
+
+// sentenceAt 返回 pos 所在句子的范围（含结束符）
+func sentenceAt(text string, pos int) span {
+	start := strings.LastIndexAny(text[:pos], sentenceEnd)
+	if start < 0 {
+		start = 0
+	} else {
+		_, size := utf8.DecodeRuneInString(text[start:])
+		start += size
+	}
+	end := strings.IndexAny(text[pos:], sentenceEnd)
+	if end < 0 {
+		end = len(text)
+	} else {
+		_, size := utf8.DecodeRuneInString(text[pos+end:])
+		end = pos + end + size
+	}
+	return span{start: start, end: end}
+}
+
+// stripSentences 删除包含指定片段的句子，保留的句子经 keep 处理后拼接
+func stripSentences(text string, spans []span, keep func(sentence span) string) string {
+	var b strings.Builder
+	for pos := 0; pos < len(text); {
+		sentence := sentenceAt(text, pos)
+		drop := false
+		for _, sp := range spans {
+			if sp.start >= sentence.start && sp.start < sentence.end {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			b.WriteString(keep(sentence))
+		}
+		pos = sentence.end
+	}
+	return b.String()
+}
+
+// formatPriceLike 按原报价的写法输出正确价格
+func formatPriceLike(original string, price float64) string {
+	value := strconv.FormatFloat(price, 'f', -1, 64)
+	if strings.HasPrefix(original, "¥") || strings.HasPrefix(original, "￥") {
+		return "¥" + value
+	}
+	return value + "元"
+}
+
+func hasConfidentMatch(matches []model.ProductMatch, minScore float64) bool {
+	for _, m := range matches {
+		if m.Score >= minScore {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsMatch 片段是否落在已命中商品的提及中（如型号 X1 属于"山地自行车X1"）
+func overlapsMatch(sp span, matches []model.ProductMatch) bool {
+	for _, m := range matches {
+		if sp.start < m.Position+len(m.Mention) && m.Position < sp.end {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, item := range list {
+		if item == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+// recordGroundingStats 累计校验统计，幻觉率 = (unmatched + price_mismatch) / mentions，由 Prometheus 记录规则计算
+func recordGroundingStats(matched, unmatched, priceMismatch int) {
+	metrics.GroundingMentions.WithLabelValues("matched").Add(float64(matched))
+	metrics.GroundingMentions.WithLabelValues("unmatched").Add(float64(unmatched))
+	metrics.GroundingPriceMismatches.Add(float64(priceMismatch))
+	metrics.GroundingResponses.WithLabelValues(strconv.FormatBool(unmatched > 0 || priceMismatch > 0)).Inc()
+}
+
+// cfg 当前生效的校验配置
+func (s *groundingService) cfg() *config.GroundingConfig {
+	return &s.store.Get().Business.Grounding
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+)
+
+func newTestGroundingService(t *testing.T, mutate func(*config.GroundingConfig)) GroundingService {
+	t.Helper()
+	products, _ := newTestProductService(t, &fakeProductRepo{products: bikeCatalog()}, 0)
+	cfg := &config.Config{}
+	cfg.Business.Grounding = config.GroundingConfig{
+		Enabled:         true,
+		Policy:          model.GroundingPolicyFlag,
+		CorrectPrices:   true,
+		PriceTolerance:  0.05,
+		MinMatchScore:   0.6,
+		IgnoreTerms:     []string{"USB3", "ip67"},
+		FallbackMessage: "抱歉，暂时没有找到合适的商品。",
+	}
+	if mutate != nil {
+		mutate(&cfg.Business.Grounding)
+	}
+	return NewGroundingService(products, config.NewStore(cfg))
+}
+
+func TestGroundingServiceGround(t *testing.T) {
+	strip := func(c *config.GroundingConfig) { c.Policy = model.GroundingPolicyStrip }
+
+	tests := []struct {
+		name          string
+		mutate        func(*config.GroundingConfig)
+		response      string
+		wantResponse  string
+		wantMatched   []string
+		wantUnmatched []string
+		wantIssues    []model.PriceIssue
+		wantStripped  bool
+	}{
+		{
+			name:         "matched product",
+			response:     "推荐山地自行车X1，爬坡很稳。",
+			wantResponse: "推荐山地自行车X1，爬坡很稳。",
+			wantMatched:  []string{"bike-001"},
+		},
+		{
+			name:         "wrong price corrected",
+			response:     "山地自行车X1只要¥999，通勤自行车C1只要799元。",
+			wantResponse: "山地自行车X1只要¥1299，通勤自行车C1只要899元。",
+			wantMatched:  []string{"bike-001", "bike-002"},
+			wantIssues: []model.PriceIssue{
+				{ProductID: "bike-001", Mentioned: 999, Actual: 1299, Corrected: true},
+				{ProductID: "bike-002", Mentioned: 799, Actual: 899, Corrected: true},
+			},
+		},
+		{
+			name:         "wrong price flagged only",
+			mutate:       func(c *config.GroundingConfig) { c.CorrectPrices = false },
+			response:     "山地自行车X1只要￥999。",
+			wantResponse: "山地自行车X1只要￥999。",
+			wantMatched:  []string{"bike-001"},
+			wantIssues:   []model.PriceIssue{{ProductID: "bike-001", Mentioned: 999, Actual: 1299}},
+		},
+		{
+			name:         "price within tolerance",
+			response:     "山地自行车X1大约1300块。",
+			wantResponse: "山地自行车X1大约1300块。",
+			wantMatched:  []string{"bike-001"},
+		},
+		{
+			name:         "price in another sentence is not checked",
+			response:     "山地自行车X1很适合你。预算500元也有别的选择。",
+			wantResponse: "山地自行车X1很适合你。预算500元也有别的选择。",
+			wantMatched:  []string{"bike-001"},
+		},
+		{
+			name:          "unknown products flagged",
+			response:      "也可以看看「闪电侠Pro」和Z9，支持USB3和IP67。",
+			wantResponse:  "也可以看看「闪电侠Pro」和Z9，支持USB3和IP67。",
+			wantUnmatched: []string{"Z9", "闪电侠Pro"},
+		},
+		{
+			name:          "unknown product sentence stripped",
+			mutate:        strip,
+			response:      "推荐山地自行车X1，只要¥999。也可以看看Z9。",
+			wantResponse:  "推荐山地自行车X1，只要¥1299。",
+			wantMatched:   []string{"bike-001"},
+			wantUnmatched: []string{"Z9"},
+			wantIssues:    []model.PriceIssue{{ProductID: "bike-001", Mentioned: 999, Actual: 1299, Corrected: true}},
+			wantStripped:  true,
+		},
+		{
+			name:          "fallback when everything stripped",
+			mutate:        strip,
+			response:      "推荐Z9！",
+			wantResponse:  "抱歉，暂时没有找到合适的商品。",
+			wantUnmatched: []string{"Z9"},
+			wantStripped:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestGroundingService(t, tt.mutate)
+			result := &model.ExecutorResult{Response: tt.response}
+
+			report, err := s.Ground(context.Background(), result)
+			if err != nil {
+				t.Fatalf("Ground() error = %v", err)
+			}
+			if result.Response != tt.wantResponse {
+				t.Errorf("response = %q, want %q", result.Response, tt.wantResponse)
+			}
+			if !reflect.DeepEqual(report.Matched, nonNil(tt.wantMatched)) {
+				t.Errorf("matched = %v, want %v", report.Matched, tt.wantMatched)
+			}
+			if !reflect.DeepEqual(report.Unmatched, nonNil(tt.wantUnmatched)) {
+				t.Errorf("unmatched = %v, want %v", report.Unmatched, tt.wantUnmatched)
+			}
+			wantIssues := tt.wantIssues
+			if wantIssues == nil {
+				wantIssues = []model.PriceIssue{}
+			}
+			if !reflect.DeepEqual(report.PriceIssues, wantIssues) {
+				t.Errorf("price issues = %+v, want %+v", report.PriceIssues, wantIssues)
+			}
+			if report.Stripped != tt.wantStripped {
+				t.Errorf("stripped = %v, want %v", report.Stripped, tt.wantStripped)
+			}
+		})
+	}
+}
+
+func TestGroundingServiceSupplementsRecommendations(t *testing.T) {
+	s := newTestGroundingService(t, nil)
+	result := &model.ExecutorResult{
+		Response:            "山地自行车X1和通勤自行车C1都不错。",
+		RecommendedProducts: []model.RecommendedProduct{{ProductID: "bike-002", Reason: "通勤首选"}},
+	}
+
+	if _, err := s.Ground(context.Background(), result); err != nil {
+		t.Fatalf("Ground() error = %v", err)
+	}
+	if got := len(result.RecommendedProducts); got != 2 {
+		t.Fatalf("recommended products = %d, want 2", got)
+	}
+	if result.RecommendedProducts[0].Reason != "通勤首选" || result.RecommendedProducts[1].ProductID != "bike-001" {
+		t.Errorf("recommended products = %+v", result.RecommendedProducts)
+	}
+}
+
+func TestGroundingServiceDisabled(t *testing.T) {
+	s := newTestGroundingService(t, func(c *config.GroundingConfig) { c.Enabled = false })
+	result := &model.ExecutorResult{Response: "推荐Z9，只要¥1。"}
+
+	report, err := s.Ground(context.Background(), result)
+	if err != nil || report != nil {
+		t.Errorf("Ground() = %+v, %v, want nil report", report, err)
+	}
+	if result.Response != "推荐Z9，只要¥1。" {
+		t.Errorf("response changed to %q", result.Response)
+	}
+}
+
+func TestSentenceAt(t *testing.T) {
+	text := "第一句。第二句！third"
+	tests := []struct {
+		pos  int
+		want string
+	}{
+		{0, "第一句。"},
+		{len("第一句。"), "第二句！"},
+		{len("第一句。第二"), "第二句！"},
+		{len(text) - 1, "third"},
+	}
+	for _, tt := range tests {
+		sp := sentenceAt(text, tt.pos)
+		if got := text[sp.start:sp.end]; got != tt.want {
+			t.Errorf("sentenceAt(%d) = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/model"
+)
+
+// defaultHealthTimeout 未配置 server.health.timeout 时单个依赖检查的超时
+const defaultHealthTimeout = 2 * time.Second
+
+// healthCacheTTL 检查结果的缓存时间，探针与外部请求频繁访问时不会每次都访问依赖
+const healthCacheTTL = time.Second
+
+// HealthCheck 依赖检查项
+type HealthCheck struct {
+	Name     string
+	Critical bool // 关键依赖异常时服务不可用（readyz 返回 503），非关键依赖异常只降级
+	Check    func(ctx context.Context) error
+}
+
+// HealthService 就绪检查服务
+type HealthService interface {
+	// Check 并发检查所有依赖，每项单独超时；结果缓存 healthCacheTTL，并发请求共用同一次检查
+	Check(ctx context.Context) *model.HealthReport
+}
+
+// healthService 就绪检查服务实现
+type healthService struct {
+	checks  []HealthCheck
+	timeout time.Duration
+
+	mu        sync.Mutex
+	cached    *model.HealthReport
+	checkedAt time.Time
+}
+
+// NewHealthService 创建就绪检查服务
+func NewHealthService(checks []HealthCheck, timeout time.Duration) HealthService {
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	return &healthService{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Check 检查依赖，结果按注册顺序返回
+func (s *healthService) Check(ctx context.Context) *model.HealthReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached != nil && time.Since(s.checkedAt) < healthCacheTTL {
+		return s.cached
+	}
+
+	// 结果会被其他请求复用，不随当前请求取消
+	s.cached = s.check(context.WithoutCancel(ctx))
+	s.checkedAt = time.Now()
+	return s.cached
+}
+
+func (s *healthService) check(ctx context.Context) *model.HealthReport {
+	report := &model.HealthReport{
+		Status: model.HealthStatusOK,
+		Checks: make([]model.DependencyStatus, len(s.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			report.Checks[i] = s.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, c := range report.Checks {
+		if c.Status == model.DependencyStatusUp {
+			continue
+		}
+		if c.Critical {
+			report.Status = model.HealthStatusUnavailable
+			break
+		}
+		report.Status = model.HealthStatusDegraded
+	}
+	return report
+}
+
+func (s *healthService) run(ctx context.Context, check HealthCheck) model.DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	status := model.DependencyStatus{
+		Name:      check.Name,
+		Status:    model.DependencyStatusUp,
+		Critical:  check.Critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		// 错误信息可能包含内部地址，只进日志不返回
+		status.Status = model.DependencyStatusDown
+		logger.FromContext(ctx).Warn("dependency check failed", "dependency", check.Name, "error", err)
+	}
+	return status
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/model"
+)
+
+func TestHealthServiceStatus(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("dial tcp 10.0.0.3:6379: connection refused") }
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		wantStatus string
+		wantDeps   []string
+	}{
+		{
+			name:       "all up",
+			checks:     []HealthCheck{{Name: "redis", Critical: true, Check: up}, {Name: "dify", Check: up}},
+			wantStatus: model.HealthStatusOK,
+			wantDeps:   []string{model.DependencyStatusUp, model.DependencyStatusUp},
+		},
+		{
+			name:       "non-critical down",
+			checks:     []HealthCheck{{Name: "redis", Critical: true, Check: up}, {Name: "dify", Check: down}},
+			wantStatus: model.HealthStatusDegraded,
+			wantDeps:   []string{model.DependencyStatusUp, model.DependencyStatusDown},
+		},
+		{
+			name:       "critical timeout",
+			checks:     []HealthCheck{{Name: "dify", Check: down}, {Name: "mysql", Critical: true, Check: hang}},
+			wantStatus: model.HealthStatusUnavailable,
+			wantDeps:   []string{model.DependencyStatusDown, model.DependencyStatusDown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewHealthService(tt.checks, 20*time.Millisecond).Check(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			for i, dep := range report.Checks {
+				if dep.Name != tt.checks[i].Name || dep.Critical != tt.checks[i].Critical || dep.Status != tt.wantDeps[i] {
+					t.Errorf("checks[%d] = %+v, want %s", i, dep, tt.wantDeps[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHealthServiceCache(t *testing.T) {
+	var calls atomic.Int32
+	s := NewHealthService([]HealthCheck{{Name: "redis", Critical: true, Check: func(ctx context.Context) error {
+		calls.Add(1)
+		return ctx.Err()
+	}}}, time.Second)
+
+	// 请求取消不影响被缓存的结果
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first := s.Check(ctx)
+	if first.Status != model.HealthStatusOK {
+		t.Errorf("status = %s, want ok for canceled request", first.Status)
+	}
+	if second := s.Check(context.Background()); second != first || calls.Load() != 1 {
+		t.Errorf("second check not served from cache, calls = %d", calls.Load())
+	}
+}
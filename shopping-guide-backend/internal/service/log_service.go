@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/panics"
+	"shopping-guide-backend/internal/repository"
+	"shopping-guide-backend/internal/requestctx"
+)
+
+// LogService 对话日志与 Dify 调用日志服务
+// 日志异步写库，队列满或写库失败时丢弃并告警，不影响对话链路
+type LogService interface {
+	// RecordChat 记录一轮对话
+	RecordChat(ctx context.Context, log *model.ChatLog)
+	// RecordDifyCall 记录一次 Dify 工作流调用，实现 client.CallRecorder
+	RecordDifyCall(ctx context.Context, log *model.DifyCallLog)
+	// Close 停止接收日志并等待队列中的日志写完
+	Close()
+}
+
+// logService 日志服务实现
+type logService struct {
+	repo repository.LogRepository
+	cfg  *config.LogPersistConfig
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan func(ctx context.Context) error
+	wg     sync.WaitGroup
+}
+
+// NewLogService 创建日志服务，启用时启动后台 worker
+func NewLogService(repo repository.LogRepository, cfg *config.LogPersistConfig) LogService {
+	s := &logService{
+		repo: repo,
+		cfg:  cfg,
+	}
+	if !cfg.Enabled {
+		return s
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	s.queue = make(chan func(ctx context.Context) error, queueSize)
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// RecordChat 记录一轮对话，请求ID与 trace-id 未填写时从 context 补全
+func (s *logService) RecordChat(ctx context.Context, log *model.ChatLog) {
+	if log.RequestID == "" {
+		if m, ok := requestctx.FromContext(ctx); ok {
+			log.RequestID, log.TraceID = m.RequestID, m.TraceID
+		}
+	}
+	s.enqueue("chat", log.RequestID, func(ctx context.Context) error {
+		return s.repo.SaveChatLog(ctx, log)
+	})
+}
+
+// RecordDifyCall 记录一次 Dify 工作流调用，请求ID与 trace-id 未填写时从 context 补全
+func (s *logService) RecordDifyCall(ctx context.Context, log *model.DifyCallLog) {
+	if log.RequestID == "" {
+		if m, ok := requestctx.FromContext(ctx); ok {
+			log.RequestID, log.TraceID = m.RequestID, m.TraceID
+		}
+	}
+	s.enqueue("dify_call", log.RequestID, func(ctx context.Context) error {
+		return s.repo.SaveDifyCallLog(ctx, log)
+	})
+}
+
+// Close 停止接收日志并等待 worker 退出
+func (s *logService) Close() {
+	s.mu.Lock()
+	if s.closed || s.queue == nil {
+		s.closed = true
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *logService) enqueue(kind, requestID string, save func(ctx context.Context) error) {
+	if !s.cfg.Enabled {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.queue <- save:
+	default:
+		slog.Warn("log persist queue is full, dropping log", "kind", kind, "request_id", requestID)
+	}
+}
+
+func (s *logService) worker() {
+	defer s.wg.Done()
+	for save := range s.queue {
+		s.persist(save)
+	}
+}
+
+// persist 写入一条日志，单条日志 panic 时记录后继续处理后续日志
+func (s *logService) persist(save func(ctx context.Context) error) {
+	ctx, cancel := context.Background(), func() {}
+	if s.cfg.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+	}
+	defer cancel()
+	defer panics.Recover(ctx, panics.SourceLogService, nil)
+
+	if err := save(ctx); err != nil {
+		slog.Error("failed to persist log", "error", err)
+	}
+}
@@ -2,9 +2,29 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/metrics"
 	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/panics"
+	"shopping-guide-backend/internal/requestctx"
+	"shopping-guide-backend/internal/style"
+	"shopping-guide-backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrUnauthenticated 请求缺少调用方身份
+	ErrUnauthenticated error = apperr.New(apperr.KindUnauthorized, "unauthenticated")
+	// ErrForbidden 调用方无权访问该资源，如访问他人的会话
+	ErrForbidden error = apperr.New(apperr.KindForbidden, "forbidden")
 )
 
 // OrchestratorService 编排服务
@@ -14,18 +34,42 @@ type OrchestratorService interface {
 	// 1. 加载会话上下文
 	// 2. 调用Planner进行规划
 	// 3. 调用对应的Executor执行
-	// 4. 保存会话和日志
+	// 4. 保存会话和日志（对话日志异步落库）
 	ProcessChat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error)
+	// ProcessChatStream 流式处理对话，流程与 ProcessChat 相同，Executor 以流式模式调用
+	// 调用 Executor 前的错误（鉴权、会话归属、配额、Planner）直接返回；之后依次推送 planner、chunk、products、done 事件，
+	// 失败时推送 error 事件（Data 为 error，由处理器转换为错误响应）后关闭通道
+	ProcessChatStream(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error)
+}
+
+// chatTurn 一轮对话在调用 Executor 前确定的上下文，阻塞与流式对话共用
+type chatTurn struct {
+	start         time.Time
+	req           *model.ChatRequest
+	principal     *auth.Principal
+	session       *model.Session
+	quotaSubject  *QuotaSubject
+	quotaStatus   *model.QuotaStatus
+	plannerResult *model.PlannerResult
+	executorReq   *ExecutorRequest
+	style         *style.Style
 }
 
 // orchestratorService 编排服务实现
 type orchestratorService struct {
-	plannerService  PlannerService
-	executorService ExecutorService
-	sessionService  SessionService
-	productService  ProductService
-	profileService  ProfileService
-	// logRepo repository.LogRepository
+	plannerService        PlannerService
+	executorService       ExecutorService
+	sessionService        SessionService
+	productService        ProductService
+	profileService        ProfileService
+	recommendationService RecommendationService
+	comparisonService     ComparisonService
+	groundingService      GroundingService
+	enrichmentService     ProfileEnrichmentService
+	quotaService          QuotaService
+	logService            LogService
+	styles                *style.Registry
+	store                 *config.Store
 }
 
 // NewOrchestratorService 创建编排服务
@@ -35,63 +79,207 @@ func NewOrchestratorService(
 	sessionService SessionService,
 	productService ProductService,
 	profileService ProfileService,
+	recommendationService RecommendationService,
+	comparisonService ComparisonService,
+	groundingService GroundingService,
+	enrichmentService ProfileEnrichmentService,
+	quotaService QuotaService,
+	logService LogService,
+	styles *style.Registry,
+	store *config.Store,
 ) OrchestratorService {
 	return &orchestratorService{
-		plannerService:  plannerService,
-		executorService: executorService,
-		sessionService:  sessionService,
-		productService:  productService,
-		profileService:  profileService,
+		plannerService:        plannerService,
+		executorService:       executorService,
+		sessionService:        sessionService,
+		productService:        productService,
+		profileService:        profileService,
+		recommendationService: recommendationService,
+		comparisonService:     comparisonService,
+		groundingService:      groundingService,
+		enrichmentService:     enrichmentService,
+		quotaService:          quotaService,
+		logService:            logService,
+		styles:                styles,
+		store:                 store,
 	}
 }
 
 // ProcessChat 处理对话
-func (s *orchestratorService) ProcessChat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
-	// TODO: 实现编排逻辑
-	// 步骤：
-	// 1. 获取会话上下文: session := s.sessionService.GetSession(...)
-	// 2. 获取商品库: productStorage := s.productService.GetProductStorage(...)
-	// 3. 调用Planner: plannerResult := s.plannerService.Analyze(...)
-	// 4. 调用Executor: executorResult := s.executorService.Execute(...)
-	// 5. 保存会话: s.sessionService.SaveSession(...)
-	// 6. 返回响应
+func (s *orchestratorService) ProcessChat(ctx context.Context, req *model.ChatRequest) (resp *model.ChatResponse, err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "OrchestratorService.ProcessChat")
+	defer func() { observeChatTurn(span, start, resp, err) }()
+
+	turn, ctx, err := s.prepare(ctx, req, start)
+	if err != nil {
+		return nil, err
+	}
+
+	executorResult, err := s.executorService.Execute(ctx, turn.executorReq)
+	if err != nil {
+		return nil, fmt.Errorf("executor execute failed: %w", err)
+	}
+	return s.finish(ctx, turn, executorResult), nil
+}
+
+// ProcessChatStream 流式处理对话
+func (s *orchestratorService) ProcessChatStream(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "OrchestratorService.ProcessChatStream")
+
+	turn, ctx, err := s.prepare(ctx, req, start)
+	if err != nil {
+		observeChatTurn(span, start, nil, err)
+		return nil, err
+	}
+
+	ch := make(chan model.StreamChunk)
+	send := func(event string, data interface{}) bool {
+		select {
+		case ch <- model.StreamChunk{Event: event, Data: data}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	go func() {
+		var (
+			resp *model.ChatResponse
+			err  error
+		)
+		defer close(ch)
+		defer func() { observeChatTurn(span, start, resp, err) }()
+		// 推送中途 panic 时补发 error 事件后结束流
+		defer panics.Recover(ctx, panics.SourceChatStream, func(any) {
+			err = errors.New("chat stream panicked")
+			send(model.StreamEventError, apperr.New(apperr.KindInternal, "internal server error"))
+		})
+
+		if !send(model.StreamEventPlanner, map[string]string{"tool": turn.executorReq.Tool}) {
+			err = ctx.Err()
+			return
+		}
+		executorResult, execErr := s.executorService.ExecuteStream(ctx, turn.executorReq, func(text string) {
+			send(model.StreamEventChunk, text)
+		})
+		if execErr != nil {
+			err = fmt.Errorf("executor execute failed: %w", execErr)
+			send(model.StreamEventError, err)
+			return
+		}
+
+		// 工作流已完成并消耗 tokens，客户端此时断开也要记录用量与日志
+		resp = s.finish(context.WithoutCancel(ctx), turn, executorResult)
+		if len(resp.RecommendedProducts) > 0 && !send(model.StreamEventProducts, resp.RecommendedProducts) {
+			return
+		}
+		send(model.StreamEventDone, &model.StreamDone{
+			SessionID: resp.SessionID,
+			RequestID: resp.Metadata.RequestID,
+			ToolUsed:  resp.ToolUsed,
+			Response:  resp.Response,
+			Metadata:  resp.Metadata,
+		})
+	}()
+	return ch, nil
+}
+
+// observeChatTurn 记录一轮对话的指标与 span 属性并结束 span
+func observeChatTurn(span trace.Span, start time.Time, resp *model.ChatResponse, err error) {
+	if err != nil {
+		metrics.ChatTurnFailures.WithLabelValues(chatFailureReason(err)).Inc()
+	}
+	if resp != nil {
+		metrics.ChatTurnDuration.WithLabelValues(resp.ToolUsed).Observe(time.Since(start).Seconds())
+		span.SetAttributes(
+			tracing.AttrSessionID.String(resp.SessionID),
+			tracing.AttrTool.String(resp.ToolUsed),
+			tracing.AttrTokensUsed.Int(resp.Metadata.TokensUsed),
+		)
+		if resp.Metadata.Quota != nil {
+			span.SetAttributes(tracing.AttrQuotaLevel.String(resp.Metadata.Quota.Level))
+		}
+	}
+	tracing.End(span, err)
+}
+
+// prepare 调用 Executor 前的步骤：校验身份与会话归属、检查配额、识别对比意图、调用 Planner、确定画像与风格
+// 返回的 context 带有会话ID日志字段
+func (s *orchestratorService) prepare(ctx context.Context, req *model.ChatRequest, start time.Time) (*chatTurn, context.Context, error) {
+	span := trace.SpanFromContext(ctx)
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, ctx, ErrUnauthenticated
+	}
+
 	session, err := s.sessionService.GetSession(ctx, req.SessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return nil, ctx, fmt.Errorf("failed to get session: %w", err)
 	}
 
 	if session == nil {
 		session, err = s.sessionService.CreateSession(ctx, &model.SessionCreateRequest{
-			UserID: req.UserID,
+			UserID:     principal.UserID,
+			MerchantID: principal.MerchantID,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to create session: %w", err)
+			return nil, ctx, fmt.Errorf("failed to create session: %w", err)
 		}
+	} else if session.UserID != principal.UserID {
+		return nil, ctx, apperr.Wrap(apperr.KindForbidden, "session belongs to another user", fmt.Errorf("%w: session %s", ErrForbidden, session.SessionID))
 	}
+	ctx = logger.With(ctx, "session_id", session.SessionID)
+	log := logger.FromContext(ctx)
 
-	plannerResult, err := s.plannerService.Analyze(ctx, &PlannerRequest{
-		Query:     req.Query,
-		History:   session.Messages,
-		SessionID: session.SessionID,
-		UserID:    session.UserID,
-	})
+	// 调用大模型前检查 token 配额：超出时拒绝，接近上限时切换到低成本模式；配额服务异常时不拦截
+	quotaSubject := &QuotaSubject{UserID: session.UserID, MerchantID: session.MerchantID}
+	if principal.Guest {
+		// 访客ID可随意更换，按客户端IP计量
+		quotaSubject.GuestIP = requestctx.ClientIP(ctx)
+	}
+	quotaStatus, err := s.quotaService.Check(ctx, quotaSubject)
 	if err != nil {
-		return nil, fmt.Errorf("failed to analyze: %w", err)
+		log.Warn("failed to check token quota, request allowed", "error", err)
+	} else if quotaStatus.Level == model.QuotaLevelExceeded {
+		return nil, ctx, ErrQuotaExceeded
 	}
+	degraded := quotaStatus != nil && quotaStatus.Level == model.QuotaLevelDegraded
+	span.SetAttributes(tracing.AttrDegraded.Bool(degraded))
 
-	session.UserID = "user_001"
-
-	userProfile, err := s.profileService.GetProfile(ctx, session.UserID)
+	// 问题提及两个以上商品库商品且带有对比意图时走商品对比，避免模型凭记忆作答；先于 Planner 检测，命中时省去 Planner 调用
+	comparison, err := s.comparisonService.Detect(ctx, req.Query)
 	if err != nil {
-		// 如果获取失败，使用默认画像
-		fmt.Printf("⚠️  Failed to get user profile: %v, using default profile\n", err)
-		userProfile = &model.UserProfile{
-			UserID:         session.UserID,
-			PreferredStyle: "xiaohongshu",
-			Age:            25,
-			Gender:         "unknown",
-			Interests:      "[]", // 空的 JSON 数组
+		log.Warn("failed to detect comparison", "error", err)
+	}
+
+	// 低成本模式跳过 Planner，直接使用配置的 Executor；对比表仍随请求传入，由该 Executor 参考
+	var plannerResult *model.PlannerResult
+	switch {
+	case degraded:
+		tool := s.cfg().Business.Quota.DegradedTool
+		if tool == "" {
+			tool = model.ToolQAAssistant
 		}
+		plannerResult = &model.PlannerResult{Tool: tool}
+	case comparison != nil:
+		plannerResult = &model.PlannerResult{Tool: model.ToolProductComparison}
+	default:
+		plannerResult, err = s.plannerService.Analyze(ctx, &PlannerRequest{
+			Query:     req.Query,
+			History:   session.Messages,
+			SessionID: session.SessionID,
+			UserID:    session.UserID,
+		})
+		if err != nil {
+			return nil, ctx, fmt.Errorf("failed to analyze: %w", err)
+		}
+	}
+
+	userProfile := s.loadProfile(ctx, principal)
+	responseStyle := s.styles.Resolve(session.Style, userProfile.PreferredStyle, s.styles.MerchantDefault(session.MerchantID))
+	if degraded {
+		responseStyle = responseStyle.WithMaxChars(s.cfg().Business.Quota.DegradedMaxChars)
 	}
 
 	// 根据Planner结果选择对应的Executor
@@ -102,17 +290,151 @@ func (s *orchestratorService) ProcessChat(ctx context.Context, req *model.ChatRe
 		History:             session.Messages,
 		BusinessInstruction: session.BusinessInstruction,
 		UserID:              session.UserID,
+		Debug:               req.Debug,
+		Style:               responseStyle,
+		Brief:               degraded,
+		Comparison:          comparison,
 	}
 
-	executorResult, err := s.executorService.Execute(ctx, executorReq)
+	return &chatTurn{
+		start:         start,
+		req:           req,
+		principal:     principal,
+		session:       session,
+		quotaSubject:  quotaSubject,
+		quotaStatus:   quotaStatus,
+		plannerResult: plannerResult,
+		executorReq:   executorReq,
+		style:         responseStyle,
+	}, ctx, nil
+}
+
+// finish Executor 返回后的步骤：记录 tokens、校验商品、按风格处理回复、记录推荐、写对话日志并提交画像补全
+func (s *orchestratorService) finish(ctx context.Context, turn *chatTurn, executorResult *model.ExecutorResult) *model.ChatResponse {
+	log := logger.FromContext(ctx)
+	session, plannerResult := turn.session, turn.plannerResult
+
+	// 记录本轮 Planner 与 Executor 消耗的 tokens
+	quotaStatus := turn.quotaStatus
+	executorTokens, _ := executorResult.Metadata["tokens_used"].(int)
+	tokensUsed := plannerResult.TokensUsed + executorTokens
+	if recorded, err := s.quotaService.Record(ctx, turn.quotaSubject, tokensUsed); err != nil {
+		log.Warn("failed to record token usage", "tokens", tokensUsed, "error", err)
+	} else {
+		quotaStatus = recorded
+	}
+
+	// 校验回复中的商品与报价，防止出现商品库中不存在的商品或编造的价格
+	groundingReport, err := s.groundingService.Ground(ctx, executorResult)
 	if err != nil {
-		return nil, fmt.Errorf("executor execute failed: %w", err)
+		log.Warn("failed to ground executor result", "error", err)
+	}
+
+	// 按风格的 emoji 与长度策略处理回复
+	executorResult.Response = turn.style.Apply(executorResult.Response)
+
+	// 记录推荐商品，供前端回传行为做转化统计；记录失败不影响本轮回复
+	recommendedProducts := executorResult.RecommendedProducts
+	if len(recommendedProducts) > 0 {
+		promptVersion, _ := executorResult.Metadata["prompt_version"].(string)
+		recorded, err := s.recommendationService.Record(ctx, &RecordRecommendationRequest{
+			SessionID:     session.SessionID,
+			UserID:        session.UserID,
+			Tool:          turn.executorReq.Tool,
+			PromptVersion: promptVersion,
+			Products:      recommendedProducts,
+		})
+		if err != nil {
+			log.Warn("failed to record recommendations", "error", err)
+		} else {
+			recommendedProducts = recorded
+		}
 	}
 
-	return &model.ChatResponse{
+	resp := &model.ChatResponse{
+		SessionID:           session.SessionID,
+		Response:            executorResult.Response,
+		ToolUsed:            turn.executorReq.Tool,
+		RecommendedProducts: recommendedProducts,
+	}
+	resp.Metadata.PlannerResult = *plannerResult
+	resp.Metadata.RequestID = requestctx.RequestID(ctx)
+	resp.Metadata.LatencyMs = time.Since(turn.start).Milliseconds()
+	resp.Metadata.TokensUsed = tokensUsed
+	resp.Metadata.Quota = quotaStatus
+	resp.Metadata.Grounding = groundingReport
+	resp.Metadata.Style = turn.style.Name
+	if table, ok := executorResult.Metadata["comparison_table"].(*model.ComparisonTable); ok {
+		resp.Metadata.Comparison = table
+	}
+	if breakdowns, ok := executorResult.Metadata["ranking"].([]model.ScoreBreakdown); ok {
+		resp.Metadata.Ranking = breakdowns
+	}
+
+	s.logService.RecordChat(ctx, &model.ChatLog{
 		SessionID: session.SessionID,
-		Response:  executorResult.Response,
-		ToolUsed:  plannerResult.Tool,
-	}, nil
+		UserID:    session.UserID,
+		Query:     turn.req.Query,
+		Response:  resp.Response,
+		ToolUsed:  resp.ToolUsed,
+		PlannerResult: map[string]interface{}{
+			"tool":        plannerResult.Tool,
+			"tokens_used": plannerResult.TokensUsed,
+		},
+		ExecutorResult:      executorResult.Metadata,
+		RecommendedProducts: recommendedProducts,
+		LatencyMs:           int(resp.Metadata.LatencyMs),
+		TokensUsed:          tokensUsed,
+		CreatedAt:           turn.start,
+	})
+
+	// 异步从本轮对话中补全用户画像，不阻塞回复；访客没有持久化画像
+	if !turn.principal.Guest {
+		s.enrichmentService.Submit(&EnrichRequest{
+			UserID:    session.UserID,
+			SessionID: session.SessionID,
+			Query:     turn.req.Query,
+			History:   session.Messages,
+		})
+	}
+
+	return resp
+}
+
+// chatFailureReason 对话失败原因，用于指标标签
+func chatFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return "unauthenticated"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrQuotaExceeded):
+		return "quota_exceeded"
+	default:
+		return "error"
+	}
+}
+
+// loadProfile 加载调用方画像，访客或画像获取失败时使用默认画像
+// 默认画像不设置偏好风格，由 Resolve 按商家默认、全局默认兜底
+func (s *orchestratorService) loadProfile(ctx context.Context, principal *auth.Principal) *model.UserProfile {
+	if !principal.Guest {
+		profile, err := s.profileService.GetProfile(ctx, principal.UserID)
+		if err == nil {
+			return profile
+		}
+		if !errors.Is(err, ErrProfileNotFound) {
+			logger.FromContext(ctx).Warn("failed to get user profile, using default profile", "error", err)
+		}
+	}
+	return &model.UserProfile{
+		UserID:    principal.UserID,
+		Gender:    "unknown",
+		Interests: []string{},
+	}
+}
 
+// cfg 当前生效的配置
+func (s *orchestratorService) cfg() *config.Config {
+	return s.store.Get()
 }
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/requestctx"
+	"shopping-guide-backend/internal/style"
+)
+
+// fakePlanner 记录调用次数，返回固定结果
+type fakePlanner struct {
+	mu     sync.Mutex
+	calls  int
+	result *model.PlannerResult
+}
+
+func (p *fakePlanner) Analyze(ctx context.Context, req *PlannerRequest) (*model.PlannerResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return p.result, nil
+}
+
+// fakeExecutor 记录收到的请求，返回固定回复；流式执行时按 chunks 推送，streamErr 非空时推送后失败
+type fakeExecutor struct {
+	mu        sync.Mutex
+	reqs      []*ExecutorRequest
+	chunks    []string
+	products  []model.RecommendedProduct
+	streamErr error
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, req *ExecutorRequest) (*model.ExecutorResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reqs = append(e.reqs, req)
+	return &model.ExecutorResult{
+		Response:            "推荐山地自行车X1。",
+		RecommendedProducts: e.products,
+		Metadata:            map[string]interface{}{"tokens_used": 30},
+	}, nil
+}
+
+func (e *fakeExecutor) ExecuteStream(ctx context.Context, req *ExecutorRequest, onText func(text string)) (*model.ExecutorResult, error) {
+	e.mu.Lock()
+	e.reqs = append(e.reqs, req)
+	e.mu.Unlock()
+	for _, text := range e.chunks {
+		onText(text)
+	}
+	if e.streamErr != nil {
+		return nil, e.streamErr
+	}
+	return &model.ExecutorResult{
+		Response:            strings.Join(e.chunks, ""),
+		RecommendedProducts: e.products,
+		Metadata:            map[string]interface{}{"tokens_used": 30},
+	}, nil
+}
+
+// fakeSessionService 内存会话
+type fakeSessionService struct {
+	mu       sync.Mutex
+	sessions map[string]*model.Session
+}
+
+func (s *fakeSessionService) CreateSession(ctx context.Context, req *model.SessionCreateRequest) (*model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session := &model.Session{SessionID: "sess-new", UserID: req.UserID, MerchantID: req.MerchantID}
+	s.sessions[session.SessionID] = session
+	return session, nil
+}
+
+func (s *fakeSessionService) GetSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[sessionID], nil
+}
+
+func (s *fakeSessionService) SaveSession(ctx context.Context, session *model.Session) error {
+	return nil
+}
+
+func (s *fakeSessionService) DeleteSession(ctx context.Context, sessionID string) error {
+	return nil
+}
+
+// fakeQuotaService 返回固定配额等级，累计记录的 tokens
+type fakeQuotaService struct {
+	QuotaService
+	level    string
+	mu       sync.Mutex
+	recorded int
+}
+
+func (q *fakeQuotaService) Check(ctx context.Context, subject *QuotaSubject) (*model.QuotaStatus, error) {
+	return &model.QuotaStatus{Level: q.level}, nil
+}
+
+func (q *fakeQuotaService) Record(ctx context.Context, subject *QuotaSubject, tokens int) (*model.QuotaStatus, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.recorded += tokens
+	return &model.QuotaStatus{Level: q.level}, nil
+}
+
+type fakeProfileService struct{ ProfileService }
+
+func (p *fakeProfileService) GetProfile(ctx context.Context, userID string) (*model.UserProfile, error) {
+	return nil, ErrProfileNotFound
+}
+
+type fakeRecommendationService struct{ RecommendationService }
+
+func (r *fakeRecommendationService) Record(ctx context.Context, req *RecordRecommendationRequest) ([]model.RecommendedProduct, error) {
+	return req.Products, nil
+}
+
+type fakeGroundingService struct{}
+
+func (g *fakeGroundingService) Ground(ctx context.Context, result *model.ExecutorResult) (*model.GroundingReport, error) {
+	return &model.GroundingReport{}, nil
+}
+
+type fakeEnrichmentService struct{ ProfileEnrichmentService }
+
+func (e *fakeEnrichmentService) Submit(req *EnrichRequest) {}
+
+type fakeLogService struct{ LogService }
+
+func (l *fakeLogService) RecordChat(ctx context.Context, log *model.ChatLog) {}
+
+// orchestratorHarness 编排服务及其依赖的替身
+type orchestratorHarness struct {
+	svc      OrchestratorService
+	planner  *fakePlanner
+	executor *fakeExecutor
+	sessions *fakeSessionService
+	quota    *fakeQuotaService
+}
+
+func newOrchestratorHarness(t *testing.T) *orchestratorHarness {
+	t.Helper()
+	stylePath := filepath.Join(t.TempDir(), "styles.yaml")
+	if err := os.WriteFile(stylePath, []byte("styles:\n  plain:\n    display_name: 朴素\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	styles, err := style.Load(stylePath, "plain")
+	if err != nil {
+		t.Fatalf("style.Load() error = %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Business.Quota.DegradedTool = model.ToolQAAssistant
+	cfg.Business.Quota.DegradedMaxChars = 200
+
+	products, _ := newTestProductService(t, &fakeProductRepo{products: bikeCatalog()}, 0)
+	h := &orchestratorHarness{
+		planner:  &fakePlanner{result: &model.PlannerResult{Tool: model.ToolProductRecommendation, TokensUsed: 10}},
+		executor: &fakeExecutor{},
+		sessions: &fakeSessionService{sessions: map[string]*model.Session{
+			"sess-1": {SessionID: "sess-1", UserID: "user_1"},
+		}},
+		quota: &fakeQuotaService{level: model.QuotaLevelNormal},
+	}
+	h.svc = NewOrchestratorService(
+		h.planner, h.executor, h.sessions, products, &fakeProfileService{}, &fakeRecommendationService{},
+		NewComparisonService(products), &fakeGroundingService{}, &fakeEnrichmentService{}, h.quota,
+		&fakeLogService{}, styles, config.NewStore(cfg),
+	)
+	return h
+}
+
+func userContext(userID string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{UserID: userID})
+}
+
+func TestOrchestratorProcessChatRouting(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		quotaLevel      string
+		wantPlanner     int
+		wantTool        string
+		wantComparison  bool
+		wantTokensTotal int
+	}{
+		{name: "planner decides", query: "推荐一辆通勤自行车", quotaLevel: model.QuotaLevelNormal,
+			wantPlanner: 1, wantTool: model.ToolProductRecommendation, wantTokensTotal: 40},
+		{name: "comparison skips planner", query: "山地自行车X1和通勤自行车C1哪个好", quotaLevel: model.QuotaLevelNormal,
+			wantPlanner: 0, wantTool: model.ToolProductComparison, wantComparison: true, wantTokensTotal: 30},
+		{name: "degraded skips planner", query: "推荐一辆通勤自行车", quotaLevel: model.QuotaLevelDegraded,
+			wantPlanner: 0, wantTool: model.ToolQAAssistant, wantTokensTotal: 30},
+		{name: "degraded comparison keeps degraded tool", query: "X1 vs C1", quotaLevel: model.QuotaLevelDegraded,
+			wantPlanner: 0, wantTool: model.ToolQAAssistant, wantComparison: true, wantTokensTotal: 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newOrchestratorHarness(t)
+			h.quota.level = tt.quotaLevel
+
+			resp, err := h.svc.ProcessChat(userContext("user_1"), &model.ChatRequest{SessionID: "sess-1", Query: tt.query})
+			if err != nil {
+				t.Fatalf("ProcessChat() error = %v", err)
+			}
+			if h.planner.calls != tt.wantPlanner {
+				t.Errorf("planner calls = %d, want %d", h.planner.calls, tt.wantPlanner)
+			}
+			if resp.ToolUsed != tt.wantTool || h.executor.reqs[0].Tool != tt.wantTool {
+				t.Errorf("tool = %s (executor %s), want %s", resp.ToolUsed, h.executor.reqs[0].Tool, tt.wantTool)
+			}
+			if got := h.executor.reqs[0].Comparison != nil; got != tt.wantComparison {
+				t.Errorf("comparison attached = %v, want %v", got, tt.wantComparison)
+			}
+			if h.executor.reqs[0].Brief != (tt.quotaLevel == model.QuotaLevelDegraded) {
+				t.Errorf("brief = %v in %s mode", h.executor.reqs[0].Brief, tt.quotaLevel)
+			}
+			if resp.Metadata.TokensUsed != tt.wantTokensTotal {
+				t.Errorf("tokens_used = %d, want %d", resp.Metadata.TokensUsed, tt.wantTokensTotal)
+			}
+		})
+	}
+}
+
+func TestOrchestratorProcessChatRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		sessionID  string
+		quotaLevel string
+		wantErr    error
+		wantKind   apperr.Kind
+	}{
+		{name: "no principal", ctx: context.Background(), sessionID: "sess-1", quotaLevel: model.QuotaLevelNormal,
+			wantErr: ErrUnauthenticated, wantKind: apperr.KindUnauthorized},
+		{name: "another user's session", ctx: userContext("user_2"), sessionID: "sess-1", quotaLevel: model.QuotaLevelNormal,
+			wantErr: ErrForbidden, wantKind: apperr.KindForbidden},
+		{name: "quota exceeded", ctx: userContext("user_1"), sessionID: "sess-1", quotaLevel: model.QuotaLevelExceeded,
+			wantErr: ErrQuotaExceeded, wantKind: apperr.KindRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newOrchestratorHarness(t)
+			h.quota.level = tt.quotaLevel
+
+			_, err := h.svc.ProcessChat(tt.ctx, &model.ChatRequest{SessionID: tt.sessionID, Query: "推荐一辆自行车"})
+			if !errors.Is(err, tt.wantErr) || apperr.KindOf(err) != tt.wantKind {
+				t.Errorf("ProcessChat() error = %v (kind %v), want %v", err, apperr.KindOf(err), tt.wantErr)
+			}
+			if h.planner.calls != 0 || len(h.executor.reqs) != 0 {
+				t.Errorf("planner calls = %d, executor calls = %d, want none", h.planner.calls, len(h.executor.reqs))
+			}
+		})
+	}
+}
+
+func TestOrchestratorProcessChatCreatesSession(t *testing.T) {
+	h := newOrchestratorHarness(t)
+
+	resp, err := h.svc.ProcessChat(userContext("user_1"), &model.ChatRequest{Query: "推荐一辆自行车"})
+	if err != nil {
+		t.Fatalf("ProcessChat() error = %v", err)
+	}
+	if resp.SessionID != "sess-new" || h.sessions.sessions["sess-new"].UserID != "user_1" {
+		t.Errorf("session = %s, want new session owned by user_1", resp.SessionID)
+	}
+}
+
+// collectStream 读取流式对话的全部事件
+func collectStream(t *testing.T, stream <-chan model.StreamChunk) []model.StreamChunk {
+	t.Helper()
+	var events []model.StreamChunk
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatalf("stream not closed, events so far: %+v", events)
+		}
+	}
+}
+
+func streamEventNames(events []model.StreamChunk) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.Event
+	}
+	return names
+}
+
+func TestOrchestratorProcessChatStream(t *testing.T) {
+	h := newOrchestratorHarness(t)
+	h.executor.chunks = []string{"推荐山地", "自行车X1。"}
+	h.executor.products = []model.RecommendedProduct{{ProductID: "bike-001", Name: "山地自行车X1", Price: 1299}}
+	ctx := requestctx.WithMetadata(userContext("user_1"), requestctx.New("req-1", ""))
+
+	stream, err := h.svc.ProcessChatStream(ctx, &model.ChatRequest{SessionID: "sess-1", Query: "推荐一辆通勤自行车"})
+	if err != nil {
+		t.Fatalf("ProcessChatStream() error = %v", err)
+	}
+	events := collectStream(t, stream)
+
+	want := []string{model.StreamEventPlanner, model.StreamEventChunk, model.StreamEventChunk, model.StreamEventProducts, model.StreamEventDone}
+	if got := streamEventNames(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if tool := events[0].Data.(map[string]string)["tool"]; tool != model.ToolProductRecommendation {
+		t.Errorf("planner tool = %s", tool)
+	}
+	if events[1].Data != "推荐山地" {
+		t.Errorf("first chunk = %v", events[1].Data)
+	}
+	done := events[4].Data.(*model.StreamDone)
+	if done.SessionID != "sess-1" || done.RequestID != "req-1" || done.ToolUsed != model.ToolProductRecommendation ||
+		done.Response != "推荐山地自行车X1。" || done.Metadata.TokensUsed != 40 {
+		t.Errorf("done = %+v", done)
+	}
+	if h.planner.calls != 1 || h.quota.recorded != 40 {
+		t.Errorf("planner calls = %d, recorded tokens = %d", h.planner.calls, h.quota.recorded)
+	}
+}
+
+func TestOrchestratorProcessChatStreamErrors(t *testing.T) {
+	h := newOrchestratorHarness(t)
+
+	// 调用 Executor 前的错误直接返回，不建立流
+	if _, err := h.svc.ProcessChatStream(userContext("user_2"), &model.ChatRequest{SessionID: "sess-1", Query: "推荐"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("ProcessChatStream() error = %v, want ErrForbidden", err)
+	}
+	h.quota.level = model.QuotaLevelExceeded
+	if _, err := h.svc.ProcessChatStream(userContext("user_1"), &model.ChatRequest{SessionID: "sess-1", Query: "推荐"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("ProcessChatStream() error = %v, want ErrQuotaExceeded", err)
+	}
+	if len(h.executor.reqs) != 0 {
+		t.Fatalf("executor called %d times for rejected requests", len(h.executor.reqs))
+	}
+
+	// 流中途失败推送带类别的错误后结束，不记录用量
+	h.quota.level = model.QuotaLevelNormal
+	h.executor.chunks = []string{"推荐"}
+	h.executor.streamErr = apperr.Wrap(apperr.KindUpstreamTimeout, "assistant service timed out", context.DeadlineExceeded)
+	stream, err := h.svc.ProcessChatStream(userContext("user_1"), &model.ChatRequest{SessionID: "sess-1", Query: "推荐"})
+	if err != nil {
+		t.Fatalf("ProcessChatStream() error = %v", err)
+	}
+	events := collectStream(t, stream)
+	want := []string{model.StreamEventPlanner, model.StreamEventChunk, model.StreamEventError}
+	if got := streamEventNames(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if err, ok := events[2].Data.(error); !ok || !apperr.Is(err, apperr.KindUpstreamTimeout) {
+		t.Errorf("error event = %v, want upstream timeout", events[2].Data)
+	}
+	if h.quota.recorded != 0 {
+		t.Errorf("recorded tokens = %d after failed stream", h.quota.recorded)
+	}
+}
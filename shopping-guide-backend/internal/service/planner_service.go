@@ -5,9 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"shopping-guide-backend/internal/client"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/metrics"
 	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/tracing"
 )
 
+// workflowPlanner Planner 工作流名称，用于 Dify 调用日志
+const workflowPlanner = "planner"
+
 // PlannerService Planner规划器服务（Master Agent）
 // 职责：意图识别、Tool选择、执行策略规划
 type PlannerService interface {
@@ -26,32 +34,52 @@ type PlannerRequest struct {
 
 // plannerService Planner服务实现
 type plannerService struct {
-	// TODO: 注入DifyClient
-	// TODO: 注入LogRepository
+	difyClient client.DifyClient
+	store      *config.Store
 }
 
 // NewPlannerService 创建Planner服务
-func NewPlannerService() PlannerService {
-	return &plannerService{}
+func NewPlannerService(difyClient client.DifyClient, store *config.Store) PlannerService {
+	return &plannerService{
+		difyClient: difyClient,
+		store:      store,
+	}
 }
 
 // Analyze 分析并规划
-func (s *plannerService) Analyze(ctx context.Context, req *PlannerRequest) (*model.PlannerResult, error) {
+func (s *plannerService) Analyze(ctx context.Context, req *PlannerRequest) (plan *model.PlannerResult, err error) {
+	ctx, span := tracing.Start(ctx, "PlannerService.Analyze")
+	defer func() {
+		if plan != nil {
+			metrics.PlannerToolTotal.WithLabelValues(plannerToolLabel(plan.Tool)).Inc()
+			span.SetAttributes(tracing.AttrTool.String(plan.Tool), tracing.AttrTokensUsed.Int(plan.TokensUsed))
+		}
+		tracing.End(span, err)
+	}()
+
 	// 调用Dify Planner工作流
-	difyreq := model.DifyWorkflowRequest{
-		Inputs: map[string]interface{}{
-			"query": req.Query,
-		},
-		ResponseMode: "blocking", // 使用 blocking 模式便于调试
-		User:         req.UserID,
-	}
+	workflow := s.cfg().Dify.Workflows.Planner
+	callCtx, cancel := withWorkflowTimeout(ctx, workflowPlanner, workflow)
+	defer cancel()
 
-	difyresp, err := model.PlannerChat(ctx, difyreq)
+	inputs := map[string]interface{}{
+		"query": req.Query,
+	}
+	result, err := s.difyClient.CallWorkflow(callCtx, workflow.AppID, inputs, req.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call planner chat: %w", err)
+		return nil, fmt.Errorf("failed to call planner workflow: %w", err)
+	}
+
+	// Planner 通常通过 text 字段输出，其次是 result 字段
+	difyresp, ok := result.Data.Outputs["text"].(string)
+	if !ok {
+		if difyresp, err = workflowOutputText(result.Data.Outputs); err != nil {
+			return nil, err
+		}
 	}
 
-	fmt.Printf("\n📝 Planner raw response: %s\n", difyresp)
+	log := logger.FromContext(ctx)
+	log.Debug("planner raw response", "output", difyresp, "tokens", result.Data.TotalTokens)
 
 	// Dify 返回的可能是 JSON 数组，如 ["SHOPPING_GUIDE_AND_INTENT_MINING_MODULE"]
 	// 需要先解析成数组，再提取第一个元素
@@ -59,7 +87,8 @@ func (s *plannerService) Analyze(ctx context.Context, req *PlannerRequest) (*mod
 	if err := json.Unmarshal([]byte(difyresp), &tools); err != nil {
 		// 如果不是数组，可能是直接返回的字符串，尝试作为单个 tool 处理
 		return &model.PlannerResult{
-			Tool: difyresp,
+			Tool:       difyresp,
+			TokensUsed: result.Data.TotalTokens,
 		}, nil
 	}
 
@@ -68,10 +97,26 @@ func (s *plannerService) Analyze(ctx context.Context, req *PlannerRequest) (*mod
 	}
 
 	plannerResult := &model.PlannerResult{
-		Tool: tools[0], // 取第一个 tool
+		Tool:       tools[0], // 取第一个 tool
+		TokensUsed: result.Data.TotalTokens,
 	}
 
-	fmt.Printf("✅ Planner result: tool=%s\n\n", plannerResult.Tool)
+	log.Debug("planner result", "tool", plannerResult.Tool)
 
 	return plannerResult, nil
 }
+
+// plannerToolLabel Planner 输出的工具作为指标标签，模型输出不在已知工具中时记为 other，避免标签基数失控
+func plannerToolLabel(tool string) string {
+	switch tool {
+	case model.ToolProductRecommendation, model.ToolShoppingGuide, model.ToolQAAssistant:
+		return tool
+	default:
+		return "other"
+	}
+}
+
+// cfg 当前生效的配置
+func (s *plannerService) cfg() *config.Config {
+	return s.store.Get()
+}
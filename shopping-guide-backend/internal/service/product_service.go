@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/panics"
+	"shopping-guide-backend/internal/repository"
+	"shopping-guide-backend/internal/search"
+)
+
+// maxSearchTopK 单次检索返回的商品数上限
+const maxSearchTopK = 100
+
+// productService 商品服务实现
+// 上架商品全量加载到内存，同时维护 BM25 索引和向量索引，支持关键词/语义/混合检索
+// 索引超过 search.refresh_interval 后在后台刷新，也可通过管理接口立即重建
+type productService struct {
+	repo     repository.ProductRepository
+	embedder search.Embedder
+	store    *config.Store
+
+	// rebuildMu 串行化索引重建：并发的首次检索只构建一次，向量索引文件也只有一个写入方
+	rebuildMu sync.Mutex
+
+	mu          sync.RWMutex
+	loaded      bool
+	refreshedAt time.Time // 最近一次构建或刷新的时间，刷新失败同样更新，避免每次检索都触发重建
+	products    map[string]model.Product
+	keyword     *search.BM25Index
+	vectors     *search.VectorIndex
+	matcher     *search.NameMatcher
+}
+
+// NewProductService 创建商品服务
+func NewProductService(
+	repo repository.ProductRepository,
+	embedder search.Embedder,
+	store *config.Store,
+) ProductService {
+	return &productService{
+		repo:     repo,
+		embedder: embedder,
+		store:    store,
+	}
+}
+
+// GetProduct 获取商品详情
+func (s *productService) GetProduct(ctx context.Context, productID string) (*model.Product, error) {
+	return s.repo.GetByID(ctx, productID)
+}
+
+// GetProductStorage 获取商品库类目结构
+func (s *productService) GetProductStorage(ctx context.Context) (*model.ProductStorage, error) {
+	return s.repo.GetCategories(ctx)
+}
+
+// SearchProducts 检索商品
+func (s *productService) SearchProducts(ctx context.Context, req *model.ProductSearchRequest) (*model.ProductSearchResponse, error) {
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = s.cfg().Search.Mode
+	}
+	topK := req.TopK
+	if topK <= 0 {
+		topK = s.cfg().TopK
+	}
+	// 接口已限制 top_k，内部调用同样截断，避免召回数量与结果切片过大
+	if topK > maxSearchTopK {
+		topK = maxSearchTopK
+	}
+
+	// 召回数量放大，给类目/价格过滤留出余量
+	recallK := topK * 5
+
+	var hits []search.Hit
+	switch mode {
+	case model.SearchModeKeyword:
+		hits = s.keywordIndex().Search(req.Query, recallK)
+	case model.SearchModeVector:
+		vectorHits, err := s.vectorSearch(ctx, req.Query, recallK)
+		if err != nil {
+			return nil, err
+		}
+		hits = vectorHits
+	case model.SearchModeHybrid, "":
+		mode = model.SearchModeHybrid
+		keywordHits := s.keywordIndex().Search(req.Query, recallK)
+		vectorHits, err := s.vectorSearch(ctx, req.Query, recallK)
+		if err != nil {
+			// 向量化服务异常时退化为关键词检索，不影响推荐与对比
+			logger.FromContext(ctx).Warn("vector search failed, falling back to keyword search", "error", err)
+			mode = model.SearchModeKeyword
+			hits = keywordHits
+			break
+		}
+		hits = search.Blend(keywordHits, vectorHits, s.cfg().Search.HybridAlpha, recallK)
+	default:
+		return nil, fmt.Errorf("unknown search mode: %s", mode)
+	}
+
+	resp := &model.ProductSearchResponse{
+		Products: make([]model.Product, 0, topK),
+		Scores:   make(map[string]float64, topK),
+		Mode:     mode,
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, hit := range hits {
+		p, ok := s.products[hit.ID]
+		if !ok || !matchFilters(&p, req) {
+			continue
+		}
+		resp.Products = append(resp.Products, p)
+		resp.Scores[p.ProductID] = hit.Score
+		if len(resp.Products) >= topK {
+			break
+		}
+	}
+	resp.Total = len(resp.Products)
+
+	return resp, nil
+}
+
+// RebuildIndex 重新加载商品并刷新索引，供管理接口在商品变更后调用
+func (s *productService) RebuildIndex(ctx context.Context) error {
+	s.rebuildMu.Lock()
+	defer s.rebuildMu.Unlock()
+	return s.rebuild(ctx)
+}
+
+// rebuild 重新加载商品并刷新索引，调用方须持有 rebuildMu
+// 向量索引按内容摘要增量更新，只对新增或变更的商品调用向量化接口；
+// 向量化失败时仍更新商品与关键词索引，缺少向量的商品只参与关键词检索，下次刷新时重试
+func (s *productService) rebuild(ctx context.Context) error {
+	products, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	vectors, err := search.LoadVectorIndex(s.cfg().Search.IndexPath, s.embedder.Model())
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]model.Product, len(products))
+	ids := make(map[string]struct{}, len(products))
+	docs := make([]search.Document, 0, len(products))
+	var pending []search.Document
+	for i := range products {
+		p := products[i]
+		doc := search.ProductDocument(&p)
+		byID[p.ProductID] = p
+		ids[p.ProductID] = struct{}{}
+		docs = append(docs, doc)
+
+		if hash, ok := vectors.Hash(p.ProductID); !ok || hash != search.ContentHash(doc.Text) {
+			pending = append(pending, doc)
+		}
+	}
+	vectors.Retain(ids)
+
+	var embedErr error
+	if len(pending) > 0 {
+		texts := make([]string, len(pending))
+		for i, d := range pending {
+			texts[i] = d.Text
+		}
+		embeddings, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			embedErr = apperr.Wrap(apperr.KindUpstreamUnavailable, "embedding service is unavailable", fmt.Errorf("failed to embed products: %w", err))
+		} else {
+			for i, d := range pending {
+				vectors.Upsert(d.ID, search.ContentHash(d.Text), embeddings[i])
+			}
+		}
+	}
+
+	if embedErr == nil && s.cfg().Search.IndexPath != "" {
+		if err := vectors.Save(s.cfg().Search.IndexPath); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.products = byID
+	s.keyword = search.NewBM25Index(docs)
+	s.vectors = vectors
+	s.matcher = search.NewNameMatcher(products)
+	s.loaded = true
+	s.refreshedAt = time.Now()
+	s.mu.Unlock()
+
+	return embedErr
+}
+
+// MatchProducts 识别文本中提及的商品
+func (s *productService) MatchProducts(ctx context.Context, text string) ([]model.ProductMatch, error) {
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := s.matcher.Match(text)
+	result := make([]model.ProductMatch, 0, len(matches))
+	for _, m := range matches {
+		p, ok := s.products[m.ID]
+		if !ok {
+			continue
+		}
+		result = append(result, model.ProductMatch{
+			Product:  p,
+			Mention:  m.Mention,
+			Position: m.Position,
+			Score:    m.Score,
+		})
+	}
+	return result, nil
+}
+
+// ensureIndex 首次检索时构建索引，索引超过刷新间隔时在后台刷新
+func (s *productService) ensureIndex(ctx context.Context) error {
+	s.mu.RLock()
+	loaded, refreshedAt := s.loaded, s.refreshedAt
+	s.mu.RUnlock()
+	if loaded {
+		if interval := s.cfg().Search.RefreshInterval; interval > 0 && time.Since(refreshedAt) >= interval {
+			s.refreshAsync(ctx)
+		}
+		return nil
+	}
+
+	s.rebuildMu.Lock()
+	defer s.rebuildMu.Unlock()
+	// 等锁期间其他请求可能已完成构建
+	if s.isLoaded() {
+		return nil
+	}
+	if err := s.rebuild(ctx); err != nil {
+		if !s.isLoaded() {
+			return err
+		}
+		logger.FromContext(ctx).Warn("product vectors incomplete, affected products use keyword search only", "error", err)
+	}
+	return nil
+}
+
+// refreshAsync 在后台刷新索引，刷新期间继续使用旧索引；已有重建进行中时跳过
+func (s *productService) refreshAsync(ctx context.Context) {
+	if !s.rebuildMu.TryLock() {
+		return
+	}
+	s.mu.Lock()
+	s.refreshedAt = time.Now()
+	s.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer s.rebuildMu.Unlock()
+		defer panics.Recover(ctx, panics.SourceProductIndex, nil)
+		if err := s.rebuild(ctx); err != nil {
+			logger.FromContext(ctx).Warn("failed to refresh product index", "error", err)
+		}
+	}()
+}
+
+func (s *productService) isLoaded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loaded
+}
+
+func (s *productService) keywordIndex() *search.BM25Index {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keyword
+}
+
+func (s *productService) vectorSearch(ctx context.Context, query string, k int) ([]search.Hit, error) {
+	embeddings, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	s.mu.RLock()
+	vectors := s.vectors
+	s.mu.RUnlock()
+
+	return vectors.Search(embeddings[0], k), nil
+}
+
+// matchFilters 类目与过滤条件匹配
+// 支持的过滤条件：sub_category、min_price、max_price、in_stock
+func matchFilters(p *model.Product, req *model.ProductSearchRequest) bool {
+	if req.Category != "" && p.Category != req.Category {
+		return false
+	}
+	if v, ok := req.Filters["sub_category"].(string); ok && v != "" && p.SubCategory != v {
+		return false
+	}
+	if v, ok := req.Filters["min_price"].(float64); ok && p.Price < v {
+		return false
+	}
+	if v, ok := req.Filters["max_price"].(float64); ok && p.Price > v {
+		return false
+	}
+	if v, ok := req.Filters["in_stock"].(bool); ok && v && p.Stock <= 0 {
+		return false
+	}
+	return true
+}
+
+// cfg 当前生效的商品配置
+func (s *productService) cfg() *config.ProductConfig {
+	return &s.store.Get().Business.Product
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/search"
+)
+
+// fakeProductRepo 内存商品库，ListActive 可计数并人为放慢，用于验证并发构建
+type fakeProductRepo struct {
+	mu       sync.Mutex
+	products []model.Product
+	lists    atomic.Int32
+	delay    time.Duration
+}
+
+func (r *fakeProductRepo) GetByID(ctx context.Context, productID string) (*model.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.products {
+		if r.products[i].ProductID == productID {
+			p := r.products[i]
+			return &p, nil
+		}
+	}
+	return nil, errors.New("product not found")
+}
+
+func (r *fakeProductRepo) GetByIDs(ctx context.Context, productIDs []string) ([]model.Product, error) {
+	var products []model.Product
+	for _, id := range productIDs {
+		if p, err := r.GetByID(ctx, id); err == nil {
+			products = append(products, *p)
+		}
+	}
+	return products, nil
+}
+
+func (r *fakeProductRepo) Search(ctx context.Context, req *model.ProductSearchRequest) ([]model.Product, error) {
+	return nil, nil
+}
+
+func (r *fakeProductRepo) GetCategories(ctx context.Context) (*model.ProductStorage, error) {
+	return &model.ProductStorage{}, nil
+}
+
+func (r *fakeProductRepo) ListActive(ctx context.Context) ([]model.Product, error) {
+	r.lists.Add(1)
+	time.Sleep(r.delay)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.Product(nil), r.products...), nil
+}
+
+func (r *fakeProductRepo) add(p model.Product) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.products = append(r.products, p)
+}
+
+// failingEmbedder 可切换失败的向量化实现
+type failingEmbedder struct {
+	search.Embedder
+	fail atomic.Bool
+}
+
+func (e *failingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.fail.Load() {
+		return nil, errors.New("embedding api error: status=500")
+	}
+	return e.Embedder.Embed(ctx, texts)
+}
+
+func testCatalog() []model.Product {
+	return []model.Product{
+		{ProductID: "bottle-001", Name: "运动水壶500ml", Category: "骑行", SubCategory: "水壶", Price: 59, Stock: 100},
+		{ProductID: "light-001", Name: "自行车尾灯", Category: "骑行", SubCategory: "车灯", Price: 89, Stock: 50,
+			Description: "USB充电 夜骑必备"},
+		{ProductID: "helmet-001", Name: "骑行头盔", Category: "骑行", SubCategory: "头盔", Price: 199, Stock: 0},
+	}
+}
+
+func newTestProductService(t *testing.T, repo *fakeProductRepo, refresh time.Duration) (*productService, *failingEmbedder) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Business.Product.TopK = 10
+	cfg.Business.Product.Search = config.ProductSearchConfig{
+		Mode:            model.SearchModeHybrid,
+		HybridAlpha:     0.5,
+		IndexPath:       filepath.Join(t.TempDir(), "vectors.gob"),
+		RefreshInterval: refresh,
+	}
+	embedder := &failingEmbedder{Embedder: search.NewHashEmbedder(64)}
+	return NewProductService(repo, embedder, config.NewStore(cfg)).(*productService), embedder
+}
+
+func TestProductServiceConcurrentFirstSearchBuildsOnce(t *testing.T) {
+	repo := &fakeProductRepo{products: testCatalog(), delay: 20 * time.Millisecond}
+	s, _ := newTestProductService(t, repo, 0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.SearchProducts(context.Background(), &model.ProductSearchRequest{Query: "水壶"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("SearchProducts() error = %v", err)
+		}
+	}
+	if n := repo.lists.Load(); n != 1 {
+		t.Errorf("ListActive called %d times, want 1", n)
+	}
+}
+
+func TestProductServiceSearch(t *testing.T) {
+	repo := &fakeProductRepo{products: testCatalog()}
+	s, embedder := newTestProductService(t, repo, 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		req       *model.ProductSearchRequest
+		embedFail bool
+		wantMode  string
+		wantFirst string
+		wantIDs   []string
+		wantErr   bool
+	}{
+		{name: "hybrid", req: &model.ProductSearchRequest{Query: "水壶"}, wantMode: model.SearchModeHybrid, wantFirst: "bottle-001"},
+		{name: "keyword", req: &model.ProductSearchRequest{Query: "尾灯", Mode: model.SearchModeKeyword}, wantMode: model.SearchModeKeyword, wantIDs: []string{"light-001"}},
+		{name: "vector", req: &model.ProductSearchRequest{Query: "头盔", Mode: model.SearchModeVector}, wantMode: model.SearchModeVector, wantFirst: "helmet-001"},
+		{name: "hybrid falls back to keyword when embedding fails", req: &model.ProductSearchRequest{Query: "尾灯"}, embedFail: true, wantMode: model.SearchModeKeyword, wantIDs: []string{"light-001"}},
+		{name: "vector fails when embedding fails", req: &model.ProductSearchRequest{Query: "尾灯", Mode: model.SearchModeVector}, embedFail: true, wantErr: true},
+		{name: "in stock filter", req: &model.ProductSearchRequest{Query: "头盔", Mode: model.SearchModeKeyword, Filters: map[string]interface{}{"in_stock": true}}, wantMode: model.SearchModeKeyword, wantIDs: []string{}},
+		{name: "price filter", req: &model.ProductSearchRequest{Query: "水壶 尾灯", Mode: model.SearchModeKeyword, Filters: map[string]interface{}{"max_price": 60.0}}, wantMode: model.SearchModeKeyword, wantIDs: []string{"bottle-001"}},
+		{name: "unknown mode", req: &model.ProductSearchRequest{Query: "水壶", Mode: "fuzzy"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder.fail.Store(tt.embedFail)
+			defer embedder.fail.Store(false)
+
+			resp, err := s.SearchProducts(ctx, tt.req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("SearchProducts() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SearchProducts() error = %v", err)
+			}
+			if resp.Mode != tt.wantMode {
+				t.Errorf("mode = %s, want %s", resp.Mode, tt.wantMode)
+			}
+			if tt.wantFirst != "" && (len(resp.Products) == 0 || resp.Products[0].ProductID != tt.wantFirst) {
+				t.Errorf("products = %v, want %s first", productIDs(resp.Products), tt.wantFirst)
+			}
+			if tt.wantIDs != nil {
+				got := productIDs(resp.Products)
+				if len(got) != len(tt.wantIDs) {
+					t.Fatalf("products = %v, want %v", got, tt.wantIDs)
+				}
+				for i := range got {
+					if got[i] != tt.wantIDs[i] {
+						t.Errorf("products = %v, want %v", got, tt.wantIDs)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProductServiceFirstBuildWithoutEmbeddings(t *testing.T) {
+	repo := &fakeProductRepo{products: testCatalog()}
+	s, embedder := newTestProductService(t, repo, 0)
+	embedder.fail.Store(true)
+
+	resp, err := s.SearchProducts(context.Background(), &model.ProductSearchRequest{Query: "尾灯"})
+	if err != nil {
+		t.Fatalf("SearchProducts() error = %v", err)
+	}
+	if resp.Mode != model.SearchModeKeyword || len(resp.Products) != 1 {
+		t.Errorf("resp = %+v, want keyword result", resp)
+	}
+
+	// 管理接口重建时暴露向量化失败
+	if err := s.RebuildIndex(context.Background()); !apperr.Is(err, apperr.KindUpstreamUnavailable) {
+		t.Errorf("RebuildIndex() error = %v, want upstream unavailable", err)
+	}
+
+	embedder.fail.Store(false)
+	if err := s.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex() error = %v", err)
+	}
+	if n := s.vectors.Len(); n != 3 {
+		t.Errorf("vectors = %d, want 3 after recovery", n)
+	}
+}
+
+func TestProductServiceRefresh(t *testing.T) {
+	repo := &fakeProductRepo{products: testCatalog()}
+	s, _ := newTestProductService(t, repo, time.Minute)
+	ctx := context.Background()
+	query := &model.ProductSearchRequest{Query: "露营帐篷", Mode: model.SearchModeKeyword}
+
+	if resp, err := s.SearchProducts(ctx, query); err != nil || len(resp.Products) != 0 {
+		t.Fatalf("SearchProducts() = %v, %v, want no products", resp, err)
+	}
+	repo.add(model.Product{ProductID: "tent-001", Name: "露营帐篷", Category: "户外", Price: 399, Stock: 5})
+
+	// 未到刷新间隔时使用已有索引
+	if resp, _ := s.SearchProducts(ctx, query); len(resp.Products) != 0 {
+		t.Errorf("products = %v before refresh interval, want none", productIDs(resp.Products))
+	}
+
+	// 超过刷新间隔后后台刷新
+	s.mu.Lock()
+	s.refreshedAt = time.Now().Add(-2 * time.Minute)
+	s.mu.Unlock()
+	if _, err := s.SearchProducts(ctx, query); err != nil {
+		t.Fatalf("SearchProducts() error = %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, _ := s.SearchProducts(ctx, query)
+		if len(resp.Products) == 1 && resp.Products[0].ProductID == "tent-001" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("new product not searchable after refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := repo.lists.Load(); n != 2 {
+		t.Errorf("ListActive called %d times, want 2", n)
+	}
+}
+
+func productIDs(products []model.Product) []string {
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ProductID)
+	}
+	return ids
+}
@@ -0,0 +1,483 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/panics"
+	"shopping-guide-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ProfileEnrichmentService 从对话中自动补全用户画像
+// 每轮对话后异步抽取画像事实，按置信度合并到画像，并记录变更历史
+type ProfileEnrichmentService interface {
+	// Submit 提交一轮对话异步处理，队列已满时丢弃
+	Submit(req *EnrichRequest)
+	// Enrich 同步抽取并合并画像
+	Enrich(ctx context.Context, req *EnrichRequest) error
+	// Close 停止接收任务并等待队列中的任务处理完
+	Close()
+}
+
+// EnrichRequest 画像补全请求
+type EnrichRequest struct {
+	UserID    string
+	SessionID string
+	Query     string
+	History   []model.Message
+}
+
+// profileEnrichmentService 画像补全服务实现
+// 任务按 user_id 分配到固定 worker，保证同一用户的补全串行执行
+type profileEnrichmentService struct {
+	repo      repository.ProfileRepository
+	extractor ProfileExtractor
+	store     *config.Store
+
+	mu     sync.RWMutex
+	closed bool
+	queues []chan *EnrichRequest
+	wg     sync.WaitGroup
+}
+
+// NewProfileEnrichmentService 创建画像补全服务并启动后台 worker
+// worker 始终启动，business.enrichment.enabled 可热更新开关；worker 数与队列长度修改后需重启
+func NewProfileEnrichmentService(
+	repo repository.ProfileRepository,
+	extractor ProfileExtractor,
+	store *config.Store,
+) ProfileEnrichmentService {
+	s := &profileEnrichmentService{
+		repo:      repo,
+		extractor: extractor,
+		store:     store,
+	}
+	cfg := s.cfg()
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	queueSize := cfg.QueueSize / workers
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	s.queues = make([]chan *EnrichRequest, workers)
+	for i := range s.queues {
+		s.queues[i] = make(chan *EnrichRequest, queueSize)
+		s.wg.Add(1)
+		go s.worker(s.queues[i])
+	}
+	return s
+}
+
+// Submit 提交异步补全任务
+func (s *profileEnrichmentService) Submit(req *EnrichRequest) {
+	if !s.cfg().Enabled || req.UserID == "" {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(req.UserID))
+	select {
+	case s.queues[h.Sum32()%uint32(len(s.queues))] <- req:
+	default:
+		slog.Warn("profile enrichment queue is full, dropping turn", "user_id", req.UserID, "session_id", req.SessionID)
+	}
+}
+
+// Close 停止接收任务并等待 worker 退出
+func (s *profileEnrichmentService) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, q := range s.queues {
+		close(q)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *profileEnrichmentService) worker(queue <-chan *EnrichRequest) {
+	defer s.wg.Done()
+	for req := range queue {
+		s.process(req)
+	}
+}
+
+// process 处理一个补全任务，单个任务 panic 时记录后继续处理后续任务
+func (s *profileEnrichmentService) process(req *EnrichRequest) {
+	ctx, cancel := context.Background(), func() {}
+	if s.cfg().Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.cfg().Timeout)
+	}
+	defer cancel()
+	ctx = logger.With(ctx, "user_id", req.UserID, "session_id", req.SessionID)
+	defer panics.Recover(ctx, panics.SourceEnrichment, nil)
+
+	if err := s.Enrich(ctx, req); err != nil {
+		slog.Error("failed to enrich profile", "user_id", req.UserID, "session_id", req.SessionID, "error", err)
+	}
+}
+
+// Enrich 抽取画像事实并合并
+func (s *profileEnrichmentService) Enrich(ctx context.Context, req *EnrichRequest) error {
+	profile, err := s.repo.GetProfile(ctx, req.UserID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		profile = &model.UserProfile{UserID: req.UserID}
+	}
+	if profile.EnrichmentDisabled {
+		return nil
+	}
+
+	facts, err := s.extractor.Extract(ctx, &ProfileExtractRequest{
+		UserID:  req.UserID,
+		Query:   req.Query,
+		History: req.History,
+		Profile: profile,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to extract profile facts: %w", err)
+	}
+
+	// 抽取期间画像可能被手动修改，在加锁读取的最新画像上重新合并，只写回合并改动的列
+	return s.repo.MergeProfile(ctx, req.UserID, func(profile *model.UserProfile) ([]string, []*model.ProfileHistory) {
+		if profile.EnrichmentDisabled {
+			return nil, nil
+		}
+		now := time.Now()
+		m := &profileMerger{
+			cfg:       s.cfg(),
+			profile:   profile,
+			source:    s.extractor.Source(),
+			sessionID: req.SessionID,
+			now:       now,
+		}
+		m.decay()
+		for _, fact := range facts {
+			m.apply(fact)
+		}
+		m.capInterests()
+
+		if !m.changed {
+			return nil, nil
+		}
+		profile.UpdatedAt = now
+		return m.changedColumns(), m.histories
+	})
+}
+
+// profileMerger 将画像事实合并到画像
+// 规则：
+//  1. 没有 signal 的非空字段为人工设置，不覆盖；
+//  2. 已有自动补全值时，新事实置信度不低于现有（衰减后）置信度才覆盖；
+//  3. 再次抽取到相同值时按 1-(1-a)(1-b) 叠加置信度并刷新时间；
+//  4. 自动补全值衰减到 prune_confidence 以下时移除
+type profileMerger struct {
+	cfg       *config.EnrichmentConfig
+	profile   *model.UserProfile
+	source    string
+	sessionID string
+	now       time.Time
+
+	histories []*model.ProfileHistory
+	changed   bool
+	columns   map[string]bool // 改动的画像列，signals 变化时总是包含 signals
+}
+
+// effective 衰减后的置信度
+func (m *profileMerger) effective(sig model.ProfileSignal) float64 {
+	if m.cfg.HalfLife <= 0 {
+		return sig.Confidence
+	}
+	age := m.now.Sub(sig.UpdatedAt)
+	if age <= 0 {
+		return sig.Confidence
+	}
+	return sig.Confidence * math.Pow(0.5, float64(age)/float64(m.cfg.HalfLife))
+}
+
+// decay 移除长期未再提及的自动补全值
+func (m *profileMerger) decay() {
+	keys := make([]string, 0, len(m.profile.Signals))
+	for key := range m.profile.Signals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		sig := m.profile.Signals[key]
+		confidence := m.effective(sig)
+		if confidence >= m.cfg.PruneConfidence {
+			continue
+		}
+		field := signalField(key)
+		old := profileFieldValue(m.profile, field, sig.Value)
+		clearProfileField(m.profile, field, sig.Value)
+		m.touch(field)
+		delete(m.profile.Signals, key)
+		m.record(field, old, "", model.ProfileSourceDecay, confidence)
+	}
+}
+
+// apply 合并一条画像事实
+func (m *profileMerger) apply(fact model.ProfileFact) {
+	value, ok := normalizeFactValue(fact.Field, fact.Value)
+	if !ok || fact.Confidence < m.cfg.MinConfidence {
+		return
+	}
+	confidence := math.Min(fact.Confidence, 1)
+
+	key := signalKey(fact.Field, value)
+	sig, hasSignal := m.profile.Signals[key]
+	current := profileFieldValue(m.profile, fact.Field, value)
+
+	if current == value {
+		if hasSignal {
+			existing := m.effective(sig)
+			sig.Confidence = 1 - (1-existing)*(1-confidence)
+			sig.UpdatedAt = m.now
+			sig.SessionID = m.sessionID
+			m.setSignal(key, sig)
+		}
+		return
+	}
+	if current != "" && !hasSignal {
+		return
+	}
+	if hasSignal && m.effective(sig) > confidence {
+		return
+	}
+
+	setProfileField(m.profile, fact.Field, value)
+	m.touch(fact.Field)
+	m.setSignal(key, model.ProfileSignal{
+		Value:      value,
+		Source:     m.source,
+		Confidence: confidence,
+		SessionID:  m.sessionID,
+		UpdatedAt:  m.now,
+	})
+	m.record(fact.Field, current, value, m.source, confidence)
+}
+
+// capInterests 兴趣超过上限时移除置信度最低的自动补全兴趣
+func (m *profileMerger) capInterests() {
+	if m.cfg.MaxInterests <= 0 || len(m.profile.Interests) <= m.cfg.MaxInterests {
+		return
+	}
+
+	type candidate struct {
+		interest   string
+		confidence float64
+	}
+	var candidates []candidate
+	for _, interest := range m.profile.Interests {
+		if sig, ok := m.profile.Signals[signalKey(model.ProfileFieldInterest, interest)]; ok {
+			candidates = append(candidates, candidate{interest: interest, confidence: m.effective(sig)})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].confidence < candidates[j].confidence })
+
+	for _, c := range candidates {
+		if len(m.profile.Interests) <= m.cfg.MaxInterests {
+			break
+		}
+		clearProfileField(m.profile, model.ProfileFieldInterest, c.interest)
+		m.touch(model.ProfileFieldInterest)
+		delete(m.profile.Signals, signalKey(model.ProfileFieldInterest, c.interest))
+		m.record(model.ProfileFieldInterest, c.interest, "", model.ProfileSourceDecay, c.confidence)
+	}
+}
+
+// touch 记录画像字段对应的列被改动
+func (m *profileMerger) touch(field string) {
+	if m.columns == nil {
+		m.columns = make(map[string]bool)
+	}
+	switch {
+	case field == model.ProfileFieldInterest:
+		m.columns["interests"] = true
+	case field == model.ProfileFieldBudgetMin:
+		m.columns["budget_min"] = true
+	case field == model.ProfileFieldBudgetMax:
+		m.columns["budget_max"] = true
+	case field == model.ProfileFieldGender:
+		m.columns["gender"] = true
+	case strings.HasPrefix(field, model.ProfileFieldSizePrefix):
+		m.columns["sizes"] = true
+	}
+}
+
+// changedColumns 需要写回的列，signals 随合并一起写回
+func (m *profileMerger) changedColumns() []string {
+	columns := []string{"signals"}
+	for column := range m.columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns[1:])
+	return columns
+}
+
+func (m *profileMerger) setSignal(key string, sig model.ProfileSignal) {
+	if m.profile.Signals == nil {
+		m.profile.Signals = make(map[string]model.ProfileSignal)
+	}
+	m.profile.Signals[key] = sig
+	m.changed = true
+}
+
+func (m *profileMerger) record(field, oldValue, newValue, source string, confidence float64) {
+	m.histories = append(m.histories, &model.ProfileHistory{
+		UserID:     m.profile.UserID,
+		Field:      field,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		Source:     source,
+		Confidence: confidence,
+		SessionID:  m.sessionID,
+		CreatedAt:  m.now,
+	})
+	m.changed = true
+}
+
+// signalKey 画像 signal 的键，兴趣按值区分
+func signalKey(field, value string) string {
+	if field == model.ProfileFieldInterest {
+		return field + ":" + value
+	}
+	return field
+}
+
+// signalField signal 键对应的字段名
+func signalField(key string) string {
+	if strings.HasPrefix(key, model.ProfileFieldInterest+":") {
+		return model.ProfileFieldInterest
+	}
+	return key
+}
+
+// normalizeFactValue 校验并规范化画像事实的值
+func normalizeFactValue(field, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+	switch {
+	case field == model.ProfileFieldInterest:
+		return value, len([]rune(value)) <= 32
+	case field == model.ProfileFieldBudgetMin || field == model.ProfileFieldBudgetMax:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v <= 0 {
+			return "", false
+		}
+		return formatAmount(v), true
+	case field == model.ProfileFieldGender:
+		return value, value == "male" || value == "female"
+	case strings.HasPrefix(field, model.ProfileFieldSizePrefix):
+		return strings.ToUpper(value), len(field) > len(model.ProfileFieldSizePrefix) && len(value) <= 16
+	}
+	return "", false
+}
+
+// profileFieldValue 字段的当前值，兴趣字段返回 value 是否已存在；未设置时返回空字符串
+func profileFieldValue(p *model.UserProfile, field, value string) string {
+	switch {
+	case field == model.ProfileFieldInterest:
+		for _, interest := range p.Interests {
+			if interest == value {
+				return value
+			}
+		}
+		return ""
+	case field == model.ProfileFieldBudgetMin:
+		return formatOptionalAmount(p.BudgetMin)
+	case field == model.ProfileFieldBudgetMax:
+		return formatOptionalAmount(p.BudgetMax)
+	case field == model.ProfileFieldGender:
+		if p.Gender == "unknown" {
+			return ""
+		}
+		return p.Gender
+	case strings.HasPrefix(field, model.ProfileFieldSizePrefix):
+		return p.Sizes[strings.TrimPrefix(field, model.ProfileFieldSizePrefix)]
+	}
+	return ""
+}
+
+func setProfileField(p *model.UserProfile, field, value string) {
+	switch {
+	case field == model.ProfileFieldInterest:
+		p.Interests = append(p.Interests, value)
+	case field == model.ProfileFieldBudgetMin:
+		p.BudgetMin, _ = strconv.ParseFloat(value, 64)
+	case field == model.ProfileFieldBudgetMax:
+		p.BudgetMax, _ = strconv.ParseFloat(value, 64)
+	case field == model.ProfileFieldGender:
+		p.Gender = value
+	case strings.HasPrefix(field, model.ProfileFieldSizePrefix):
+		if p.Sizes == nil {
+			p.Sizes = make(map[string]string)
+		}
+		p.Sizes[strings.TrimPrefix(field, model.ProfileFieldSizePrefix)] = value
+	}
+}
+
+func clearProfileField(p *model.UserProfile, field, value string) {
+	switch {
+	case field == model.ProfileFieldInterest:
+		interests := p.Interests[:0]
+		for _, interest := range p.Interests {
+			if interest != value {
+				interests = append(interests, interest)
+			}
+		}
+		p.Interests = interests
+	case field == model.ProfileFieldBudgetMin:
+		p.BudgetMin = 0
+	case field == model.ProfileFieldBudgetMax:
+		p.BudgetMax = 0
+	case field == model.ProfileFieldGender:
+		p.Gender = ""
+	case strings.HasPrefix(field, model.ProfileFieldSizePrefix):
+		delete(p.Sizes, strings.TrimPrefix(field, model.ProfileFieldSizePrefix))
+	}
+}
+
+func formatOptionalAmount(v float64) string {
+	if v <= 0 {
+		return ""
+	}
+	return formatAmount(v)
+}
+
+// cfg 当前生效的画像补全配置
+func (s *profileEnrichmentService) cfg() *config.EnrichmentConfig {
+	return &s.store.Get().Business.Enrichment
+}
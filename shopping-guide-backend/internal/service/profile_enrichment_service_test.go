@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+)
+
+// stubExtractor 返回固定画像事实
+type stubExtractor struct {
+	facts []model.ProfileFact
+	calls int
+}
+
+func (e *stubExtractor) Extract(ctx context.Context, req *ProfileExtractRequest) ([]model.ProfileFact, error) {
+	e.calls++
+	return e.facts, nil
+}
+
+func (e *stubExtractor) Source() string {
+	return model.ProfileSourceRules
+}
+
+func newTestEnrichmentService(t *testing.T, repo *memoryProfileRepo, extractor ProfileExtractor) ProfileEnrichmentService {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Business.Enrichment = config.EnrichmentConfig{
+		Enabled:         true,
+		MinConfidence:   0.5,
+		HalfLife:        24 * time.Hour,
+		PruneConfidence: 0.2,
+		MaxInterests:    3,
+		Workers:         1,
+		QueueSize:       4,
+	}
+	s := NewProfileEnrichmentService(repo, extractor, config.NewStore(cfg))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestProfileEnrichmentMerge(t *testing.T) {
+	now := time.Now()
+	autoSignal := func(value string, confidence float64, age time.Duration) model.ProfileSignal {
+		return model.ProfileSignal{Value: value, Source: model.ProfileSourceRules, Confidence: confidence, UpdatedAt: now.Add(-age)}
+	}
+	interestKey := func(v string) string { return signalKey(model.ProfileFieldInterest, v) }
+
+	tests := []struct {
+		name        string
+		profile     *model.UserProfile
+		facts       []model.ProfileFact
+		check       func(t *testing.T, p *model.UserProfile)
+		wantChanges []historyChange
+	}{
+		{
+			name: "fills empty profile",
+			facts: []model.ProfileFact{
+				{Field: model.ProfileFieldGender, Value: "female", Confidence: 0.9},
+				{Field: model.ProfileFieldInterest, Value: " 骑行 ", Confidence: 0.6},
+				{Field: model.ProfileFieldBudgetMax, Value: "500.0", Confidence: 0.8},
+				{Field: model.ProfileFieldSizePrefix + "clothing", Value: "m", Confidence: 0.8},
+				{Field: model.ProfileFieldInterest, Value: "露营", Confidence: 0.3},
+				{Field: model.ProfileFieldGender, Value: "robot", Confidence: 0.9},
+				{Field: model.ProfileFieldBudgetMin, Value: "-1", Confidence: 0.9},
+			},
+			check: func(t *testing.T, p *model.UserProfile) {
+				if p.Gender != "female" || p.BudgetMax != 500 || p.Sizes["clothing"] != "M" || !reflect.DeepEqual(p.Interests, []string{"骑行"}) {
+					t.Errorf("profile = %+v", p)
+				}
+				if sig := p.Signals[interestKey("骑行")]; sig.Confidence != 0.6 || sig.SessionID != "sess-1" {
+					t.Errorf("interest signal = %+v", sig)
+				}
+			},
+			wantChanges: []historyChange{
+				{model.ProfileFieldBudgetMax, "", "500"},
+				{model.ProfileFieldGender, "", "female"},
+				{model.ProfileFieldInterest, "", "骑行"},
+				{model.ProfileFieldSizePrefix + "clothing", "", "M"},
+			},
+		},
+		{
+			name:    "manual value kept",
+			profile: &model.UserProfile{Gender: "male"},
+			facts:   []model.ProfileFact{{Field: model.ProfileFieldGender, Value: "female", Confidence: 0.9}},
+			check: func(t *testing.T, p *model.UserProfile) {
+				if p.Gender != "male" || len(p.Signals) != 0 {
+					t.Errorf("profile = %+v", p)
+				}
+			},
+		},
+		{
+			name: "weaker fact does not override",
+			profile: &model.UserProfile{BudgetMax: 500, Signals: map[string]model.ProfileSignal{
+				model.ProfileFieldBudgetMax: autoSignal("500", 0.9, 0),
+			}},
+			facts: []model.ProfileFact{{Field: model.ProfileFieldBudgetMax, Value: "800", Confidence: 0.7}},
+			check: func(t *testing.T, p *model.UserProfile) {
+				if p.BudgetMax != 500 {
+					t.Errorf("budget_max = %v, want 500", p.BudgetMax)
+				}
+			},
+		},
+		{
+			name: "stronger fact overrides",
+			profile: &model.UserProfile{BudgetMax: 500, Signals: map[string]model.ProfileSignal{
+				model.ProfileFieldBudgetMax: autoSignal("500", 0.6, 0),
+			}},
+			facts: []model.ProfileFact{{Field: model.ProfileFieldBudgetMax, Value: "800", Confidence: 0.8}},
+			check: func(t *testing.T, p *model.UserProfile) {
+				if p.BudgetMax != 800 || p.Signals[model.ProfileFieldBudgetMax].Value != "800" {
+					t.Errorf("profile = %+v", p)
+				}
+			},
+			wantChanges: []historyChange{{model.ProfileFieldBudgetMax, "500", "800"}},
+		},
+		{
+			name: "repeated fact reinforces confidence",
+			profile: &model.UserProfile{Interests: []string{"骑行"}, Signals: map[string]model.ProfileSignal{
+				interestKey("骑行"): autoSignal("骑行", 0.6, 0),
+			}},
+			facts: []model.ProfileFact{{Field: model.ProfileFieldInterest, Value: "骑行", Confidence: 0.6}},
+			check: func(t *testing.T, p *model.UserProfile) {
+				// 0.6 与 0.6 叠加为 0.84，测试执行期间的衰减可忽略
+				if got := p.Signals[interestKey("骑行")].Confidence; math.Abs(got-0.84) > 1e-6 {
+					t.Errorf("confidence = %v, want 0.84", got)
+				}
+			},
+		},
+		{
+			name: "stale signal decays",
+			profile: &model.UserProfile{Interests: []string{"阅读", "露营"}, Signals: map[string]model.ProfileSignal{
+				interestKey("露营"): autoSignal("露营", 0.6, 48*time.Hour),
+			}},
+			check: func(t *testing.T, p *model.UserProfile) {
+				if !reflect.DeepEqual(p.Interests, []string{"阅读"}) || len(p.Signals) != 0 {
+					t.Errorf("profile = %+v", p)
+				}
+			},
+			wantChanges: []historyChange{{model.ProfileFieldInterest, "露营", ""}},
+		},
+		{
+			name: "interests capped by confidence",
+			profile: &model.UserProfile{Interests: []string{"阅读", "骑行", "徒步"}, Signals: map[string]model.ProfileSignal{
+				interestKey("骑行"): autoSignal("骑行", 0.9, 0),
+				interestKey("徒步"): autoSignal("徒步", 0.7, 0),
+			}},
+			facts: []model.ProfileFact{{Field: model.ProfileFieldInterest, Value: "露营", Confidence: 0.8}},
+			check: func(t *testing.T, p *model.UserProfile) {
+				if !reflect.DeepEqual(p.Interests, []string{"阅读", "骑行", "露营"}) {
+					t.Errorf("interests = %v", p.Interests)
+				}
+			},
+			wantChanges: []historyChange{
+				{model.ProfileFieldInterest, "徒步", ""},
+				{model.ProfileFieldInterest, "", "露营"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryProfileRepo()
+			if tt.profile != nil {
+				tt.profile.UserID = "user_1"
+				repo.profiles["user_1"] = tt.profile
+			}
+			s := newTestEnrichmentService(t, repo, &stubExtractor{facts: tt.facts})
+
+			if err := s.Enrich(context.Background(), &EnrichRequest{UserID: "user_1", SessionID: "sess-1"}); err != nil {
+				t.Fatalf("Enrich() error = %v", err)
+			}
+			profile := repo.profiles["user_1"]
+			if profile == nil {
+				profile = &model.UserProfile{}
+			}
+			tt.check(t, profile)
+			if got := historyChanges(repo.histories); !reflect.DeepEqual(got, nonNilChanges(tt.wantChanges)) {
+				t.Errorf("changes = %v, want %v", got, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestProfileEnrichmentDisabled(t *testing.T) {
+	repo := newMemoryProfileRepo()
+	repo.profiles["user_1"] = &model.UserProfile{UserID: "user_1", EnrichmentDisabled: true}
+	extractor := &stubExtractor{facts: []model.ProfileFact{{Field: model.ProfileFieldGender, Value: "female", Confidence: 0.9}}}
+	s := newTestEnrichmentService(t, repo, extractor)
+
+	if err := s.Enrich(context.Background(), &EnrichRequest{UserID: "user_1"}); err != nil {
+		t.Fatalf("Enrich() error = %v", err)
+	}
+	if extractor.calls != 0 || repo.profiles["user_1"].Gender != "" {
+		t.Errorf("extractor calls = %d, profile = %+v", extractor.calls, repo.profiles["user_1"])
+	}
+}
+
+func TestProfileEnrichmentSubmit(t *testing.T) {
+	repo := newMemoryProfileRepo()
+	extractor := &stubExtractor{facts: []model.ProfileFact{{Field: model.ProfileFieldGender, Value: "female", Confidence: 0.9}}}
+	s := newTestEnrichmentService(t, repo, extractor)
+
+	s.Submit(&EnrichRequest{UserID: "user_1", SessionID: "sess-1", Query: "我是女生"})
+	s.Submit(&EnrichRequest{SessionID: "sess-guest"})
+	s.Close()
+
+	if extractor.calls != 1 || repo.profiles["user_1"].Gender != "female" {
+		t.Errorf("extractor calls = %d, profile = %+v", extractor.calls, repo.profiles["user_1"])
+	}
+	// 关闭后提交的任务被忽略
+	s.Submit(&EnrichRequest{UserID: "user_2"})
+}
+
+func TestRuleProfileExtractor(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string // field=value
+	}{
+		{"预算1000-2000元，想买辆自行车", []string{"budget_max=2000", "budget_min=1000"}},
+		{"500元以内的水壶", []string{"budget_max=500"}},
+		{"预算大概1.5k", []string{"budget_max=1500"}},
+		{"孩子1-2岁", nil},
+		{"我是女生，平时穿M码，鞋码38", []string{"gender=female", "size.clothing=M", "size.shoe=38"}},
+		{"我平时很喜欢骑行和露营所以想买装备", []string{"interest=露营", "interest=骑行"}},
+		{"我老婆喜欢瑜伽", nil},
+		{"我的爱好是摄影、徒步", []string{"interest=徒步", "interest=摄影"}},
+		{"我喜欢这款", nil},
+	}
+	extractor := &ruleProfileExtractor{}
+	for _, tt := range tests {
+		facts, err := extractor.Extract(context.Background(), &ProfileExtractRequest{Query: tt.query})
+		if err != nil {
+			t.Fatalf("Extract(%q) error = %v", tt.query, err)
+		}
+		var got []string
+		for _, f := range facts {
+			got = append(got, f.Field+"="+f.Value)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Extract(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func nonNilChanges(changes []historyChange) []historyChange {
+	if changes == nil {
+		return []historyChange{}
+	}
+	return changes
+}
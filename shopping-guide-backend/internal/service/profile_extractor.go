@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"shopping-guide-backend/internal/client"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+)
+
+// workflowProfileExtractor 画像抽取工作流配置名（对应 dify.workflows.executors 下的键）
+const workflowProfileExtractor = "profile_extractor"
+
+// ProfileExtractor 画像抽取器，从一轮对话中抽取画像事实
+type ProfileExtractor interface {
+	Extract(ctx context.Context, req *ProfileExtractRequest) ([]model.ProfileFact, error)
+	// Source 抽取结果的来源标识，写入画像 signal 与变更记录
+	Source() string
+}
+
+// ProfileExtractRequest 画像抽取请求
+type ProfileExtractRequest struct {
+	UserID  string
+	Query   string
+	History []model.Message
+	Profile *model.UserProfile
+}
+
+// NewProfileExtractor 按 business.enrichment.extractor 创建画像抽取器，默认使用本地规则
+// 抽取器类型在启动时确定，修改后需重启
+func NewProfileExtractor(difyClient client.DifyClient, store *config.Store) ProfileExtractor {
+	if store.Get().Business.Enrichment.Extractor == model.ProfileSourceDify {
+		return &difyProfileExtractor{
+			difyClient: difyClient,
+			store:      store,
+		}
+	}
+	return &ruleProfileExtractor{}
+}
+
+// difyProfileExtractor 基于 Dify 工作流的画像抽取
+// 工作流输出 facts：[{"field","value","confidence"}]（数组或JSON字符串）
+type difyProfileExtractor struct {
+	difyClient client.DifyClient
+	store      *config.Store
+}
+
+func (e *difyProfileExtractor) Source() string {
+	return model.ProfileSourceDify
+}
+
+func (e *difyProfileExtractor) Extract(ctx context.Context, req *ProfileExtractRequest) ([]model.ProfileFact, error) {
+	workflow := e.store.Get().Dify.Workflows.Executors[workflowProfileExtractor]
+	if workflow.AppID == "" {
+		return nil, fmt.Errorf("workflow %s is not configured", workflowProfileExtractor)
+	}
+
+	portrait, err := req.Profile.Portrait()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build user portrait: %w", err)
+	}
+	inputs := map[string]interface{}{
+		"query":         req.Query,
+		"user_portrait": portrait,
+	}
+	if len(req.History) > 0 {
+		inputs["history"] = req.History
+	}
+
+	callCtx, cancel := withWorkflowTimeout(ctx, workflowProfileExtractor, workflow)
+	defer cancel()
+
+	difyresp, err := e.difyClient.CallWorkflow(callCtx, workflow.AppID, inputs, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call profile extractor workflow: %w", err)
+	}
+
+	var raw []byte
+	switch v := difyresp.Data.Outputs["facts"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		raw = []byte(v)
+	default:
+		if raw, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("failed to marshal facts: %w", err)
+		}
+	}
+
+	var facts []model.ProfileFact
+	if err := json.Unmarshal(raw, &facts); err != nil {
+		return nil, fmt.Errorf("failed to parse facts: %w", err)
+	}
+	return facts, nil
+}
+
+// 规则抽取的金额写法：1500 / 1.5k / 2千 / 1万
+const amountPattern = `(\d+(?:\.\d+)?)\s*([kK千万wW]?)`
+
+var (
+	// budgetRangeRe 预算区间：预算1000-2000 / 500到800元
+	budgetRangeRe = regexp.MustCompile(`(预算[^\d]{0,6})?` + amountPattern + `\s*(?:元|块)?\s*(?:-|~|～|到|至)\s*` + amountPattern + `\s*(元|块)?`)
+	// budgetUpperRe 预算上限：500元以内 / 1000以下
+	budgetUpperRe = regexp.MustCompile(amountPattern + `\s*(?:元|块)?\s*(?:以内|以下|之内)`)
+	// budgetAboutRe 预算金额：预算1000 / 不超过800 / 最多2千
+	budgetAboutRe = regexp.MustCompile(`(?:预算|不超过|不要超过|别超过|最多)(?:是|在|大概|大约|控制在)?\s*` + amountPattern)
+	// clothingSizeRe 服装尺码：穿M码 / 尺码是XL
+	clothingSizeRe = regexp.MustCompile(`(?i)(?:\b(XXXL|XXL|XL|XXS|XS|S|M|L|[2-5]XL)\s*码|尺码(?:是|为)?\s*(XXXL|XXL|XL|XXS|XS|S|M|L|[2-5]XL)\b)`)
+	// shoeSizeRe 鞋码：鞋码42 / 穿40码的鞋
+	shoeSizeRe = regexp.MustCompile(`鞋码(?:是|为)?\s*(3[4-9]|4[0-8])(?:\.5)?|(3[4-9]|4[0-8])(?:\.5)?\s*码`)
+	// genderRe 自述性别
+	genderRe = regexp.MustCompile(`(?:我是|本人是?|我一个)\s*(男生|女生|男的|女的|男性|女性|男孩|女孩|小哥哥|小姐姐)`)
+	// interestRe 自述兴趣：我平时很喜欢跑步和看书；主语省略时要求位于分句开头，避免"我老婆喜欢…"
+	interestRe = regexp.MustCompile(`(?:^|[，,。！!？?；;\s]|我|本人)(?:平时|平常|一直|特别|非常|很|超|比较|最|也|还)*(?:喜欢|热爱|爱)([\p{Han}、和与跟及]{2,24})`)
+	// hobbyRe 爱好/兴趣是...
+	hobbyRe = regexp.MustCompile(`(?:爱好|兴趣)(?:是|有)([\p{Han}、和与跟及]{2,24})`)
+)
+
+var (
+	interestSeparators = regexp.MustCompile(`[、和与跟及]|还有`)
+	// interestCutWords 兴趣片段在这些词处截断，如"跑步所以想买"取"跑步"
+	interestCutWords = []string{"所以", "因为", "但是", "可是", "想", "要", "的时候", "平时"}
+	// interestStopPrefixes 指代类片段不是兴趣，如"这款""那个颜色"
+	interestStopPrefixes = []string{"这", "那", "哪", "什么", "你", "它", "他", "她", "上", "买"}
+)
+
+var genderValues = map[string]string{
+	"男生": "male", "男的": "male", "男性": "male", "男孩": "male", "小哥哥": "male",
+	"女生": "female", "女的": "female", "女性": "female", "女孩": "female", "小姐姐": "female",
+}
+
+// ruleProfileExtractor 基于正则的画像抽取，只处理用户本轮输入中的自述信息
+type ruleProfileExtractor struct{}
+
+func (e *ruleProfileExtractor) Source() string {
+	return model.ProfileSourceRules
+}
+
+func (e *ruleProfileExtractor) Extract(ctx context.Context, req *ProfileExtractRequest) ([]model.ProfileFact, error) {
+	text := req.Query
+	var facts []model.ProfileFact
+
+	facts = append(facts, extractBudget(text)...)
+
+	for _, m := range clothingSizeRe.FindAllStringSubmatch(text, -1) {
+		size := m[1]
+		if size == "" {
+			size = m[2]
+		}
+		facts = append(facts, model.ProfileFact{Field: model.ProfileFieldSizePrefix + "clothing", Value: strings.ToUpper(size), Confidence: 0.8})
+	}
+	for _, m := range shoeSizeRe.FindAllStringSubmatch(text, -1) {
+		if m[1] != "" {
+			facts = append(facts, model.ProfileFact{Field: model.ProfileFieldSizePrefix + "shoe", Value: m[1], Confidence: 0.9})
+		} else {
+			facts = append(facts, model.ProfileFact{Field: model.ProfileFieldSizePrefix + "shoe", Value: m[2], Confidence: 0.7})
+		}
+	}
+
+	if m := genderRe.FindStringSubmatch(text); m != nil {
+		facts = append(facts, model.ProfileFact{Field: model.ProfileFieldGender, Value: genderValues[m[1]], Confidence: 0.9})
+	}
+
+	for _, m := range interestRe.FindAllStringSubmatch(text, -1) {
+		// "爱好是…"由 hobbyRe 处理
+		if strings.HasPrefix(m[1], "好") {
+			continue
+		}
+		for _, interest := range splitInterests(m[1]) {
+			facts = append(facts, model.ProfileFact{Field: model.ProfileFieldInterest, Value: interest, Confidence: 0.6})
+		}
+	}
+	for _, m := range hobbyRe.FindAllStringSubmatch(text, -1) {
+		for _, interest := range splitInterests(m[1]) {
+			facts = append(facts, model.ProfileFact{Field: model.ProfileFieldInterest, Value: interest, Confidence: 0.8})
+		}
+	}
+
+	return facts, nil
+}
+
+// extractBudget 抽取预算，区间优先，其次上限，最后是单个金额
+func extractBudget(text string) []model.ProfileFact {
+	for _, m := range budgetRangeRe.FindAllStringSubmatch(text, -1) {
+		// 区间需要带"预算"前缀或金额单位，避免把"1-2岁"之类识别为预算
+		if m[1] == "" && m[6] == "" {
+			continue
+		}
+		low, high := parseAmount(m[2], m[3]), parseAmount(m[4], m[5])
+		if low <= 0 || high < low {
+			continue
+		}
+		return []model.ProfileFact{
+			{Field: model.ProfileFieldBudgetMin, Value: formatAmount(low), Confidence: 0.8},
+			{Field: model.ProfileFieldBudgetMax, Value: formatAmount(high), Confidence: 0.8},
+		}
+	}
+	if m := budgetUpperRe.FindStringSubmatch(text); m != nil {
+		if v := parseAmount(m[1], m[2]); v > 0 {
+			return []model.ProfileFact{{Field: model.ProfileFieldBudgetMax, Value: formatAmount(v), Confidence: 0.8}}
+		}
+	}
+	if m := budgetAboutRe.FindStringSubmatch(text); m != nil {
+		if v := parseAmount(m[1], m[2]); v > 0 {
+			return []model.ProfileFact{{Field: model.ProfileFieldBudgetMax, Value: formatAmount(v), Confidence: 0.7}}
+		}
+	}
+	return nil
+}
+
+func parseAmount(number, unit string) float64 {
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0
+	}
+	switch unit {
+	case "k", "K", "千":
+		v *= 1000
+	case "万", "w", "W":
+		v *= 10000
+	}
+	return v
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// splitInterests 拆分并清洗兴趣片段，保留 2-6 个字的兴趣
+func splitInterests(fragment string) []string {
+	for _, w := range interestCutWords {
+		if i := strings.Index(fragment, w); i > 0 {
+			fragment = fragment[:i]
+		}
+	}
+
+	var interests []string
+	for _, part := range interestSeparators.Split(fragment, -1) {
+		part = strings.TrimRight(part, "了的呀啊呢吧哦")
+		n := len([]rune(part))
+		if n < 2 || n > 6 || hasStopPrefix(part) {
+			continue
+		}
+		interests = append(interests, part)
+	}
+	return interests
+}
+
+func hasStopPrefix(s string) bool {
+	for _, prefix := range interestStopPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,31 +2,212 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
 	"shopping-guide-backend/internal/model"
 	"shopping-guide-backend/internal/repository"
+	"shopping-guide-backend/internal/style"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrProfileNotFound 用户画像不存在
+	ErrProfileNotFound error = apperr.New(apperr.KindNotFound, "profile not found")
+	// ErrInvalidStyle 不支持的导购风格
+	ErrInvalidStyle error = apperr.New(apperr.KindInvalidInput, "invalid preferred style")
 )
 
 // ProfileService 用户画像服务接口
 type ProfileService interface {
 	GetProfile(ctx context.Context, userID string) (*model.UserProfile, error)
 	UpdateProfile(ctx context.Context, userID string, profile *model.UserProfile) error
+	// PatchProfile 部分更新画像，画像不存在时以空画像为基础创建
+	PatchProfile(ctx context.Context, userID string, req *model.ProfilePatchRequest) (*model.UserProfile, error)
+	// ListHistory 画像变更记录，按时间倒序
+	ListHistory(ctx context.Context, userID string, limit int) ([]model.ProfileHistory, error)
 }
 
 // ProfileServiceImpl 用户画像服务实现
 type ProfileServiceImpl struct {
-	repo repository.ProfileRepository
+	repo   repository.ProfileRepository
+	styles *style.Registry
 }
 
-func NewProfileService(repo repository.ProfileRepository) ProfileService {
+func NewProfileService(repo repository.ProfileRepository, styles *style.Registry) ProfileService {
 	return &ProfileServiceImpl{
-		repo: repo,
+		repo:   repo,
+		styles: styles,
 	}
 }
 
 func (s *ProfileServiceImpl) GetProfile(ctx context.Context, userID string) (*model.UserProfile, error) {
-	return s.repo.GetProfile(ctx, userID)
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+	return profile, nil
 }
 
+// UpdateProfile 人工更新画像
+// 被修改的字段视为人工设置：移除对应的自动补全 signal，并记录变更历史
 func (s *ProfileServiceImpl) UpdateProfile(ctx context.Context, userID string, profile *model.UserProfile) error {
-	return s.repo.UpdateProfile(ctx, userID, profile)
+	if err := validateStyle(s.styles, profile.PreferredStyle); err != nil {
+		return err
+	}
+
+	old, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, ErrProfileNotFound) {
+			return err
+		}
+		old = &model.UserProfile{UserID: userID}
+	}
+
+	now := time.Now()
+	profile.UserID = userID
+	profile.UpdatedAt = now
+	histories := diffProfile(old, profile, now)
+
+	profile.Signals = make(map[string]model.ProfileSignal, len(old.Signals))
+	for key, sig := range old.Signals {
+		profile.Signals[key] = sig
+	}
+	for _, h := range histories {
+		delete(profile.Signals, signalKey(h.Field, h.OldValue))
+		delete(profile.Signals, signalKey(h.Field, h.NewValue))
+	}
+
+	return s.repo.SaveWithHistory(ctx, profile, histories)
+}
+
+func (s *ProfileServiceImpl) PatchProfile(ctx context.Context, userID string, req *model.ProfilePatchRequest) (*model.UserProfile, error) {
+	current, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, ErrProfileNotFound) {
+			return nil, err
+		}
+		current = &model.UserProfile{UserID: userID}
+	}
+	// 拷贝一份再修改，UpdateProfile 需要用原值计算变更
+	patched := *current
+	profile := &patched
+	profile.Interests = append([]string(nil), current.Interests...)
+	if current.Sizes != nil {
+		profile.Sizes = make(map[string]string, len(current.Sizes))
+		for k, v := range current.Sizes {
+			profile.Sizes[k] = v
+		}
+	}
+
+	if req.PreferredStyle != nil {
+		profile.PreferredStyle = *req.PreferredStyle
+	}
+	if req.Age != nil {
+		profile.Age = *req.Age
+	}
+	if req.Gender != nil {
+		profile.Gender = *req.Gender
+	}
+	if req.Interests != nil {
+		profile.Interests = *req.Interests
+	}
+	if req.BudgetMin != nil {
+		profile.BudgetMin = *req.BudgetMin
+	}
+	if req.BudgetMax != nil {
+		profile.BudgetMax = *req.BudgetMax
+	}
+	if req.Sizes != nil {
+		profile.Sizes = *req.Sizes
+	}
+	if req.EnrichmentDisabled != nil {
+		profile.EnrichmentDisabled = *req.EnrichmentDisabled
+	}
+
+	if err := s.UpdateProfile(ctx, userID, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// validateStyle 校验风格名称，空字符串表示未设置
+func validateStyle(styles *style.Registry, name string) error {
+	if name == "" || styles.Has(name) {
+		return nil
+	}
+	return apperr.Wrap(apperr.KindInvalidInput,
+		fmt.Sprintf("invalid preferred style %q, must be one of %s", name, strings.Join(styles.Names(), "/")), ErrInvalidStyle)
+}
+
+func (s *ProfileServiceImpl) ListHistory(ctx context.Context, userID string, limit int) ([]model.ProfileHistory, error) {
+	return s.repo.ListHistory(ctx, userID, limit)
+}
+
+// diffProfile 计算人工修改产生的变更记录
+func diffProfile(old, updated *model.UserProfile, now time.Time) []*model.ProfileHistory {
+	var histories []*model.ProfileHistory
+	add := func(field, oldValue, newValue string) {
+		if oldValue == newValue {
+			return
+		}
+		histories = append(histories, &model.ProfileHistory{
+			UserID:     updated.UserID,
+			Field:      field,
+			OldValue:   oldValue,
+			NewValue:   newValue,
+			Source:     model.ProfileSourceManual,
+			Confidence: 1,
+			CreatedAt:  now,
+		})
+	}
+
+	add(model.ProfileFieldPreferredStyle, old.PreferredStyle, updated.PreferredStyle)
+	add(model.ProfileFieldAge, strconv.Itoa(old.Age), strconv.Itoa(updated.Age))
+	add(model.ProfileFieldGender, old.Gender, updated.Gender)
+	add(model.ProfileFieldBudgetMin, formatOptionalAmount(old.BudgetMin), formatOptionalAmount(updated.BudgetMin))
+	add(model.ProfileFieldBudgetMax, formatOptionalAmount(old.BudgetMax), formatOptionalAmount(updated.BudgetMax))
+	add(model.ProfileFieldEnrichment, strconv.FormatBool(old.EnrichmentDisabled), strconv.FormatBool(updated.EnrichmentDisabled))
+
+	oldInterests := make(map[string]struct{}, len(old.Interests))
+	for _, interest := range old.Interests {
+		oldInterests[interest] = struct{}{}
+	}
+	newInterests := make(map[string]struct{}, len(updated.Interests))
+	for _, interest := range updated.Interests {
+		newInterests[interest] = struct{}{}
+		if _, ok := oldInterests[interest]; !ok {
+			add(model.ProfileFieldInterest, "", interest)
+		}
+	}
+	for _, interest := range old.Interests {
+		if _, ok := newInterests[interest]; !ok {
+			add(model.ProfileFieldInterest, interest, "")
+		}
+	}
+
+	kinds := make([]string, 0, len(old.Sizes)+len(updated.Sizes))
+	for kind := range old.Sizes {
+		kinds = append(kinds, kind)
+	}
+	for kind := range updated.Sizes {
+		if _, ok := old.Sizes[kind]; !ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		add(model.ProfileFieldSizePrefix+kind, old.Sizes[kind], updated.Sizes[kind])
+	}
+
+	return histories
 }
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+	"shopping-guide-backend/internal/style"
+
+	"gorm.io/gorm"
+)
+
+// memoryProfileRepo 内存画像库，读写均拷贝，模拟数据库行
+type memoryProfileRepo struct {
+	mu        sync.Mutex
+	profiles  map[string]*model.UserProfile
+	histories []*model.ProfileHistory
+}
+
+func newMemoryProfileRepo() *memoryProfileRepo {
+	return &memoryProfileRepo{profiles: make(map[string]*model.UserProfile)}
+}
+
+func (r *memoryProfileRepo) GetProfile(ctx context.Context, userID string) (*model.UserProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.profiles[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return copyProfile(p), nil
+}
+
+func (r *memoryProfileRepo) UpdateProfile(ctx context.Context, userID string, profile *model.UserProfile) error {
+	return r.SaveWithHistory(ctx, profile, nil)
+}
+
+func (r *memoryProfileRepo) SaveWithHistory(ctx context.Context, profile *model.UserProfile, histories []*model.ProfileHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.profiles[profile.UserID] = copyProfile(profile)
+	r.histories = append(r.histories, histories...)
+	return nil
+}
+
+func (r *memoryProfileRepo) MergeProfile(ctx context.Context, userID string, merge repository.ProfileMergeFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	profile := &model.UserProfile{UserID: userID}
+	if p, ok := r.profiles[userID]; ok {
+		profile = copyProfile(p)
+	}
+	columns, histories := merge(profile)
+	if len(columns) == 0 {
+		return nil
+	}
+	r.profiles[userID] = profile
+	r.histories = append(r.histories, histories...)
+	return nil
+}
+
+func (r *memoryProfileRepo) ListHistory(ctx context.Context, userID string, limit int) ([]model.ProfileHistory, error) {
+	return nil, nil
+}
+
+func copyProfile(p *model.UserProfile) *model.UserProfile {
+	c := *p
+	c.Interests = append([]string(nil), p.Interests...)
+	if p.Sizes != nil {
+		c.Sizes = make(map[string]string, len(p.Sizes))
+		for k, v := range p.Sizes {
+			c.Sizes[k] = v
+		}
+	}
+	if p.Signals != nil {
+		c.Signals = make(map[string]model.ProfileSignal, len(p.Signals))
+		for k, v := range p.Signals {
+			c.Signals[k] = v
+		}
+	}
+	return &c
+}
+
+// testStyles 包含 xiaohongshu 与 dongyuhui 的风格注册表
+func testStyles(t *testing.T) *style.Registry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "styles.yaml")
+	content := "styles:\n  xiaohongshu:\n    display_name: 小红书\n  dongyuhui:\n    display_name: 董宇辉\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	styles, err := style.Load(path, "xiaohongshu")
+	if err != nil {
+		t.Fatalf("style.Load() error = %v", err)
+	}
+	return styles
+}
+
+// historyChange 便于比较的变更记录
+type historyChange struct {
+	Field, Old, New string
+}
+
+func historyChanges(histories []*model.ProfileHistory) []historyChange {
+	changes := make([]historyChange, len(histories))
+	for i, h := range histories {
+		changes[i] = historyChange{h.Field, h.OldValue, h.NewValue}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Field != changes[j].Field {
+			return changes[i].Field < changes[j].Field
+		}
+		return changes[i].Old+changes[i].New < changes[j].Old+changes[j].New
+	})
+	return changes
+}
+
+func TestDiffProfile(t *testing.T) {
+	now := time.Now()
+	old := &model.UserProfile{
+		UserID:         "user_1",
+		PreferredStyle: "xiaohongshu",
+		Age:            28,
+		Gender:         "female",
+		Interests:      []string{"骑行", "露营"},
+		BudgetMax:      1000,
+		Sizes:          map[string]string{"shoe": "38", "clothing": "M"},
+	}
+	updated := &model.UserProfile{
+		UserID:             "user_1",
+		PreferredStyle:     "dongyuhui",
+		Age:                28,
+		Gender:             "female",
+		Interests:          []string{"露营", "徒步"},
+		BudgetMin:          200,
+		BudgetMax:          1000,
+		Sizes:              map[string]string{"shoe": "39", "hat": "L"},
+		EnrichmentDisabled: true,
+	}
+
+	histories := diffProfile(old, updated, now)
+	want := []historyChange{
+		{model.ProfileFieldBudgetMin, "", "200"},
+		{model.ProfileFieldEnrichment, "false", "true"},
+		{model.ProfileFieldInterest, "", "徒步"},
+		{model.ProfileFieldInterest, "骑行", ""},
+		{model.ProfileFieldPreferredStyle, "xiaohongshu", "dongyuhui"},
+		{model.ProfileFieldSizePrefix + "clothing", "M", ""},
+		{model.ProfileFieldSizePrefix + "hat", "", "L"},
+		{model.ProfileFieldSizePrefix + "shoe", "38", "39"},
+	}
+	if got := historyChanges(histories); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes =\n%v\nwant\n%v", got, want)
+	}
+	for _, h := range histories {
+		if h.UserID != "user_1" || h.Source != model.ProfileSourceManual || h.Confidence != 1 || !h.CreatedAt.Equal(now) {
+			t.Errorf("history = %+v", h)
+		}
+	}
+
+	if histories := diffProfile(old, copyProfile(old), now); len(histories) != 0 {
+		t.Errorf("diff of identical profiles = %v, want none", historyChanges(histories))
+	}
+}
+
+func TestProfileServicePatchProfile(t *testing.T) {
+	repo := newMemoryProfileRepo()
+	repo.profiles["user_1"] = &model.UserProfile{
+		UserID:    "user_1",
+		Gender:    "female",
+		Interests: []string{"骑行"},
+		Sizes:     map[string]string{"shoe": "38"},
+		Signals: map[string]model.ProfileSignal{
+			model.ProfileFieldGender:                    {Value: "female", Source: model.ProfileSourceRules, Confidence: 0.7},
+			signalKey(model.ProfileFieldInterest, "骑行"): {Value: "骑行", Source: model.ProfileSourceRules, Confidence: 0.8},
+			model.ProfileFieldBudgetMax:                 {Value: "500", Source: model.ProfileSourceRules, Confidence: 0.6},
+		},
+	}
+	s := NewProfileService(repo, testStyles(t))
+
+	age, interests, dongyuhui := 30, []string{"露营"}, "dongyuhui"
+	profile, err := s.PatchProfile(context.Background(), "user_1", &model.ProfilePatchRequest{
+		Age:            &age,
+		Interests:      &interests,
+		PreferredStyle: &dongyuhui,
+	})
+	if err != nil {
+		t.Fatalf("PatchProfile() error = %v", err)
+	}
+
+	// 未修改的字段保持不变
+	if profile.Gender != "female" || profile.Sizes["shoe"] != "38" || profile.Age != 30 || !reflect.DeepEqual(profile.Interests, interests) {
+		t.Errorf("profile = %+v", profile)
+	}
+	// 人工修改的兴趣不再由自动补全管理，未修改字段的 signal 保留
+	stored := repo.profiles["user_1"]
+	if _, ok := stored.Signals[signalKey(model.ProfileFieldInterest, "骑行")]; ok {
+		t.Error("signal of manually removed interest kept")
+	}
+	if _, ok := stored.Signals[model.ProfileFieldGender]; !ok {
+		t.Error("signal of unchanged gender removed")
+	}
+	if _, ok := stored.Signals[model.ProfileFieldBudgetMax]; !ok {
+		t.Error("signal of unchanged budget removed")
+	}
+	want := []historyChange{
+		{model.ProfileFieldAge, "0", "30"},
+		{model.ProfileFieldInterest, "", "露营"},
+		{model.ProfileFieldInterest, "骑行", ""},
+		{model.ProfileFieldPreferredStyle, "", "dongyuhui"},
+	}
+	if got := historyChanges(repo.histories); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestProfileServicePatchCreatesProfile(t *testing.T) {
+	repo := newMemoryProfileRepo()
+	s := NewProfileService(repo, testStyles(t))
+
+	if _, err := s.GetProfile(context.Background(), "user_2"); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("GetProfile() error = %v, want ErrProfileNotFound", err)
+	}
+
+	gender := "male"
+	profile, err := s.PatchProfile(context.Background(), "user_2", &model.ProfilePatchRequest{Gender: &gender})
+	if err != nil {
+		t.Fatalf("PatchProfile() error = %v", err)
+	}
+	if profile.UserID != "user_2" || profile.Gender != "male" || repo.profiles["user_2"] == nil {
+		t.Errorf("profile = %+v", profile)
+	}
+}
+
+func TestProfileServiceRejectsUnknownStyle(t *testing.T) {
+	repo := newMemoryProfileRepo()
+	s := NewProfileService(repo, testStyles(t))
+
+	unknown := "shouting"
+	_, err := s.PatchProfile(context.Background(), "user_1", &model.ProfilePatchRequest{PreferredStyle: &unknown})
+	if !errors.Is(err, ErrInvalidStyle) || !apperr.Is(err, apperr.KindInvalidInput) {
+		t.Errorf("PatchProfile() error = %v, want invalid style", err)
+	}
+	if len(repo.profiles) != 0 {
+		t.Error("profile saved with invalid style")
+	}
+}
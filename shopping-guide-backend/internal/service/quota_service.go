@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/logger"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/panics"
+	"shopping-guide-backend/internal/repository"
+)
+
+// ErrQuotaExceeded token 配额已用完
+var ErrQuotaExceeded error = apperr.New(apperr.KindRateLimited, "token quota exceeded")
+
+// QuotaService 大模型 token 配额服务
+// 按用户、商家分别统计每日与每月的 Dify total_tokens，计数保存在 Redis，定时汇总到 MySQL
+type QuotaService interface {
+	// Check 调用大模型前检查配额
+	Check(ctx context.Context, subject *QuotaSubject) (*model.QuotaStatus, error)
+	// Record 记录一轮对话消耗的 tokens，用量首次达到预警比例时发出预警事件
+	Record(ctx context.Context, subject *QuotaSubject, tokens int) (*model.QuotaStatus, error)
+	// Usage 主体的当前配额状态与最近 days 天按天汇总的用量
+	Usage(ctx context.Context, scope, subjectID string, days int) (*model.UsageResponse, error)
+	// Rollup 把前一日与当日的用量汇总到 MySQL
+	Rollup(ctx context.Context) error
+	// Close 停止定时汇总，并做最后一次汇总
+	Close()
+}
+
+// QuotaSubject 计量主体，MerchantID 为空时只按用户计量
+type QuotaSubject struct {
+	UserID     string
+	MerchantID string
+	GuestIP    string // 访客的客户端IP，不为空时按IP使用访客额度计量，不按访客ID计量
+}
+
+// quotaService 配额服务实现
+type quotaService struct {
+	quotaRepo repository.QuotaRepository
+	usageRepo repository.TokenUsageRepository
+	store     *config.Store
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewQuotaService 创建配额服务，配置了汇总间隔时启动定时汇总
+// 汇总始终运行，business.quota.enabled 可热更新开关；汇总间隔修改后需重启
+func NewQuotaService(
+	quotaRepo repository.QuotaRepository,
+	usageRepo repository.TokenUsageRepository,
+	store *config.Store,
+) QuotaService {
+	s := &quotaService{
+		quotaRepo: quotaRepo,
+		usageRepo: usageRepo,
+		store:     store,
+		stop:      make(chan struct{}),
+	}
+	if s.cfg().RollupInterval > 0 {
+		s.wg.Add(1)
+		go s.rollupLoop()
+	}
+	return s
+}
+
+// Check 检查配额
+func (s *quotaService) Check(ctx context.Context, subject *QuotaSubject) (*model.QuotaStatus, error) {
+	if !s.cfg().Enabled {
+		return &model.QuotaStatus{Level: model.QuotaLevelNormal, Usages: []model.QuotaUsage{}}, nil
+	}
+
+	now := time.Now()
+	var usages []model.QuotaUsage
+	for _, target := range s.targets(subject) {
+		daily, monthly, err := s.quotaRepo.Get(ctx, target.scope, target.id, now)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, s.usages(target.scope, target.id, daily, monthly)...)
+	}
+	return s.status(usages), nil
+}
+
+// Record 记录用量
+func (s *quotaService) Record(ctx context.Context, subject *QuotaSubject, tokens int) (*model.QuotaStatus, error) {
+	if !s.cfg().Enabled || tokens <= 0 {
+		return s.Check(ctx, subject)
+	}
+
+	now := time.Now()
+	var usages []model.QuotaUsage
+	for _, target := range s.targets(subject) {
+		daily, monthly, err := s.quotaRepo.Incr(ctx, target.scope, target.id, int64(tokens), now)
+		if err != nil {
+			return nil, err
+		}
+		for _, usage := range s.usages(target.scope, target.id, daily, monthly) {
+			// 计数由 Redis 原子累加，跨过预警线的只有一个请求，预警事件不会在多副本间重复发送
+			if s.crossedWarning(usage, int64(tokens)) {
+				s.emitWarning(ctx, usage, now)
+			}
+			usages = append(usages, usage)
+		}
+	}
+	return s.status(usages), nil
+}
+
+// Usage 查询用量
+func (s *quotaService) Usage(ctx context.Context, scope, subjectID string, days int) (*model.UsageResponse, error) {
+	now := time.Now()
+	daily, monthly, err := s.quotaRepo.Get(ctx, scope, subjectID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	since := now.AddDate(0, 0, 1-days)
+	history, err := s.usageRepo.List(ctx, scope, subjectID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UsageResponse{
+		Status:  s.status(s.usages(scope, subjectID, daily, monthly)),
+		History: history,
+	}, nil
+}
+
+// Rollup 汇总用量，写入的是 Redis 中的累计值，多副本同时汇总结果一致
+func (s *quotaService) Rollup(ctx context.Context) error {
+	now := time.Now()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		usages, err := s.quotaRepo.ListDaily(ctx, day)
+		if err != nil {
+			return err
+		}
+		if err := s.usageRepo.Upsert(ctx, usages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 停止定时汇总
+func (s *quotaService) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
+
+func (s *quotaService) rollupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg().RollupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.rollupOnce()
+		case <-s.stop:
+			s.rollupOnce()
+			return
+		}
+	}
+}
+
+// rollupOnce 汇总一次用量，panic 时记录后等待下一周期
+func (s *quotaService) rollupOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	defer panics.Recover(ctx, panics.SourceQuota, nil)
+	if err := s.Rollup(ctx); err != nil {
+		slog.Error("failed to roll up token usage", "error", err)
+	}
+}
+
+type quotaTarget struct {
+	scope string
+	id    string
+}
+
+func (s *quotaService) targets(subject *QuotaSubject) []quotaTarget {
+	targets := []quotaTarget{{scope: model.QuotaScopeUser, id: subject.UserID}}
+	if subject.GuestIP != "" {
+		targets[0] = quotaTarget{scope: model.QuotaScopeGuest, id: subject.GuestIP}
+	}
+	if subject.MerchantID != "" {
+		targets = append(targets, quotaTarget{scope: model.QuotaScopeMerchant, id: subject.MerchantID})
+	}
+	return targets
+}
+
+// usages 主体的日、月用量与对应额度
+func (s *quotaService) usages(scope, subjectID string, daily, monthly int64) []model.QuotaUsage {
+	dailyLimit, monthlyLimit := s.cfg().UserDailyTokens, s.cfg().UserMonthlyTokens
+	switch scope {
+	case model.QuotaScopeGuest:
+		dailyLimit, monthlyLimit = s.cfg().GuestDailyTokens, s.cfg().GuestMonthlyTokens
+	case model.QuotaScopeMerchant:
+		dailyLimit, monthlyLimit = s.cfg().MerchantDailyTokens, s.cfg().MerchantMonthlyTokens
+	}
+	return []model.QuotaUsage{
+		newQuotaUsage(scope, subjectID, model.QuotaPeriodDaily, daily, dailyLimit),
+		newQuotaUsage(scope, subjectID, model.QuotaPeriodMonthly, monthly, monthlyLimit),
+	}
+}
+
+// status 按占比最高的一项确定配额状态
+func (s *quotaService) status(usages []model.QuotaUsage) *model.QuotaStatus {
+	var maxRatio float64
+	for _, u := range usages {
+		if u.Ratio > maxRatio {
+			maxRatio = u.Ratio
+		}
+	}
+
+	level := model.QuotaLevelNormal
+	switch {
+	case maxRatio >= 1:
+		level = model.QuotaLevelExceeded
+	case s.cfg().DegradeRatio > 0 && maxRatio >= s.cfg().DegradeRatio:
+		level = model.QuotaLevelDegraded
+	case s.cfg().WarnRatio > 0 && maxRatio >= s.cfg().WarnRatio:
+		level = model.QuotaLevelWarning
+	}
+	if usages == nil {
+		usages = []model.QuotaUsage{}
+	}
+	return &model.QuotaStatus{Level: level, Usages: usages}
+}
+
+// crossedWarning 本次累加是否使用量从预警线以下达到预警线
+func (s *quotaService) crossedWarning(usage model.QuotaUsage, tokens int64) bool {
+	if usage.Limit <= 0 || s.cfg().WarnRatio <= 0 {
+		return false
+	}
+	threshold := s.cfg().WarnRatio * float64(usage.Limit)
+	return float64(usage.Used-tokens) < threshold && float64(usage.Used) >= threshold
+}
+
+// emitWarning 发出预警事件，发布失败不影响本轮对话
+func (s *quotaService) emitWarning(ctx context.Context, usage model.QuotaUsage, at time.Time) {
+	event := &model.QuotaEvent{
+		Type:       model.QuotaEventWarning,
+		QuotaUsage: usage,
+		At:         at,
+	}
+	log := logger.FromContext(ctx)
+	log.Warn("token quota warning",
+		"scope", usage.Scope, "subject_id", usage.SubjectID, "period", usage.Period,
+		"used", usage.Used, "limit", usage.Limit, "ratio", usage.Ratio)
+
+	if s.cfg().EventChannel == "" {
+		return
+	}
+	if err := s.quotaRepo.Publish(ctx, s.cfg().EventChannel, event); err != nil {
+		log.Error("failed to publish quota event", "error", err)
+	}
+}
+
+func newQuotaUsage(scope, subjectID, period string, used, limit int64) model.QuotaUsage {
+	usage := model.QuotaUsage{
+		Scope:     scope,
+		SubjectID: subjectID,
+		Period:    period,
+		Used:      used,
+		Limit:     limit,
+	}
+	if limit > 0 {
+		usage.Ratio = float64(used) / float64(limit)
+	}
+	return usage
+}
+
+// cfg 当前生效的配额配置
+func (s *quotaService) cfg() *config.QuotaConfig {
+	return &s.store.Get().Business.Quota
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// recordingQuotaRepo 基于 miniredis 的配额计数，记录发布的事件
+type recordingQuotaRepo struct {
+	repository.QuotaRepository
+	mu     sync.Mutex
+	events []*model.QuotaEvent
+}
+
+func (r *recordingQuotaRepo) Publish(ctx context.Context, channel string, event *model.QuotaEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func newTestQuotaService(t *testing.T, mutate func(*config.QuotaConfig)) (QuotaService, *recordingQuotaRepo) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := &config.Config{}
+	cfg.Business.Quota = config.QuotaConfig{
+		Enabled:             true,
+		UserDailyTokens:     1000,
+		UserMonthlyTokens:   10000,
+		GuestDailyTokens:    100,
+		MerchantDailyTokens: 5000,
+		WarnRatio:           0.8,
+		DegradeRatio:        0.9,
+		EventChannel:        "quota_events",
+	}
+	if mutate != nil {
+		mutate(&cfg.Business.Quota)
+	}
+	repo := &recordingQuotaRepo{QuotaRepository: repository.NewQuotaRepository(rdb)}
+	s := NewQuotaService(repo, nil, config.NewStore(cfg))
+	t.Cleanup(s.Close)
+	return s, repo
+}
+
+func TestQuotaServiceLevels(t *testing.T) {
+	s, repo := newTestQuotaService(t, nil)
+	ctx := context.Background()
+	subject := &QuotaSubject{UserID: "user_1"}
+
+	steps := []struct {
+		tokens     int
+		wantLevel  string
+		wantEvents int
+	}{
+		{tokens: 0, wantLevel: model.QuotaLevelNormal},
+		{tokens: 799, wantLevel: model.QuotaLevelNormal},
+		{tokens: 1, wantLevel: model.QuotaLevelWarning, wantEvents: 1},
+		{tokens: 50, wantLevel: model.QuotaLevelWarning, wantEvents: 1},
+		{tokens: 50, wantLevel: model.QuotaLevelDegraded, wantEvents: 1},
+		{tokens: 100, wantLevel: model.QuotaLevelExceeded, wantEvents: 1},
+	}
+	for i, step := range steps {
+		status, err := s.Record(ctx, subject, step.tokens)
+		if err != nil {
+			t.Fatalf("step %d: Record() error = %v", i, err)
+		}
+		if status.Level != step.wantLevel {
+			t.Errorf("step %d: level = %s, want %s (usages %+v)", i, status.Level, step.wantLevel, status.Usages)
+		}
+		if len(repo.events) != step.wantEvents {
+			t.Errorf("step %d: events = %d, want %d", i, len(repo.events), step.wantEvents)
+		}
+		checked, err := s.Check(ctx, subject)
+		if err != nil || checked.Level != status.Level {
+			t.Errorf("step %d: Check() = %v, %v, want level %s", i, checked, err, status.Level)
+		}
+	}
+
+	event := repo.events[0]
+	if event.Type != model.QuotaEventWarning || event.Scope != model.QuotaScopeUser || event.Period != model.QuotaPeriodDaily || event.Used != 800 {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestQuotaServiceSubjects(t *testing.T) {
+	s, _ := newTestQuotaService(t, nil)
+	ctx := context.Background()
+
+	// 商家额度按所有用户累计，任一主体的最高占比决定等级
+	for _, userID := range []string{"user_1", "user_2", "user_3", "user_4", "user_5"} {
+		if _, err := s.Record(ctx, &QuotaSubject{UserID: userID, MerchantID: "merchant_a"}, 900); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	status, err := s.Check(ctx, &QuotaSubject{UserID: "user_6", MerchantID: "merchant_a"})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if status.Level != model.QuotaLevelDegraded || len(status.Usages) != 4 {
+		t.Errorf("merchant status = %+v, want degraded with user and merchant usages", status)
+	}
+	// 额度为 0 表示不限
+	if monthly := status.Usages[3]; monthly.Period != model.QuotaPeriodMonthly || monthly.Used != 4500 || monthly.Ratio != 0 {
+		t.Errorf("unlimited merchant monthly usage = %+v", monthly)
+	}
+
+	// 访客按IP计量，不同访客ID共享额度
+	for _, guestID := range []string{"guest_a", "guest_b"} {
+		status, err = s.Record(ctx, &QuotaSubject{UserID: guestID, GuestIP: "203.0.113.7"}, 50)
+		if err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if status.Level != model.QuotaLevelExceeded || status.Usages[0].Scope != model.QuotaScopeGuest || status.Usages[0].SubjectID != "203.0.113.7" {
+		t.Errorf("guest status = %+v, want exceeded by ip", status)
+	}
+
+	// 日、月额度分别计算
+	status, err = s.Record(ctx, &QuotaSubject{UserID: "user_7"}, 9000)
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if status.Level != model.QuotaLevelExceeded {
+		t.Errorf("level = %s, want exceeded by daily limit", status.Level)
+	}
+	if monthly := status.Usages[1]; monthly.Ratio != 0.9 {
+		t.Errorf("monthly usage = %+v", monthly)
+	}
+}
+
+func TestQuotaServiceDisabled(t *testing.T) {
+	s, repo := newTestQuotaService(t, func(c *config.QuotaConfig) { c.Enabled = false })
+
+	status, err := s.Record(context.Background(), &QuotaSubject{UserID: "user_1"}, 100000)
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if status.Level != model.QuotaLevelNormal || len(status.Usages) != 0 || len(repo.events) != 0 {
+		t.Errorf("status = %+v, events = %d", status, len(repo.events))
+	}
+}
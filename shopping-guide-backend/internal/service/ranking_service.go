@@ -0,0 +1,434 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+	"shopping-guide-backend/internal/search"
+)
+
+// 排序特征名
+const (
+	FeatureRelevance        = "relevance"
+	FeatureInterest         = "interest"
+	FeatureCategoryAffinity = "category_affinity"
+	FeatureDemographic      = "demographic"
+	FeaturePriceBand        = "price_band"
+	FeaturePopularity       = "popularity"
+	FeatureStock            = "stock"
+	FeatureDiversity        = "diversity"
+)
+
+// neutralScore 缺少数据时的中性特征值，既不加分也不明显压低
+const neutralScore = 0.5
+
+// RankingService 推荐排序服务
+// 位于候选召回与推荐工作流之间，结合画像与行为数据对候选商品重排
+type RankingService interface {
+	Rank(ctx context.Context, req *RankRequest) (*RankResult, error)
+	// Preview 按指定权重预览排序结果，供运营调权
+	Preview(ctx context.Context, req *model.RankingPreviewRequest) (*model.RankingPreviewResponse, error)
+}
+
+// RankRequest 排序请求
+type RankRequest struct {
+	UserID          string
+	Profile         model.UserProfile
+	Candidates      []model.Product
+	RelevanceScores map[string]float64 // 检索得分，product_id -> score
+	TopK            int
+	Weights         map[string]float64 // 为空时使用配置权重
+}
+
+// RankResult 排序结果，Breakdowns 与 Products 一一对应
+type RankResult struct {
+	Products   []model.Product
+	Breakdowns []model.ScoreBreakdown
+}
+
+// rankingService 排序服务实现
+type rankingService struct {
+	productRepo    repository.ProductRepository
+	recRepo        repository.RecommendationRepository
+	productService ProductService
+	profileService ProfileService
+	store          *config.Store
+}
+
+// NewRankingService 创建排序服务
+func NewRankingService(
+	productRepo repository.ProductRepository,
+	recRepo repository.RecommendationRepository,
+	productService ProductService,
+	profileService ProfileService,
+	store *config.Store,
+) RankingService {
+	return &rankingService{
+		productRepo:    productRepo,
+		recRepo:        recRepo,
+		productService: productService,
+		profileService: profileService,
+		store:          store,
+	}
+}
+
+// userSignals 用户行为信号
+type userSignals struct {
+	categoryWeight    map[string]float64
+	subCategoryWeight map[string]float64
+	purchasePrices    []float64
+}
+
+// Rank 排序
+// 先计算与顺序无关的特征，再按"当前得分 + 多样性"贪心选取，保证同一子类目不会霸屏
+func (s *rankingService) Rank(ctx context.Context, req *RankRequest) (*RankResult, error) {
+	weights := req.Weights
+	if weights == nil {
+		weights = s.configWeights()
+	}
+	topK := req.TopK
+	if topK <= 0 || topK > len(req.Candidates) {
+		topK = len(req.Candidates)
+	}
+
+	since := time.Now().Add(-s.cfg().Business.Ranking.HistoryWindow)
+	signals, err := s.loadUserSignals(ctx, req.UserID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]string, len(req.Candidates))
+	for i, p := range req.Candidates {
+		productIDs[i] = p.ProductID
+	}
+	popularity, err := s.recRepo.CountProductActions(ctx, productIDs, since)
+	if err != nil {
+		return nil, err
+	}
+	var maxPopularity int64
+	for _, n := range popularity {
+		if n > maxPopularity {
+			maxPopularity = n
+		}
+	}
+
+	relevance := normalizeRelevance(req.RelevanceScores)
+	interests := req.Profile.Interests
+
+	breakdowns := make([]model.ScoreBreakdown, len(req.Candidates))
+	for i := range req.Candidates {
+		p := &req.Candidates[i]
+		features := map[string]float64{
+			FeatureRelevance:        relevance[p.ProductID],
+			FeatureInterest:         interestScore(p, interests),
+			FeatureCategoryAffinity: categoryAffinity(p, signals),
+			FeatureDemographic:      demographicScore(p, &req.Profile),
+			FeaturePriceBand:        priceBandScore(p.Price, signals.purchasePrices),
+			FeaturePopularity:       popularityScore(popularity[p.ProductID], maxPopularity),
+			FeatureStock:            stockScore(p.Stock, s.cfg().Business.Ranking.LowStockThreshold),
+		}
+		breakdowns[i] = model.ScoreBreakdown{
+			ProductID: p.ProductID,
+			Features:  features,
+			Weighted:  make(map[string]float64, len(features)+1),
+		}
+		for name, value := range features {
+			contribution := weights[name] * value
+			breakdowns[i].Weighted[name] = contribution
+			breakdowns[i].Score += contribution
+		}
+	}
+
+	// 贪心选取：每轮按 基础得分 + 多样性得分 选最高者，已选子类目越多多样性得分越低
+	result := &RankResult{
+		Products:   make([]model.Product, 0, topK),
+		Breakdowns: make([]model.ScoreBreakdown, 0, topK),
+	}
+	selected := make([]bool, len(req.Candidates))
+	subCategoryCount := make(map[string]int)
+	for len(result.Products) < topK {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range req.Candidates {
+			if selected[i] {
+				continue
+			}
+			diversity := 1 / float64(1+subCategoryCount[req.Candidates[i].SubCategory])
+			score := breakdowns[i].Score + weights[FeatureDiversity]*diversity
+			if score > bestScore || (score == bestScore && req.Candidates[i].ProductID < req.Candidates[best].ProductID) {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		selected[best] = true
+		diversity := 1 / float64(1+subCategoryCount[req.Candidates[best].SubCategory])
+		subCategoryCount[req.Candidates[best].SubCategory]++
+
+		b := breakdowns[best]
+		b.Features[FeatureDiversity] = diversity
+		b.Weighted[FeatureDiversity] = weights[FeatureDiversity] * diversity
+		b.Score = bestScore
+		result.Products = append(result.Products, req.Candidates[best])
+		result.Breakdowns = append(result.Breakdowns, b)
+	}
+
+	return result, nil
+}
+
+// Preview 排序预览
+func (s *rankingService) Preview(ctx context.Context, req *model.RankingPreviewRequest) (*model.RankingPreviewResponse, error) {
+	topK := req.TopK
+	if topK <= 0 {
+		topK = s.cfg().Business.Product.TopK
+	}
+
+	weights := s.configWeights()
+	for name, w := range req.Weights {
+		if _, ok := weights[name]; !ok {
+			return nil, apperr.New(apperr.KindInvalidInput, fmt.Sprintf("unknown ranking feature: %s", name))
+		}
+		weights[name] = w
+	}
+
+	searchResp, err := s.productService.SearchProducts(ctx, &model.ProductSearchRequest{
+		Query: req.Query,
+		TopK:  topK * s.recallMultiplier(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	profile, err := s.profileService.GetProfile(ctx, req.UserID)
+	if errors.Is(err, ErrProfileNotFound) {
+		// 画像缺失时仍可预览行为与商品侧特征
+		profile = &model.UserProfile{UserID: req.UserID}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+
+	ranked, err := s.Rank(ctx, &RankRequest{
+		UserID:          req.UserID,
+		Profile:         *profile,
+		Candidates:      searchResp.Products,
+		RelevanceScores: searchResp.Scores,
+		TopK:            topK,
+		Weights:         weights,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.RankingPreviewResponse{
+		Weights:    weights,
+		Products:   ranked.Products,
+		Breakdowns: ranked.Breakdowns,
+	}, nil
+}
+
+// recallMultiplier 召回放大倍数
+func (s *rankingService) recallMultiplier() int {
+	if m := s.cfg().Business.Ranking.RecallMultiplier; m > 0 {
+		return m
+	}
+	return 1
+}
+
+// configWeights 配置权重转换为按特征名索引
+func (s *rankingService) configWeights() map[string]float64 {
+	w := s.cfg().Business.Ranking.Weights
+	return map[string]float64{
+		FeatureRelevance:        w.Relevance,
+		FeatureInterest:         w.Interest,
+		FeatureCategoryAffinity: w.CategoryAffinity,
+		FeatureDemographic:      w.Demographic,
+		FeaturePriceBand:        w.PriceBand,
+		FeaturePopularity:       w.Popularity,
+		FeatureStock:            w.Stock,
+		FeatureDiversity:        w.Diversity,
+	}
+}
+
+// loadUserSignals 从历史推荐行为中提取类目偏好和购买价位
+// 行为按漏斗深度加权，购买比点击更能代表偏好
+func (s *rankingService) loadUserSignals(ctx context.Context, userID string, since time.Time) (*userSignals, error) {
+	signals := &userSignals{
+		categoryWeight:    make(map[string]float64),
+		subCategoryWeight: make(map[string]float64),
+	}
+	if userID == "" {
+		return signals, nil
+	}
+
+	recs, err := s.recRepo.ListUserActions(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return signals, nil
+	}
+
+	ids := make([]string, 0, len(recs))
+	for _, r := range recs {
+		ids = append(ids, r.ProductID)
+	}
+	products, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.Product, len(products))
+	for i := range products {
+		byID[products[i].ProductID] = &products[i]
+	}
+
+	for _, r := range recs {
+		p, ok := byID[r.ProductID]
+		if !ok {
+			continue
+		}
+		weight := float64(model.UserActionRank(r.UserAction))
+		signals.categoryWeight[p.Category] += weight
+		signals.subCategoryWeight[p.SubCategory] += weight
+		if r.UserAction == model.UserActionPurchase {
+			signals.purchasePrices = append(signals.purchasePrices, p.Price)
+		}
+	}
+	return signals, nil
+}
+
+// normalizeRelevance 检索得分 min-max 归一化
+func normalizeRelevance(scores map[string]float64) map[string]float64 {
+	hits := make([]search.Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, search.Hit{ID: id, Score: score})
+	}
+	return search.NormalizeScores(hits)
+}
+
+// interestScore 兴趣匹配：命中一个兴趣 0.5 分，每多命中一个补足剩余差距的一半
+func interestScore(p *model.Product, interests []string) float64 {
+	if len(interests) == 0 {
+		return 0
+	}
+	text := strings.ToLower(search.ProductDocument(p).Text)
+	matched := 0
+	for _, interest := range interests {
+		interest = strings.ToLower(strings.TrimSpace(interest))
+		if interest != "" && strings.Contains(text, interest) {
+			matched++
+		}
+	}
+	return 1 - math.Pow(0.5, float64(matched))
+}
+
+// categoryAffinity 类目偏好：类目与子类目行为权重相对最大值的占比，各占一半
+func categoryAffinity(p *model.Product, signals *userSignals) float64 {
+	return 0.5*relativeWeight(signals.categoryWeight, p.Category) +
+		0.5*relativeWeight(signals.subCategoryWeight, p.SubCategory)
+}
+
+func relativeWeight(weights map[string]float64, key string) float64 {
+	var maxWeight float64
+	for _, w := range weights {
+		if w > maxWeight {
+			maxWeight = w
+		}
+	}
+	if maxWeight == 0 {
+		return 0
+	}
+	return weights[key] / maxWeight
+}
+
+// demographicScore 年龄/性别适配
+// 商品属性 gender（male/female/unisex）与 age_range（如 "18-35"）缺失时不参与计算
+func demographicScore(p *model.Product, profile *model.UserProfile) float64 {
+	var total float64
+	var count int
+
+	if gender, ok := p.Attributes["gender"].(string); ok && gender != "" && profile.Gender != "" {
+		count++
+		if gender == "unisex" || strings.EqualFold(gender, profile.Gender) {
+			total++
+		}
+	}
+
+	if ageRange, ok := p.Attributes["age_range"].(string); ok && profile.Age > 0 {
+		if low, high, ok := parseAgeRange(ageRange); ok {
+			count++
+			if profile.Age >= low && profile.Age <= high {
+				total++
+			}
+		}
+	}
+
+	if count == 0 {
+		return neutralScore
+	}
+	return total / float64(count)
+}
+
+func parseAgeRange(v string) (int, int, bool) {
+	parts := strings.SplitN(v, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	low, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	high, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil || low > high {
+		return 0, 0, false
+	}
+	return low, high, true
+}
+
+// priceBandScore 价位匹配：以历史购买价中位数为锚点，价格偏离一倍得分约 0.5
+func priceBandScore(price float64, purchasePrices []float64) float64 {
+	if len(purchasePrices) == 0 || price <= 0 {
+		return neutralScore
+	}
+	prices := append([]float64(nil), purchasePrices...)
+	sort.Float64s(prices)
+	median := prices[len(prices)/2]
+	if len(prices)%2 == 0 {
+		median = (prices[len(prices)/2-1] + prices[len(prices)/2]) / 2
+	}
+	if median <= 0 {
+		return neutralScore
+	}
+	return math.Exp(-math.Abs(math.Log(price / median)))
+}
+
+// popularityScore 热度：对数压缩后相对最大值的占比
+func popularityScore(count, maxCount int64) float64 {
+	if maxCount == 0 {
+		return 0
+	}
+	return math.Log1p(float64(count)) / math.Log1p(float64(maxCount))
+}
+
+// stockScore 库存：无货为 0，达到阈值为 1
+func stockScore(stock, threshold int) float64 {
+	if stock <= 0 {
+		return 0
+	}
+	if threshold <= 0 || stock >= threshold {
+		return 1
+	}
+	return float64(stock) / float64(threshold)
+}
+
+// cfg 当前生效的配置
+func (s *rankingService) cfg() *config.Config {
+	return s.store.Get()
+}
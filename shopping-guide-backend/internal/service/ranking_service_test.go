@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+)
+
+// fakeRecommendationRepo 固定的用户行为与商品热度
+type fakeRecommendationRepo struct {
+	repository.RecommendationRepository
+	actions    []model.ProductRecommendation
+	popularity map[string]int64
+}
+
+func (r *fakeRecommendationRepo) ListUserActions(ctx context.Context, userID string, since time.Time) ([]model.ProductRecommendation, error) {
+	var actions []model.ProductRecommendation
+	for _, a := range r.actions {
+		if a.UserID == userID {
+			actions = append(actions, a)
+		}
+	}
+	return actions, nil
+}
+
+func (r *fakeRecommendationRepo) CountProductActions(ctx context.Context, productIDs []string, since time.Time) (map[string]int64, error) {
+	return r.popularity, nil
+}
+
+func newTestRankingService(recRepo *fakeRecommendationRepo, products []model.Product) *rankingService {
+	cfg := &config.Config{}
+	cfg.Business.Ranking.HistoryWindow = 30 * 24 * time.Hour
+	cfg.Business.Ranking.LowStockThreshold = 10
+	return NewRankingService(&fakeProductRepo{products: products}, recRepo, nil, nil, config.NewStore(cfg)).(*rankingService)
+}
+
+func rankedIDs(result *RankResult) []string {
+	return productIDs(result.Products)
+}
+
+func TestRankingServiceDiversity(t *testing.T) {
+	s := newTestRankingService(&fakeRecommendationRepo{}, nil)
+	candidates := []model.Product{
+		{ProductID: "a1", SubCategory: "水壶"},
+		{ProductID: "a2", SubCategory: "水壶"},
+		{ProductID: "b1", SubCategory: "车灯"},
+		{ProductID: "c1", SubCategory: "头盔"},
+	}
+	relevance := map[string]float64{"a1": 1.0, "a2": 0.8, "b1": 0.7, "c1": 0}
+
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		want    []string
+	}{
+		{name: "relevance only", weights: map[string]float64{FeatureRelevance: 1}, want: []string{"a1", "a2", "b1"}},
+		{name: "diversity spreads sub categories", weights: map[string]float64{FeatureRelevance: 1, FeatureDiversity: 0.5}, want: []string{"a1", "b1", "a2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Rank(context.Background(), &RankRequest{
+				Candidates:      candidates,
+				RelevanceScores: relevance,
+				TopK:            3,
+				Weights:         tt.weights,
+			})
+			if err != nil {
+				t.Fatalf("Rank() error = %v", err)
+			}
+			if got := rankedIDs(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankingServicePersonalization(t *testing.T) {
+	history := testCatalog()
+	recRepo := &fakeRecommendationRepo{
+		actions: []model.ProductRecommendation{
+			{UserID: "user_1", ProductID: "helmet-001", UserAction: model.UserActionPurchase},
+			{UserID: "user_1", ProductID: "bottle-001", UserAction: model.UserActionView},
+		},
+		popularity: map[string]int64{"helmet-002": 9, "bottle-002": 0},
+	}
+	s := newTestRankingService(recRepo, history)
+	candidates := []model.Product{
+		{ProductID: "bottle-002", Name: "保温水壶", Category: "骑行", SubCategory: "水壶", Price: 79, Stock: 5},
+		{ProductID: "helmet-002", Name: "公路头盔", Category: "骑行", SubCategory: "头盔", Price: 219, Stock: 20},
+	}
+
+	result, err := s.Rank(context.Background(), &RankRequest{
+		UserID:     "user_1",
+		Candidates: candidates,
+		Weights:    map[string]float64{FeatureCategoryAffinity: 1},
+	})
+	if err != nil {
+		t.Fatalf("Rank() error = %v", err)
+	}
+	if got := rankedIDs(result); !reflect.DeepEqual(got, []string{"helmet-002", "bottle-002"}) {
+		t.Fatalf("ranked = %v, want helmet first for a helmet buyer", got)
+	}
+
+	helmet, bottle := result.Breakdowns[0], result.Breakdowns[1]
+	wantFeatures := map[string][2]float64{
+		FeatureCategoryAffinity: {1, 0.5 + 0.5*1.0/4},
+		FeaturePriceBand:        {math.Exp(-math.Abs(math.Log(219.0 / 199))), math.Exp(-math.Abs(math.Log(79.0 / 199)))},
+		FeaturePopularity:       {1, 0},
+		FeatureStock:            {1, 0.5},
+		FeatureDemographic:      {neutralScore, neutralScore},
+		FeatureInterest:         {0, 0},
+	}
+	for name, want := range wantFeatures {
+		if !approxEqual(helmet.Features[name], want[0]) || !approxEqual(bottle.Features[name], want[1]) {
+			t.Errorf("%s = %.3f/%.3f, want %.3f/%.3f", name, helmet.Features[name], bottle.Features[name], want[0], want[1])
+		}
+	}
+	if !approxEqual(helmet.Score, 1) || !approxEqual(helmet.Weighted[FeatureCategoryAffinity], 1) {
+		t.Errorf("helmet score = %.3f, weighted = %v", helmet.Score, helmet.Weighted)
+	}
+}
+
+func TestRankingServiceTopK(t *testing.T) {
+	s := newTestRankingService(&fakeRecommendationRepo{}, nil)
+	candidates := testCatalog()
+
+	for _, topK := range []int{0, 2, 10} {
+		result, err := s.Rank(context.Background(), &RankRequest{Candidates: candidates, TopK: topK})
+		if err != nil {
+			t.Fatalf("Rank() error = %v", err)
+		}
+		want := topK
+		if topK <= 0 || topK > len(candidates) {
+			want = len(candidates)
+		}
+		if len(result.Products) != want || len(result.Breakdowns) != want {
+			t.Errorf("TopK %d: %d products, %d breakdowns, want %d", topK, len(result.Products), len(result.Breakdowns), want)
+		}
+	}
+}
+
+func TestInterestScore(t *testing.T) {
+	p := &model.Product{Name: "碳纤维公路自行车", Category: "骑行", Description: "适合长途骑行和爬坡"}
+	tests := []struct {
+		interests []string
+		want      float64
+	}{
+		{nil, 0},
+		{[]string{"露营"}, 0},
+		{[]string{"骑行"}, 0.5},
+		{[]string{"骑行", " 爬坡 ", "露营"}, 0.75},
+		{[]string{"骑行", "爬坡", "碳纤维"}, 0.875},
+		{[]string{""}, 0},
+	}
+	for _, tt := range tests {
+		if got := interestScore(p, tt.interests); !approxEqual(got, tt.want) {
+			t.Errorf("interestScore(%v) = %v, want %v", tt.interests, got, tt.want)
+		}
+	}
+}
+
+func TestDemographicScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string]interface{}
+		profile    model.UserProfile
+		want       float64
+	}{
+		{name: "no attributes", profile: model.UserProfile{Gender: "female", Age: 25}, want: neutralScore},
+		{name: "gender match", attributes: map[string]interface{}{"gender": "Female"}, profile: model.UserProfile{Gender: "female"}, want: 1},
+		{name: "unisex", attributes: map[string]interface{}{"gender": "unisex"}, profile: model.UserProfile{Gender: "male"}, want: 1},
+		{name: "gender mismatch", attributes: map[string]interface{}{"gender": "male"}, profile: model.UserProfile{Gender: "female"}, want: 0},
+		{name: "age in range", attributes: map[string]interface{}{"age_range": "18-35"}, profile: model.UserProfile{Age: 35}, want: 1},
+		{name: "gender match age out of range", attributes: map[string]interface{}{"gender": "male", "age_range": "6 - 12"}, profile: model.UserProfile{Gender: "male", Age: 30}, want: 0.5},
+		{name: "unknown age", attributes: map[string]interface{}{"age_range": "18-35"}, want: neutralScore},
+		{name: "invalid range", attributes: map[string]interface{}{"age_range": "35-18"}, profile: model.UserProfile{Age: 20}, want: neutralScore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := demographicScore(&model.Product{Attributes: tt.attributes}, &tt.profile); got != tt.want {
+				t.Errorf("demographicScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceBandScore(t *testing.T) {
+	tests := []struct {
+		price  float64
+		prices []float64
+		want   float64
+	}{
+		{100, nil, neutralScore},
+		{0, []float64{100}, neutralScore},
+		{100, []float64{100}, 1},
+		{200, []float64{100}, 0.5},
+		{50, []float64{100}, 0.5},
+		{100, []float64{50, 300, 150, 100}, 0.8}, // 中位数 125
+	}
+	for _, tt := range tests {
+		if got := priceBandScore(tt.price, tt.prices); !approxEqual(got, tt.want) {
+			t.Errorf("priceBandScore(%v, %v) = %v, want %v", tt.price, tt.prices, got, tt.want)
+		}
+	}
+}
+
+func TestStockAndPopularityScore(t *testing.T) {
+	stockTests := []struct {
+		stock, threshold int
+		want             float64
+	}{
+		{0, 10, 0},
+		{-1, 10, 0},
+		{5, 10, 0.5},
+		{10, 10, 1},
+		{3, 0, 1},
+	}
+	for _, tt := range stockTests {
+		if got := stockScore(tt.stock, tt.threshold); got != tt.want {
+			t.Errorf("stockScore(%d, %d) = %v, want %v", tt.stock, tt.threshold, got, tt.want)
+		}
+	}
+
+	if got := popularityScore(5, 0); got != 0 {
+		t.Errorf("popularityScore(5, 0) = %v, want 0", got)
+	}
+	if got := popularityScore(9, 9); got != 1 {
+		t.Errorf("popularityScore(9, 9) = %v, want 1", got)
+	}
+	if got := popularityScore(3, 15); !approxEqual(got, 0.5) {
+		t.Errorf("popularityScore(3, 15) = %v, want 0.5", got)
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// stubProfileService GetProfile 返回固定画像或错误
+type stubProfileService struct {
+	ProfileService
+	profile *model.UserProfile
+	err     error
+}
+
+func (p *stubProfileService) GetProfile(ctx context.Context, userID string) (*model.UserProfile, error) {
+	return p.profile, p.err
+}
+
+func TestRankingServicePreview(t *testing.T) {
+	products, _ := newTestProductService(t, &fakeProductRepo{products: bikeCatalog()}, 0)
+	newPreviewService := func(profiles ProfileService) RankingService {
+		cfg := &config.Config{}
+		cfg.Business.Product.TopK = 2
+		cfg.Business.Ranking.Weights = config.RankingWeights{Relevance: 1, Stock: 0.2}
+		return NewRankingService(&fakeProductRepo{products: bikeCatalog()}, &fakeRecommendationRepo{}, products, profiles, config.NewStore(cfg))
+	}
+
+	s := newPreviewService(&fakeProfileService{})
+	resp, err := s.Preview(context.Background(), &model.RankingPreviewRequest{UserID: "user_1", Query: "自行车", Weights: map[string]float64{FeatureStock: 1}})
+	if err != nil {
+		t.Fatalf("Preview() without profile error = %v", err)
+	}
+	if resp.Weights[FeatureStock] != 1 || resp.Weights[FeatureRelevance] != 1 || len(resp.Products) != 2 || len(resp.Breakdowns) != 2 {
+		t.Errorf("resp = %+v", resp)
+	}
+
+	_, err = s.Preview(context.Background(), &model.RankingPreviewRequest{UserID: "user_1", Query: "自行车", Weights: map[string]float64{"price": 1}})
+	if !apperr.Is(err, apperr.KindInvalidInput) || apperr.Message(err) != "unknown ranking feature: price" {
+		t.Errorf("Preview() unknown feature error = %v, want invalid input", err)
+	}
+
+	dbErr := errors.New("connection refused")
+	_, err = newPreviewService(&stubProfileService{err: dbErr}).Preview(context.Background(), &model.RankingPreviewRequest{UserID: "user_1", Query: "自行车"})
+	if !errors.Is(err, dbErr) {
+		t.Errorf("Preview() profile error = %v, want %v", err, dbErr)
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrRecommendationNotFound 推荐记录不存在
+var ErrRecommendationNotFound error = apperr.New(apperr.KindNotFound, "recommendation not found")
+
+// RecommendationService 推荐记录与转化统计服务
+type RecommendationService interface {
+	// Record 为返回给用户的每个推荐商品写入一条记录，返回回填了 RecommendationID 的商品列表
+	Record(ctx context.Context, req *RecordRecommendationRequest) ([]model.RecommendedProduct, error)
+	// TrackEvent 记录前端上报的用户行为，只能上报调用方自己的推荐记录，同一推荐的同一行为只记一次
+	TrackEvent(ctx context.Context, recID int64, action string) error
+	// ConversionMetrics 按 tool/product/prompt_version 统计转化
+	ConversionMetrics(ctx context.Context, groupBy string, since time.Time) ([]model.ConversionStat, error)
+}
+
+// RecordRecommendationRequest 推荐记录请求
+type RecordRecommendationRequest struct {
+	SessionID     string
+	UserID        string
+	Tool          string
+	PromptVersion string
+	Products      []model.RecommendedProduct
+}
+
+// recommendationService 推荐服务实现
+type recommendationService struct {
+	repo repository.RecommendationRepository
+}
+
+// NewRecommendationService 创建推荐服务
+func NewRecommendationService(repo repository.RecommendationRepository) RecommendationService {
+	return &recommendationService{
+		repo: repo,
+	}
+}
+
+// Record 写入推荐记录
+func (s *recommendationService) Record(ctx context.Context, req *RecordRecommendationRequest) ([]model.RecommendedProduct, error) {
+	if len(req.Products) == 0 {
+		return req.Products, nil
+	}
+
+	recs := make([]*model.ProductRecommendation, 0, len(req.Products))
+	for i, p := range req.Products {
+		recs = append(recs, &model.ProductRecommendation{
+			SessionID:     req.SessionID,
+			UserID:        req.UserID,
+			ProductID:     p.ProductID,
+			Reason:        p.Reason,
+			Tool:          req.Tool,
+			PromptVersion: req.PromptVersion,
+			Position:      i,
+		})
+	}
+	if err := s.repo.CreateBatch(ctx, recs); err != nil {
+		return nil, err
+	}
+
+	products := make([]model.RecommendedProduct, len(req.Products))
+	for i, p := range req.Products {
+		p.RecommendationID = recs[i].RecID
+		products[i] = p
+	}
+	return products, nil
+}
+
+// TrackEvent 记录用户行为
+func (s *recommendationService) TrackEvent(ctx context.Context, recID int64, action string) error {
+	if model.UserActionRank(action) == 0 {
+		return apperr.New(apperr.KindInvalidInput, fmt.Sprintf("invalid user action: %s", action))
+	}
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	rec, err := s.repo.GetByID(ctx, recID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRecommendationNotFound
+		}
+		return fmt.Errorf("failed to get recommendation: %w", err)
+	}
+	if rec.UserID != principal.UserID {
+		return apperr.Wrap(apperr.KindForbidden, "recommendation belongs to another user", fmt.Errorf("%w: recommendation %d", ErrForbidden, rec.RecID))
+	}
+
+	err = s.repo.RecordEvent(ctx, &model.RecommendationEvent{
+		RecID:  rec.RecID,
+		UserID: rec.UserID,
+		Action: action,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRecommendationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ConversionMetrics 转化统计
+func (s *recommendationService) ConversionMetrics(ctx context.Context, groupBy string, since time.Time) ([]model.ConversionStat, error) {
+	return s.repo.AggregateConversion(ctx, groupBy, since)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// memoryRecommendationRepo 内存推荐记录，按写入顺序分配 RecID
+type memoryRecommendationRepo struct {
+	repository.RecommendationRepository
+	recs   []*model.ProductRecommendation
+	events []*model.RecommendationEvent
+}
+
+func (r *memoryRecommendationRepo) CreateBatch(ctx context.Context, recs []*model.ProductRecommendation) error {
+	for _, rec := range recs {
+		rec.RecID = int64(len(r.recs) + 1)
+		r.recs = append(r.recs, rec)
+	}
+	return nil
+}
+
+func (r *memoryRecommendationRepo) GetByID(ctx context.Context, recID int64) (*model.ProductRecommendation, error) {
+	if recID <= 0 || int(recID) > len(r.recs) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.recs[recID-1], nil
+}
+
+func (r *memoryRecommendationRepo) RecordEvent(ctx context.Context, event *model.RecommendationEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestRecommendationServiceRecord(t *testing.T) {
+	repo := &memoryRecommendationRepo{}
+	s := NewRecommendationService(repo)
+
+	products, err := s.Record(context.Background(), &RecordRecommendationRequest{
+		SessionID:     "sess-1",
+		UserID:        "user_1",
+		Tool:          model.ToolProductRecommendation,
+		PromptVersion: "v2",
+		Products: []model.RecommendedProduct{
+			{ProductID: "bottle-001", Reason: "轻便"},
+			{ProductID: "light-001", Reason: "夜骑"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if products[0].RecommendationID != 1 || products[1].RecommendationID != 2 {
+		t.Errorf("recommendation ids = %d, %d, want 1, 2", products[0].RecommendationID, products[1].RecommendationID)
+	}
+	for i, rec := range repo.recs {
+		if rec.Position != i || rec.Tool != model.ToolProductRecommendation || rec.PromptVersion != "v2" || rec.SessionID != "sess-1" {
+			t.Errorf("rec %d = %+v", i, rec)
+		}
+	}
+
+	if products, err := s.Record(context.Background(), &RecordRecommendationRequest{UserID: "user_1"}); err != nil || len(products) != 0 || len(repo.recs) != 2 {
+		t.Errorf("Record() without products = %v, %v; %d records", products, err, len(repo.recs))
+	}
+}
+
+func TestRecommendationServiceTrackEvent(t *testing.T) {
+	repo := &memoryRecommendationRepo{}
+	s := NewRecommendationService(repo)
+	if _, err := s.Record(context.Background(), &RecordRecommendationRequest{
+		UserID:   "user_1",
+		Products: []model.RecommendedProduct{{ProductID: "bottle-001"}},
+	}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		recID    int64
+		action   string
+		wantErr  error
+		wantKind apperr.Kind
+	}{
+		{name: "click", ctx: userContext("user_1"), recID: 1, action: model.UserActionClick},
+		{name: "purchase", ctx: userContext("user_1"), recID: 1, action: model.UserActionPurchase},
+		{name: "unknown action", ctx: userContext("user_1"), recID: 1, action: "share", wantKind: apperr.KindInvalidInput},
+		{name: "no principal", ctx: context.Background(), recID: 1, action: model.UserActionClick, wantErr: ErrUnauthenticated, wantKind: apperr.KindUnauthorized},
+		{name: "missing recommendation", ctx: userContext("user_1"), recID: 9, action: model.UserActionClick, wantErr: ErrRecommendationNotFound, wantKind: apperr.KindNotFound},
+		{name: "another user's recommendation", ctx: userContext("user_2"), recID: 1, action: model.UserActionClick, wantErr: ErrForbidden, wantKind: apperr.KindForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(repo.events)
+			err := s.TrackEvent(tt.ctx, tt.recID, tt.action)
+			if tt.wantKind == apperr.KindInternal && tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("TrackEvent() error = %v", err)
+				}
+				event := repo.events[len(repo.events)-1]
+				if len(repo.events) != before+1 || event.RecID != tt.recID || event.UserID != "user_1" || event.Action != tt.action {
+					t.Errorf("events = %+v", repo.events)
+				}
+				return
+			}
+			if !apperr.Is(err, tt.wantKind) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("TrackEvent() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.events) != before {
+				t.Errorf("event recorded on error")
+			}
+		})
+	}
+}
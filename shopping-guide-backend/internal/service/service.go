@@ -11,4 +11,7 @@ type ProductService interface {
 	GetProduct(ctx context.Context, productID string) (*model.Product, error)
 	SearchProducts(ctx context.Context, req *model.ProductSearchRequest) (*model.ProductSearchResponse, error)
 	GetProductStorage(ctx context.Context) (*model.ProductStorage, error)
+	RebuildIndex(ctx context.Context) error
+	// MatchProducts 识别文本中按ID或名称提及的商品库商品
+	MatchProducts(ctx context.Context, text string) ([]model.ProductMatch, error)
 }
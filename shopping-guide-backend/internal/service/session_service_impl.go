@@ -2,10 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"shopping-guide-backend/internal/auth"
 	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/metrics"
 	"shopping-guide-backend/internal/model"
 	"shopping-guide-backend/internal/repository"
+	"shopping-guide-backend/internal/style"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,32 +20,42 @@ type SessionService interface {
 	CreateSession(ctx context.Context, req *model.SessionCreateRequest) (*model.Session, error)
 	GetSession(ctx context.Context, sessionID string) (*model.Session, error)
 	SaveSession(ctx context.Context, session *model.Session) error
-	//DeleteSession(ctx context.Context, sessionID string) error
+	DeleteSession(ctx context.Context, sessionID string) error
 }
 
 type sessionServiceImpl struct {
 	repo        repository.SessionRepository
 	productRepo repository.ProductRepository
-	cfg         *config.Config
+	styles      *style.Registry
+	store       *config.Store
 }
 
 // NewSessionServiceImpl 创建会话服务实现
 func NewSessionServiceImpl(
 	repo repository.SessionRepository,
 	productRepo repository.ProductRepository,
-	cfg *config.Config,
+	styles *style.Registry,
+	store *config.Store,
 ) SessionService {
 	return &sessionServiceImpl{
 		repo:        repo,
 		productRepo: productRepo,
-		cfg:         cfg,
+		styles:      styles,
+		store:       store,
 	}
 }
 
 // CreateSession 创建新会话
 func (s *sessionServiceImpl) CreateSession(ctx context.Context, req *model.SessionCreateRequest) (*model.Session, error) {
+	if err := validateStyle(s.styles, req.Style); err != nil {
+		return nil, err
+	}
 
 	sessionID := uuid.New().String()
+	merchantID := req.MerchantID
+	if merchantID == "" {
+		merchantID = s.cfg().Business.Session.MerchantID
+	}
 
 	now := time.Now()
 
@@ -50,18 +64,22 @@ func (s *sessionServiceImpl) CreateSession(ctx context.Context, req *model.Sessi
 		UserID:              req.UserID,
 		Messages:            []model.Message{}, // 初始为空
 		BusinessInstruction: req.BusinessInstruction,
-		UserProfile: model.UserProfile{
-			PreferredStyle: s.cfg.Business.Session.DefaultStyle,
-		},
-		CreatedAt: now,
-		UpdatedAt: now,
-		ExpiresAt: now.Add(s.cfg.Redis.SessionTTL),
+		Style:               req.Style,
+		MerchantID:          merchantID,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+		ExpiresAt:           now.Add(s.cfg().Redis.SessionTTL),
 	}
 
 	//保存会话数据
 	if err := s.repo.Save(ctx, session); err != nil {
 		return nil, fmt.Errorf("failed to save session: %w", err)
 	}
+	userType := "user"
+	if auth.IsGuestID(req.UserID) {
+		userType = "guest"
+	}
+	metrics.SessionsCreated.WithLabelValues(userType).Inc()
 
 	return session, nil
 
@@ -70,7 +88,7 @@ func (s *sessionServiceImpl) CreateSession(ctx context.Context, req *model.Sessi
 func (s *sessionServiceImpl) GetSession(ctx context.Context, sessionID string) (*model.Session, error) {
 	session, err := s.repo.Get(ctx, sessionID)
 	if err != nil {
-		if err.Error() == "session not found" {
+		if errors.Is(err, repository.ErrSessionNotFound) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get session: %w", err)
@@ -81,3 +99,16 @@ func (s *sessionServiceImpl) GetSession(ctx context.Context, sessionID string) (
 func (s *sessionServiceImpl) SaveSession(ctx context.Context, session *model.Session) error {
 	return s.repo.Save(ctx, session)
 }
+
+func (s *sessionServiceImpl) DeleteSession(ctx context.Context, sessionID string) error {
+	if err := s.repo.Delete(ctx, sessionID); err != nil {
+		return err
+	}
+	metrics.SessionsDeleted.Inc()
+	return nil
+}
+
+// cfg 当前生效的配置
+func (s *sessionServiceImpl) cfg() *config.Config {
+	return s.store.Get()
+}
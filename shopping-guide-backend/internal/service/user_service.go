@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUserExists 邮箱或手机号已被注册
+	ErrUserExists error = apperr.New(apperr.KindConflict, "email or phone already registered")
+	// ErrInvalidCredentials 账号或密码错误
+	ErrInvalidCredentials error = apperr.New(apperr.KindUnauthorized, "invalid account or password")
+	// ErrInvalidToken 刷新令牌无效或对应用户不存在
+	ErrInvalidToken error = apperr.New(apperr.KindUnauthorized, "invalid refresh token")
+)
+
+// userIDPrefix 注册用户ID前缀，与访客ID（guest_）区分
+const userIDPrefix = "user_"
+
+// UserService 用户账号服务接口
+type UserService interface {
+	// Register 注册用户并创建默认画像，返回登录令牌
+	Register(ctx context.Context, req *model.RegisterRequest) (*model.TokenResponse, error)
+	// Login 邮箱或手机号 + 密码登录
+	Login(ctx context.Context, req *model.LoginRequest) (*model.TokenResponse, error)
+	// Refresh 用 refresh token 换取新的令牌
+	Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error)
+}
+
+// userService 用户账号服务实现
+type userService struct {
+	repo   repository.UserRepository
+	tokens *auth.TokenManager
+}
+
+// NewUserService 创建用户账号服务
+func NewUserService(repo repository.UserRepository, tokens *auth.TokenManager) UserService {
+	return &userService{
+		repo:   repo,
+		tokens: tokens,
+	}
+}
+
+// Register 注册用户
+func (s *userService) Register(ctx context.Context, req *model.RegisterRequest) (*model.TokenResponse, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	userID := userIDPrefix + uuid.New().String()
+	user := &model.User{
+		UserID:       userID,
+		Username:     strings.TrimSpace(req.Username),
+		Email:        optionalString(normalizeEmail(req.Email)),
+		Phone:        optionalString(strings.TrimSpace(req.Phone)),
+		PasswordHash: string(hash),
+		// 不设置偏好风格，对话时按商家默认、全局默认兜底
+		Profile: &model.UserProfile{
+			UserID:    userID,
+			Interests: []string{},
+			UpdatedAt: time.Now(),
+		},
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateUser) {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return s.issue(user)
+}
+
+// Login 登录，账号包含 @ 时按邮箱查找，否则按手机号查找
+func (s *userService) Login(ctx context.Context, req *model.LoginRequest) (*model.TokenResponse, error) {
+	account := strings.TrimSpace(req.Account)
+
+	var user *model.User
+	var err error
+	if strings.Contains(account, "@") {
+		user, err = s.repo.GetByEmail(ctx, normalizeEmail(account))
+	} else {
+		user, err = s.repo.GetByPhone(ctx, account)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.issue(user)
+}
+
+// Refresh 刷新令牌，同时签发新的 refresh token
+func (s *userService) Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	claims, err := s.tokens.Parse(refreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.repo.GetByID(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return s.issue(user)
+}
+
+func (s *userService) issue(user *model.User) (*model.TokenResponse, error) {
+	pair, err := s.tokens.Issue(user.UserID, user.Roles)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TokenResponse{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(pair.ExpiresIn / time.Second),
+		User:         user,
+	}, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// optionalString 空字符串存为 NULL，避免触发唯一索引冲突
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"shopping-guide-backend/internal/apperr"
+	"shopping-guide-backend/internal/auth"
+	"shopping-guide-backend/internal/config"
+	"shopping-guide-backend/internal/model"
+	"shopping-guide-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// memoryUserRepo 内存用户库，邮箱与手机号唯一
+type memoryUserRepo struct {
+	users []*model.User
+}
+
+func (r *memoryUserRepo) find(match func(u *model.User) bool) (*model.User, error) {
+	for _, u := range r.users {
+		if match(u) {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepo) GetByID(ctx context.Context, userID string) (*model.User, error) {
+	return r.find(func(u *model.User) bool { return u.UserID == userID })
+}
+
+func (r *memoryUserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.find(func(u *model.User) bool { return u.Email != nil && *u.Email == email })
+}
+
+func (r *memoryUserRepo) GetByPhone(ctx context.Context, phone string) (*model.User, error) {
+	return r.find(func(u *model.User) bool { return u.Phone != nil && *u.Phone == phone })
+}
+
+func (r *memoryUserRepo) Create(ctx context.Context, user *model.User) error {
+	for _, u := range r.users {
+		if (user.Email != nil && u.Email != nil && *u.Email == *user.Email) ||
+			(user.Phone != nil && u.Phone != nil && *u.Phone == *user.Phone) {
+			return repository.ErrDuplicateUser
+		}
+	}
+	r.users = append(r.users, user)
+	return nil
+}
+
+func (r *memoryUserRepo) Update(ctx context.Context, user *model.User) error {
+	return nil
+}
+
+func newTestUserService(t *testing.T) (UserService, *memoryUserRepo, *auth.TokenManager) {
+	t.Helper()
+	tokens, err := auth.NewTokenManager(&config.AuthConfig{
+		JWTSecret:     "test-secret",
+		TokenExpire:   time.Hour,
+		RefreshExpire: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenManager() error = %v", err)
+	}
+	repo := &memoryUserRepo{}
+	return NewUserService(repo, tokens), repo, tokens
+}
+
+func TestUserServiceRegister(t *testing.T) {
+	s, repo, tokens := newTestUserService(t)
+	ctx := context.Background()
+
+	resp, err := s.Register(ctx, &model.RegisterRequest{Username: " 小王 ", Email: " Wang@Example.com ", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	user := repo.users[0]
+	if !strings.HasPrefix(user.UserID, userIDPrefix) || user.Username != "小王" || *user.Email != "wang@example.com" || user.Phone != nil {
+		t.Errorf("user = %+v", user)
+	}
+	if user.PasswordHash == "" || user.PasswordHash == "password123" {
+		t.Errorf("password hash = %q", user.PasswordHash)
+	}
+	if user.Profile == nil || user.Profile.UserID != user.UserID || user.Profile.PreferredStyle != "" {
+		t.Errorf("default profile = %+v", user.Profile)
+	}
+	if resp.TokenType != "Bearer" || resp.ExpiresIn != 3600 || resp.User.UserID != user.UserID {
+		t.Errorf("resp = %+v", resp)
+	}
+	claims, err := tokens.Parse(resp.AccessToken, auth.TokenTypeAccess)
+	if err != nil || claims.Subject != user.UserID {
+		t.Errorf("access token claims = %+v, %v", claims, err)
+	}
+
+	_, err = s.Register(ctx, &model.RegisterRequest{Email: "wang@example.com", Password: "password456"})
+	if !errors.Is(err, ErrUserExists) || !apperr.Is(err, apperr.KindConflict) {
+		t.Errorf("Register() duplicate email error = %v, want ErrUserExists", err)
+	}
+}
+
+func TestUserServiceLogin(t *testing.T) {
+	s, _, _ := newTestUserService(t)
+	ctx := context.Background()
+	if _, err := s.Register(ctx, &model.RegisterRequest{Email: "wang@example.com", Phone: "13800000000", Password: "password123"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		account  string
+		password string
+		wantErr  error
+	}{
+		{name: "email", account: "WANG@example.com ", password: "password123"},
+		{name: "phone", account: "13800000000", password: "password123"},
+		{name: "wrong password", account: "wang@example.com", password: "password124", wantErr: ErrInvalidCredentials},
+		{name: "unknown account", account: "li@example.com", password: "password123", wantErr: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Login(ctx, &model.LoginRequest{Account: tt.account, Password: tt.password})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !apperr.Is(err, apperr.KindUnauthorized) {
+					t.Errorf("Login() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || resp.AccessToken == "" {
+				t.Errorf("Login() = %+v, %v", resp, err)
+			}
+		})
+	}
+}
+
+func TestUserServiceRefresh(t *testing.T) {
+	s, repo, tokens := newTestUserService(t)
+	ctx := context.Background()
+	registered, err := s.Register(ctx, &model.RegisterRequest{Phone: "13800000000", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	resp, err := s.Refresh(ctx, registered.RefreshToken)
+	if err != nil || resp.User.UserID != repo.users[0].UserID {
+		t.Fatalf("Refresh() = %+v, %v", resp, err)
+	}
+
+	// access token 不能用于刷新
+	if _, err := s.Refresh(ctx, registered.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh(access token) error = %v, want ErrInvalidToken", err)
+	}
+	// 用户已不存在
+	orphan, err := tokens.Issue("user_deleted", nil)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if _, err := s.Refresh(ctx, orphan.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh(deleted user) error = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,246 @@
+package style
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Style 导购风格（人设）定义
+type Style struct {
+	Name         string       `yaml:"-" json:"name"`
+	DisplayName  string       `yaml:"display_name" json:"display_name"`
+	SystemPrompt string       `yaml:"system_prompt" json:"system_prompt"` // 注入工作流的人设提示词片段
+	Emoji        EmojiPolicy  `yaml:"emoji" json:"emoji"`
+	Length       LengthPolicy `yaml:"length" json:"length"`
+	Examples     []Example    `yaml:"examples" json:"examples"`
+}
+
+// EmojiPolicy emoji 使用策略
+type EmojiPolicy struct {
+	Enabled bool     `yaml:"enabled" json:"enabled"`
+	Max     int      `yaml:"max" json:"max"`         // 单条回复最多保留的 emoji 数，0 表示不限
+	Palette []string `yaml:"palette" json:"palette"` // 推荐使用的 emoji
+}
+
+// LengthPolicy 回复长度策略
+type LengthPolicy struct {
+	MaxChars int `yaml:"max_chars" json:"max_chars"` // 最大字符数，0 表示不限
+}
+
+// Example 示例问答
+type Example struct {
+	Question string `yaml:"question" json:"question"`
+	Answer   string `yaml:"answer" json:"answer"`
+}
+
+// file 风格定义文件结构
+type file struct {
+	Styles    map[string]*Style `yaml:"styles"`
+	Merchants map[string]string `yaml:"merchants"`
+}
+
+// Registry 风格注册表，支持整体重新加载
+type Registry struct {
+	catalog atomic.Pointer[catalog]
+}
+
+// catalog 一次加载得到的风格定义，加载后只读
+type catalog struct {
+	styles       map[string]*Style
+	merchants    map[string]string
+	defaultStyle string
+}
+
+// Load 从 YAML 文件加载风格定义，defaultStyle 为兜底风格，必须在文件中定义
+func Load(path, defaultStyle string) (*Registry, error) {
+	c, err := loadCatalog(path, defaultStyle)
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{}
+	r.catalog.Store(c)
+	return r, nil
+}
+
+// Reload 重新加载风格定义，加载失败时保留原有定义
+func (r *Registry) Reload(path, defaultStyle string) error {
+	c, err := loadCatalog(path, defaultStyle)
+	if err != nil {
+		return err
+	}
+	r.catalog.Store(c)
+	return nil
+}
+
+func loadCatalog(path, defaultStyle string) (*catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read style file: %w", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse style file: %w", err)
+	}
+	if len(f.Styles) == 0 {
+		return nil, fmt.Errorf("no style defined in %s", path)
+	}
+
+	c := &catalog{
+		styles:       make(map[string]*Style, len(f.Styles)),
+		merchants:    make(map[string]string, len(f.Merchants)),
+		defaultStyle: defaultStyle,
+	}
+	for name, s := range f.Styles {
+		if s == nil {
+			return nil, fmt.Errorf("style %s is empty", name)
+		}
+		s.Name = name
+		c.styles[name] = s
+	}
+	for merchantID, name := range f.Merchants {
+		if _, ok := c.styles[name]; !ok {
+			return nil, fmt.Errorf("merchant %s uses undefined style %s", merchantID, name)
+		}
+		c.merchants[merchantID] = name
+	}
+	if _, ok := c.styles[defaultStyle]; !ok {
+		return nil, fmt.Errorf("default style %s is not defined in %s", defaultStyle, path)
+	}
+	return c, nil
+}
+
+// Get 按名称获取风格
+func (r *Registry) Get(name string) (*Style, bool) {
+	s, ok := r.catalog.Load().styles[name]
+	return s, ok
+}
+
+// Has 风格是否存在
+func (r *Registry) Has(name string) bool {
+	_, ok := r.catalog.Load().styles[name]
+	return ok
+}
+
+// Names 所有风格名称，按字典序排列
+func (r *Registry) Names() []string {
+	return r.catalog.Load().names()
+}
+
+// List 所有风格定义，按名称排列
+func (r *Registry) List() []*Style {
+	c := r.catalog.Load()
+	names := c.names()
+	styles := make([]*Style, len(names))
+	for i, name := range names {
+		styles[i] = c.styles[name]
+	}
+	return styles
+}
+
+// MerchantDefault 商家默认风格，未配置时返回空字符串
+func (r *Registry) MerchantDefault(merchantID string) string {
+	return r.catalog.Load().merchants[merchantID]
+}
+
+// Resolve 按候选顺序选择第一个已定义的风格，都不可用时使用默认风格
+func (r *Registry) Resolve(candidates ...string) *Style {
+	c := r.catalog.Load()
+	for _, name := range candidates {
+		if s, ok := c.styles[name]; ok {
+			return s
+		}
+	}
+	return c.styles[c.defaultStyle]
+}
+
+func (c *catalog) names() []string {
+	names := make([]string, 0, len(c.styles))
+	for name := range c.styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Inputs 传给 Dify 工作流的风格参数
+func (s *Style) Inputs() map[string]interface{} {
+	examples, _ := json.Marshal(s.Examples)
+	return map[string]interface{}{
+		"style":          s.Name,
+		"style_prompt":   s.SystemPrompt,
+		"style_examples": string(examples),
+	}
+}
+
+// Apply 按 emoji 与长度策略处理回复
+func (s *Style) Apply(answer string) string {
+	answer = s.applyEmoji(answer)
+	return s.applyLength(answer)
+}
+
+// applyEmoji 不允许 emoji 时全部移除，否则只保留前 Max 个
+func (s *Style) applyEmoji(answer string) string {
+	var b strings.Builder
+	kept, dropping := 0, false
+	for _, r := range answer {
+		switch {
+		case isEmoji(r):
+			if !s.Emoji.Enabled || (s.Emoji.Max > 0 && kept >= s.Emoji.Max) {
+				dropping = true
+				continue
+			}
+			dropping = false
+			kept++
+		case r == '\uFE0F' || r == '\u200D':
+			// 变体选择符、零宽连接符随被移除的 emoji 一起移除
+			if dropping {
+				continue
+			}
+		default:
+			dropping = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// applyLength 超长时在最后一个句末标点处截断，找不到句末标点时硬截断并加省略号
+func (s *Style) applyLength(answer string) string {
+	if s.Length.MaxChars <= 0 || utf8.RuneCountInString(answer) <= s.Length.MaxChars {
+		return answer
+	}
+
+	runes := []rune(answer)[:s.Length.MaxChars]
+	for i := len(runes) - 1; i >= len(runes)/2; i-- {
+		if strings.ContainsRune("。！？!?\n", runes[i]) {
+			return strings.TrimSpace(string(runes[:i+1]))
+		}
+	}
+	return string(runes) + "…"
+}
+
+// WithMaxChars 返回回复长度上限不超过 maxChars 的副本，maxChars 不大于 0 时返回自身
+func (s *Style) WithMaxChars(maxChars int) *Style {
+	if maxChars <= 0 || (s.Length.MaxChars > 0 && s.Length.MaxChars <= maxChars) {
+		return s
+	}
+	limited := *s
+	limited.Length.MaxChars = maxChars
+	return &limited
+}
+
+// isEmoji 常见 emoji 码段
+func isEmoji(r rune) bool {
+	return (r >= 0x1F300 && r <= 0x1FAFF) || // 符号与象形文字、表情、交通、补充符号
+		(r >= 0x2600 && r <= 0x27BF) || // 杂项符号、装饰符号（含 ✨）
+		(r >= 0x1F1E6 && r <= 0x1F1FF) || // 区域指示符（国旗）
+		r == 0x2B50 || r == 0x2B55 // ⭐ ⭕
+}
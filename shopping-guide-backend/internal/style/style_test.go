@@ -0,0 +1,208 @@
+package style
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testStyles = `
+styles:
+  xiaohongshu:
+    display_name: 小红书种草风
+    system_prompt: 用闺蜜聊天的口吻
+    emoji:
+      enabled: true
+      max: 2
+    length:
+      max_chars: 20
+    examples:
+      - question: 通勤用什么自行车好？
+        answer: C1 ✨
+  dongyuhui:
+    display_name: 董宇辉知识带货风
+    emoji:
+      enabled: false
+merchants:
+  merchant_a: dongyuhui
+`
+
+func writeStyles(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "styles.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	r, err := Load(writeStyles(t, testStyles), "xiaohongshu")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := r.Names(); !reflect.DeepEqual(got, []string{"dongyuhui", "xiaohongshu"}) {
+		t.Errorf("Names() = %v", got)
+	}
+	s, ok := r.Get("xiaohongshu")
+	if !ok || s.Name != "xiaohongshu" || s.Emoji.Max != 2 || len(s.Examples) != 1 {
+		t.Errorf("Get(xiaohongshu) = %+v, %v", s, ok)
+	}
+
+	inputs := s.Inputs()
+	if inputs["style"] != "xiaohongshu" || inputs["style_prompt"] != "用闺蜜聊天的口吻" ||
+		inputs["style_examples"] != `[{"question":"通勤用什么自行车好？","answer":"C1 ✨"}]` {
+		t.Errorf("Inputs() = %v", inputs)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		defaultStyle string
+		wantErr      string
+	}{
+		{name: "no styles", content: "styles: {}\n", defaultStyle: "a", wantErr: "no style defined"},
+		{name: "empty style", content: "styles:\n  a:\n", defaultStyle: "a", wantErr: "style a is empty"},
+		{name: "undefined merchant style", content: "styles:\n  a: {display_name: A}\nmerchants:\n  m1: b\n", defaultStyle: "a", wantErr: "merchant m1 uses undefined style b"},
+		{name: "undefined default", content: "styles:\n  a: {display_name: A}\n", defaultStyle: "b", wantErr: "default style b is not defined"},
+		{name: "invalid yaml", content: "styles: [", defaultStyle: "a", wantErr: "failed to parse style file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeStyles(t, tt.content), tt.defaultStyle)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	r, err := Load(writeStyles(t, testStyles), "xiaohongshu")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		candidates []string
+		want       string
+	}{
+		{nil, "xiaohongshu"},
+		{[]string{"", "unknown"}, "xiaohongshu"},
+		{[]string{"dongyuhui", "xiaohongshu"}, "dongyuhui"},
+		{[]string{"unknown", "", r.MerchantDefault("merchant_a")}, "dongyuhui"},
+		{[]string{r.MerchantDefault("merchant_b")}, "xiaohongshu"},
+	}
+	for _, tt := range tests {
+		if got := r.Resolve(tt.candidates...).Name; got != tt.want {
+			t.Errorf("Resolve(%q) = %s, want %s", tt.candidates, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryReload(t *testing.T) {
+	path := writeStyles(t, testStyles)
+	r, err := Load(path, "xiaohongshu")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("styles:\n  plain: {display_name: 朴素}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(path, "xiaohongshu"); err == nil {
+		t.Fatal("Reload() without default style succeeded")
+	}
+	if !r.Has("dongyuhui") {
+		t.Error("failed reload replaced the styles")
+	}
+
+	if err := r.Reload(path, "plain"); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if r.Has("dongyuhui") || r.Resolve().Name != "plain" {
+		t.Errorf("Names() = %v after reload", r.Names())
+	}
+}
+
+func TestStyleApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		style  Style
+		answer string
+		want   string
+	}{
+		{
+			name:   "emoji disabled",
+			style:  Style{},
+			answer: "推荐这款✨骑行🚴很稳⭐",
+			want:   "推荐这款骑行很稳",
+		},
+		{
+			name:   "emoji capped",
+			style:  Style{Emoji: EmojiPolicy{Enabled: true, Max: 2}},
+			answer: "A✨B🔥C💖D",
+			want:   "A✨B🔥CD",
+		},
+		{
+			name:   "dropped emoji takes its variation selector and joiner",
+			style:  Style{Emoji: EmojiPolicy{Enabled: true, Max: 1}},
+			answer: "好❤️看👨\u200d👩\u200d👧!",
+			want:   "好❤️看!",
+		},
+		{
+			name:   "unlimited emoji",
+			style:  Style{Emoji: EmojiPolicy{Enabled: true}},
+			answer: "✨✨✨✨",
+			want:   "✨✨✨✨",
+		},
+		{
+			name:   "cut at sentence end",
+			style:  Style{Emoji: EmojiPolicy{Enabled: true}, Length: LengthPolicy{MaxChars: 12}},
+			answer: "第一句话说完。第二句话还没有说完",
+			want:   "第一句话说完。",
+		},
+		{
+			name:   "hard cut without sentence end",
+			style:  Style{Emoji: EmojiPolicy{Enabled: true}, Length: LengthPolicy{MaxChars: 5}},
+			answer: "一二三四五六七",
+			want:   "一二三四五…",
+		},
+		{
+			name:   "within limit",
+			style:  Style{Length: LengthPolicy{MaxChars: 7}},
+			answer: "一二三四五六七",
+			want:   "一二三四五六七",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.style.Apply(tt.answer); got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyleWithMaxChars(t *testing.T) {
+	unlimited := &Style{Name: "a"}
+	if got := unlimited.WithMaxChars(0); got != unlimited {
+		t.Error("WithMaxChars(0) returned a copy")
+	}
+	limited := unlimited.WithMaxChars(100)
+	if limited == unlimited || limited.Length.MaxChars != 100 || unlimited.Length.MaxChars != 0 {
+		t.Errorf("WithMaxChars(100) = %+v, original %+v", limited, unlimited)
+	}
+
+	short := &Style{Length: LengthPolicy{MaxChars: 50}}
+	if got := short.WithMaxChars(100); got != short {
+		t.Error("WithMaxChars raised a stricter limit")
+	}
+	if got := short.WithMaxChars(20); got.Length.MaxChars != 20 {
+		t.Errorf("WithMaxChars(20).MaxChars = %d", got.Length.MaxChars)
+	}
+}
@@ -0,0 +1,174 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"shopping-guide-backend/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName 本服务 tracer 的名称
+const instrumentationName = "shopping-guide-backend"
+
+// 导出方式
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// 业务 span 属性
+const (
+	AttrRequestID     = attribute.Key("request.id")
+	AttrSessionID     = attribute.Key("session.id")
+	AttrTool          = attribute.Key("shopping_guide.tool")
+	AttrTokensUsed    = attribute.Key("shopping_guide.tokens_used")
+	AttrDegraded      = attribute.Key("shopping_guide.degraded")
+	AttrQuotaLevel    = attribute.Key("shopping_guide.quota_level")
+	AttrWorkflow      = attribute.Key("dify.workflow")
+	AttrWorkflowRunID = attribute.Key("dify.workflow_run_id")
+	AttrAttempt       = attribute.Key("retry.attempt")
+	AttrRowsAffected  = attribute.Key("db.rows_affected")
+	AttrBreakerFrom   = attribute.Key("circuit_breaker.from")
+	AttrBreakerTo     = attribute.Key("circuit_breaker.to")
+)
+
+// span 事件名
+const (
+	EventRetry           = "retry"
+	EventBreakerState    = "circuit_breaker.state_change" // 熔断器状态变化
+	EventBreakerRejected = "circuit_breaker.rejected"     // 熔断期间拒绝调用
+)
+
+// Init 按配置初始化全局 TracerProvider，并始终注册 W3C traceparent/baggage 传播器
+// 未启用时保留默认的 noop 实现；返回的 shutdown 在退出时刷新未导出的 span
+func Init(cfg *config.TracingConfig, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = instrumentationName
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+func newExporter(cfg *config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case ExporterOTLP, "":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterFile:
+		if err := os.MkdirAll(filepath.Dir(cfg.FilePath), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create trace dir: %w", err)
+		}
+		f, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exporter, f, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter: %s", cfg.Exporter)
+	}
+}
+
+// Start 创建 span，未启用追踪时返回 noop span
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End 记录错误并结束 span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// AddBreakerStateEvent 在当前 span 上记录熔断器状态变化
+func AddBreakerStateEvent(ctx context.Context, from, to string) {
+	trace.SpanFromContext(ctx).AddEvent(EventBreakerState, trace.WithAttributes(
+		AttrBreakerFrom.String(from),
+		AttrBreakerTo.String(to),
+	))
+}
+
+// AddBreakerRejectedEvent 在当前 span 上记录一次熔断拒绝
+func AddBreakerRejectedEvent(ctx context.Context) {
+	trace.SpanFromContext(ctx).AddEvent(EventBreakerRejected)
+}
+
+// AddRetryEvent 在当前 span 上记录一次重试
+func AddRetryEvent(ctx context.Context, attempt int, err error) {
+	trace.SpanFromContext(ctx).AddEvent(EventRetry, trace.WithAttributes(
+		AttrAttempt.Int(attempt),
+		attribute.String("error", err.Error()),
+	))
+}
@@ -7,24 +7,61 @@ USE shopping_guide;
 CREATE TABLE `user_profiles` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '主键ID',
   `user_id` varchar(64) NOT NULL COMMENT '用户唯一标识（业务ID，如UUID/手机号等）',
-  `preferred_style` varchar(50) NOT NULL COMMENT '偏好风格（如：xiaohongshu/dongyuhui）',
+  `preferred_style` varchar(50) NOT NULL DEFAULT '' COMMENT '偏好风格（如：xiaohongshu/dongyuhui），为空时使用商家默认风格',
   `age` int(11) NOT NULL COMMENT '年龄',
   `gender` varchar(20) NOT NULL COMMENT '性别（如：male/female/other）',
   `interests` json DEFAULT NULL COMMENT '兴趣爱好列表',
+  `budget_min` decimal(10,2) NOT NULL DEFAULT 0 COMMENT '预算下限，0表示未知',
+  `budget_max` decimal(10,2) NOT NULL DEFAULT 0 COMMENT '预算上限，0表示未知',
+  `sizes` json DEFAULT NULL COMMENT '尺码偏好，如 {"clothing":"M","shoe":"42"}',
+  `signals` json DEFAULT NULL COMMENT '自动补全字段的来源与置信度',
+  `enrichment_disabled` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否关闭从对话中自动补全画像',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (`id`),
   UNIQUE INDEX `idx_user_id` (`user_id`) COMMENT '用户ID唯一索引，加速查询并防止重复'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户画像表';
+
+-- 用户画像变更记录表
+CREATE TABLE IF NOT EXISTS `user_profile_histories` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `user_id` varchar(64) NOT NULL COMMENT '用户ID',
+  `field` varchar(64) NOT NULL COMMENT '字段：interest/budget_min/budget_max/gender/size.*等',
+  `old_value` varchar(255) NOT NULL DEFAULT '' COMMENT '原值',
+  `new_value` varchar(255) NOT NULL DEFAULT '' COMMENT '新值',
+  `source` varchar(20) NOT NULL COMMENT '来源：manual/rules/dify/decay',
+  `confidence` decimal(5,4) NOT NULL DEFAULT 1 COMMENT '置信度',
+  `session_id` varchar(64) NOT NULL DEFAULT '' COMMENT '触发变更的会话ID',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_id` (`user_id`),
+  INDEX `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户画像变更记录表';
+
+-- token 用量按天汇总表（实时计数在 Redis，定时汇总）
+CREATE TABLE IF NOT EXISTS `token_usages` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `scope` varchar(20) NOT NULL COMMENT '主体类型：user/guest/merchant',
+  `subject_id` varchar(64) NOT NULL COMMENT '用户ID或商家ID',
+  `day` date NOT NULL COMMENT '日期',
+  `tokens` bigint(20) NOT NULL DEFAULT 0 COMMENT '当日消耗的 tokens',
+  `requests` bigint(20) NOT NULL DEFAULT 0 COMMENT '当日对话轮数',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_usage` (`scope`, `subject_id`, `day`),
+  INDEX `idx_day` (`day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='token 用量表';
 -- 用户表
 CREATE TABLE IF NOT EXISTS users (
     user_id VARCHAR(64) PRIMARY KEY,
     username VARCHAR(128),
-    email VARCHAR(255),
-    phone VARCHAR(32),
-    profile JSON COMMENT '用户画像',
+    email VARCHAR(255) NULL COMMENT '邮箱，未填写为NULL',
+    phone VARCHAR(32) NULL COMMENT '手机号，未填写为NULL',
+    password_hash VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'bcrypt密码哈希',
+    roles JSON NULL COMMENT '角色列表，如 ["admin"]，只能直接写库授予',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    INDEX idx_email (email),
-    INDEX idx_phone (phone)
+    UNIQUE KEY uk_email (email),
+    UNIQUE KEY uk_phone (phone)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户表';
 
 -- 商品表
@@ -48,6 +85,8 @@ CREATE TABLE IF NOT EXISTS products (
 -- 对话日志表
 CREATE TABLE IF NOT EXISTS chat_logs (
     log_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    request_id VARCHAR(128) NOT NULL DEFAULT '' COMMENT '请求ID(X-Request-ID)',
+    trace_id CHAR(32) NOT NULL DEFAULT '' COMMENT 'W3C trace-id',
     session_id VARCHAR(64) NOT NULL,
     user_id VARCHAR(64) NOT NULL,
     query TEXT NOT NULL COMMENT '用户输入',
@@ -59,6 +98,7 @@ CREATE TABLE IF NOT EXISTS chat_logs (
     latency_ms INT COMMENT '响应时长(毫秒)',
     tokens_used INT COMMENT 'Token消耗',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_request (request_id),
     INDEX idx_session (session_id),
     INDEX idx_user (user_id),
     INDEX idx_tool (tool_used),
@@ -68,8 +108,10 @@ CREATE TABLE IF NOT EXISTS chat_logs (
 -- Dify调用日志表
 CREATE TABLE IF NOT EXISTS dify_call_logs (
     log_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    request_id VARCHAR(128) NOT NULL DEFAULT '' COMMENT '请求ID(X-Request-ID)',
+    trace_id CHAR(32) NOT NULL DEFAULT '' COMMENT 'W3C trace-id',
     workflow_name VARCHAR(64) NOT NULL COMMENT '工作流名称',
-    app_id VARCHAR(64) NOT NULL,
+    app_id VARCHAR(64) NOT NULL COMMENT '应用API Key(脱敏)',
     workflow_run_id VARCHAR(128) COMMENT 'Dify运行ID',
     inputs JSON COMMENT '输入参数',
     outputs JSON COMMENT '输出结果',
@@ -78,6 +120,7 @@ CREATE TABLE IF NOT EXISTS dify_call_logs (
     latency_ms INT COMMENT '调用时长',
     tokens_used INT COMMENT 'Token消耗',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_request (request_id),
     INDEX idx_workflow (workflow_name),
     INDEX idx_app (app_id),
     INDEX idx_status (status),
@@ -91,14 +134,52 @@ CREATE TABLE IF NOT EXISTS product_recommendations (
     user_id VARCHAR(64) NOT NULL,
     product_id VARCHAR(64) NOT NULL,
     reason TEXT COMMENT '推荐理由',
-    user_action VARCHAR(32) COMMENT '用户行为: view/click/add_cart/purchase',
+    tool VARCHAR(64) COMMENT '产生推荐的Executor',
+    prompt_version VARCHAR(32) COMMENT 'Executor提示词版本',
+    position INT DEFAULT 0 COMMENT '推荐列表中的位置',
+    user_action VARCHAR(32) COMMENT '用户行为: view/click/add_cart/purchase（漏斗中最深的行为）',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_session (session_id),
     INDEX idx_user (user_id),
     INDEX idx_product (product_id),
+    INDEX idx_tool (tool),
+    INDEX idx_prompt_version (prompt_version),
     INDEX idx_action (user_action)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商品推荐记录表';
 
+-- 推荐行为事件表
+CREATE TABLE IF NOT EXISTS recommendation_events (
+    event_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    rec_id BIGINT NOT NULL COMMENT '推荐记录ID',
+    user_id VARCHAR(64) NOT NULL,
+    action VARCHAR(32) NOT NULL COMMENT '用户行为: view/click/add_cart/purchase',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_rec_action (rec_id, action),
+    INDEX idx_user (user_id),
+    INDEX idx_action (action),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='推荐行为事件表';
+
+-- 迁移记录表：本文件只用于全新安装，已包含 scripts/migrations 下的全部迁移；
+-- 新增表结构变更时同时修改本文件并新增迁移文件，在下面登记为已执行
+CREATE TABLE IF NOT EXISTS `schema_migrations` (
+  `version` varchar(64) NOT NULL COMMENT '迁移文件名（不含 .sql）',
+  `applied_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '执行时间',
+  PRIMARY KEY (`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据库迁移记录表';
+
+INSERT IGNORE INTO `schema_migrations` (`version`) VALUES
+('000_schema_migrations'),
+('001_user_accounts'),
+('002_recommendation_tracking'),
+('003_profile_enrichment'),
+('004_profile_default_style'),
+('005_token_usage'),
+('006_request_correlation'),
+('007_user_roles'),
+('008_drop_users_profile');
+
 -- 插入测试数据
 INSERT INTO products (product_id, name, category, sub_category, price, stock, description, status) VALUES
 ('bike-001', '山地自行车X1', '骑行', '自行车', 1299.00, 50, '适合山地骑行的专业自行车', 1),
@@ -0,0 +1,10 @@
+-- 迁移记录表，make migrate-up 据此跳过已执行的迁移
+USE shopping_guide;
+
+CREATE TABLE IF NOT EXISTS `schema_migrations` (
+  `version` varchar(64) NOT NULL COMMENT '迁移文件名（不含 .sql）',
+  `applied_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '执行时间',
+  PRIMARY KEY (`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据库迁移记录表';
+
+INSERT IGNORE INTO `schema_migrations` (`version`) VALUES ('000_schema_migrations');
@@ -0,0 +1,19 @@
+-- 用户账号：密码哈希，邮箱/手机号未填写时存 NULL 并加唯一索引
+-- 执行前需清理重复的邮箱/手机号，否则唯一索引创建失败
+USE shopping_guide;
+
+ALTER TABLE users
+  MODIFY COLUMN email VARCHAR(255) NULL COMMENT '邮箱，未填写为NULL',
+  MODIFY COLUMN phone VARCHAR(32) NULL COMMENT '手机号，未填写为NULL',
+  ADD COLUMN password_hash VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'bcrypt密码哈希' AFTER phone;
+
+UPDATE users SET email = NULL WHERE email = '';
+UPDATE users SET phone = NULL WHERE phone = '';
+
+ALTER TABLE users
+  DROP INDEX idx_email,
+  DROP INDEX idx_phone,
+  ADD UNIQUE KEY uk_email (email),
+  ADD UNIQUE KEY uk_phone (phone);
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('001_user_accounts');
@@ -0,0 +1,25 @@
+-- 推荐记录：产生推荐的 Executor、提示词版本与位置，以及行为事件明细
+USE shopping_guide;
+
+ALTER TABLE product_recommendations
+  ADD COLUMN tool VARCHAR(64) COMMENT '产生推荐的Executor' AFTER reason,
+  ADD COLUMN prompt_version VARCHAR(32) COMMENT 'Executor提示词版本' AFTER tool,
+  ADD COLUMN position INT DEFAULT 0 COMMENT '推荐列表中的位置' AFTER prompt_version,
+  MODIFY COLUMN user_action VARCHAR(32) COMMENT '用户行为: view/click/add_cart/purchase（漏斗中最深的行为）',
+  ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP AFTER created_at,
+  ADD INDEX idx_tool (tool),
+  ADD INDEX idx_prompt_version (prompt_version);
+
+CREATE TABLE IF NOT EXISTS recommendation_events (
+    event_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    rec_id BIGINT NOT NULL COMMENT '推荐记录ID',
+    user_id VARCHAR(64) NOT NULL,
+    action VARCHAR(32) NOT NULL COMMENT '用户行为: view/click/add_cart/purchase',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_rec_action (rec_id, action),
+    INDEX idx_user (user_id),
+    INDEX idx_action (action),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='推荐行为事件表';
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('002_recommendation_tracking');
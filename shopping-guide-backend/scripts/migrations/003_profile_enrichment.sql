@@ -0,0 +1,27 @@
+-- 用户画像补全：预算、尺码、字段来源与置信度、补全开关，以及画像变更记录
+USE shopping_guide;
+
+ALTER TABLE `user_profiles`
+  ADD COLUMN `budget_min` decimal(10,2) NOT NULL DEFAULT 0 COMMENT '预算下限，0表示未知' AFTER `interests`,
+  ADD COLUMN `budget_max` decimal(10,2) NOT NULL DEFAULT 0 COMMENT '预算上限，0表示未知' AFTER `budget_min`,
+  ADD COLUMN `sizes` json DEFAULT NULL COMMENT '尺码偏好，如 {"clothing":"M","shoe":"42"}' AFTER `budget_max`,
+  ADD COLUMN `signals` json DEFAULT NULL COMMENT '自动补全字段的来源与置信度' AFTER `sizes`,
+  ADD COLUMN `enrichment_disabled` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否关闭从对话中自动补全画像' AFTER `signals`,
+  ADD COLUMN `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间' AFTER `enrichment_disabled`;
+
+CREATE TABLE IF NOT EXISTS `user_profile_histories` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `user_id` varchar(64) NOT NULL COMMENT '用户ID',
+  `field` varchar(64) NOT NULL COMMENT '字段：interest/budget_min/budget_max/gender/size.*等',
+  `old_value` varchar(255) NOT NULL DEFAULT '' COMMENT '原值',
+  `new_value` varchar(255) NOT NULL DEFAULT '' COMMENT '新值',
+  `source` varchar(20) NOT NULL COMMENT '来源：manual/rules/dify/decay',
+  `confidence` decimal(5,4) NOT NULL DEFAULT 1 COMMENT '置信度',
+  `session_id` varchar(64) NOT NULL DEFAULT '' COMMENT '触发变更的会话ID',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_id` (`user_id`),
+  INDEX `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户画像变更记录表';
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('003_profile_enrichment');
@@ -0,0 +1,7 @@
+-- 偏好风格允许为空，为空时使用商家默认风格
+USE shopping_guide;
+
+ALTER TABLE `user_profiles`
+  MODIFY COLUMN `preferred_style` varchar(50) NOT NULL DEFAULT '' COMMENT '偏好风格（如：xiaohongshu/dongyuhui），为空时使用商家默认风格';
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('004_profile_default_style');
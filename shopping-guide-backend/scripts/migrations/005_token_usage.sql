@@ -0,0 +1,17 @@
+-- token 用量按天汇总表（实时计数在 Redis，定时汇总）
+USE shopping_guide;
+
+CREATE TABLE IF NOT EXISTS `token_usages` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `scope` varchar(20) NOT NULL COMMENT '主体类型：user/guest/merchant',
+  `subject_id` varchar(64) NOT NULL COMMENT '用户ID或商家ID',
+  `day` date NOT NULL COMMENT '日期',
+  `tokens` bigint(20) NOT NULL DEFAULT 0 COMMENT '当日消耗的 tokens',
+  `requests` bigint(20) NOT NULL DEFAULT 0 COMMENT '当日对话轮数',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_usage` (`scope`, `subject_id`, `day`),
+  INDEX `idx_day` (`day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='token 用量表';
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('005_token_usage');
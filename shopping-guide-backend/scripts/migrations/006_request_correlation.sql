@@ -0,0 +1,15 @@
+-- 对话日志与 Dify 调用日志记录请求ID与 trace-id
+USE shopping_guide;
+
+ALTER TABLE chat_logs
+  ADD COLUMN request_id VARCHAR(128) NOT NULL DEFAULT '' COMMENT '请求ID(X-Request-ID)' AFTER log_id,
+  ADD COLUMN trace_id CHAR(32) NOT NULL DEFAULT '' COMMENT 'W3C trace-id' AFTER request_id,
+  ADD INDEX idx_request (request_id);
+
+ALTER TABLE dify_call_logs
+  ADD COLUMN request_id VARCHAR(128) NOT NULL DEFAULT '' COMMENT '请求ID(X-Request-ID)' AFTER log_id,
+  ADD COLUMN trace_id CHAR(32) NOT NULL DEFAULT '' COMMENT 'W3C trace-id' AFTER request_id,
+  MODIFY COLUMN app_id VARCHAR(64) NOT NULL COMMENT '应用API Key(脱敏)',
+  ADD INDEX idx_request (request_id);
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('006_request_correlation');
@@ -0,0 +1,8 @@
+-- 用户角色，admin 可访问 /admin 接口；只能直接写库授予，如
+-- UPDATE users SET roles = JSON_ARRAY('admin') WHERE user_id = '...';
+USE shopping_guide;
+
+ALTER TABLE users
+  ADD COLUMN roles JSON NULL COMMENT '角色列表，如 ["admin"]，只能直接写库授予' AFTER password_hash;
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('007_user_roles');
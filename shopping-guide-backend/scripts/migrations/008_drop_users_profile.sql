@@ -0,0 +1,8 @@
+-- 画像已迁移到 user_profiles 表，移除 users 表中不再读写的 profile 列
+-- 执行前如需保留旧数据请先备份该列
+USE shopping_guide;
+
+ALTER TABLE users
+  DROP COLUMN profile;
+
+INSERT INTO `schema_migrations` (`version`) VALUES ('008_drop_users_profile');